@@ -0,0 +1,169 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/xiongle/alphaFFM-go/pkg/frame"
+	"github.com/xiongle/alphaFFM-go/pkg/input"
+	"github.com/xiongle/alphaFFM-go/pkg/model"
+	"github.com/xiongle/alphaFFM-go/pkg/sample"
+	"github.com/xiongle/alphaFFM-go/pkg/utils"
+)
+
+func fieldCheckHelp() string {
+	return `
+usage: cat sample | ./ffm_fieldcheck [<options>]
+   or: ./ffm_fieldcheck [<options>] <file|glob> ...
+
+input files may be gzip compressed, stdin is read when no file is given
+
+checks a field config against ffm format samples before training: reports which rule resolved each feature,
+features that fell back to default_field or could not be resolved, the resulting field list and the estimated model size.
+exits with status 1 when any line fails to parse, -on_error fail and -max_error_rate stop the check early
+
+options:
+-field_config <config_path>: field mapping config file
+-dim <factor_num>: dim of 2-way interactions used for the model size estimate	default:8
+-top <n>: number of features listed for default_field, failures and errors	default:20
+-core <threads_num>: set the number of threads	default:1
+-producers <n>: number of input files read in parallel, 0 means the same as -core	default:0
+-on_error <policy>: skip invalid samples, or fail at the first one	default:skip
+-max_error_rate <rate>: fail when the fraction of invalid samples exceeds rate, 0 disables the check	default:0
+-rejected <path>: write invalid samples to path as location<TAB>reason<TAB>line
+`
+}
+
+func main() {
+	fieldConfig := flag.String("field_config", "", "field mapping config file")
+	dim := flag.Int("dim", 8, "factor num")
+	top := flag.Int("top", 20, "number of features listed")
+	core := flag.Int("core", 1, "threads num")
+	producers := flag.Int("producers", 0, "input files read in parallel")
+	onError := flag.String("on_error", sample.OnErrorSkip, "invalid sample policy: skip or fail")
+	maxErrorRate := flag.Float64("max_error_rate", 0, "max fraction of invalid samples")
+	rejected := flag.String("rejected", "", "file for invalid samples")
+
+	flag.Parse()
+
+	inputs := []string{input.Stdin}
+	if flag.NArg() > 0 {
+		var err error
+		if inputs, err = input.Expand(flag.Args()); err != nil {
+			fmt.Fprintf(os.Stderr, "invalid input: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	if *fieldConfig == "" {
+		fmt.Fprintln(os.Stderr, "field config path required")
+		fmt.Fprint(os.Stderr, fieldCheckHelp())
+		os.Exit(1)
+	}
+	if *dim <= 0 {
+		fmt.Fprintf(os.Stderr, "invalid dim: %d\n", *dim)
+		fmt.Fprint(os.Stderr, fieldCheckHelp())
+		os.Exit(1)
+	}
+
+	policy := sample.ErrorPolicy{OnError: *onError, MaxErrorRate: *maxErrorRate, RejectedPath: *rejected}
+	if err := policy.Validate(); err != nil {
+		fmt.Fprintf(os.Stderr, "invalid error policy: %v\n", err)
+		fmt.Fprint(os.Stderr, fieldCheckHelp())
+		os.Exit(1)
+	}
+
+	cfg, format, err := model.LoadFieldConfig(*fieldConfig)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("field config: %s (%s format, mode: %s, %d mappings, %d rules, hash %s)\n",
+		*fieldConfig, format, cfg.Mode, len(cfg.FeatureToField), len(cfg.Rules), cfg.Hash())
+
+	checker, err := model.NewFieldChecker(cfg, *dim, policy)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "create checker error: %v\n", err)
+		os.Exit(1)
+	}
+	ctx, cancel := utils.SignalContext()
+	defer cancel()
+	pcFrame := frame.NewPCFrame()
+	pcFrame.Init(checker, *core)
+	if *producers <= 0 {
+		*producers = *core
+	}
+	err = pcFrame.RunFiles(ctx, inputs, *producers)
+	fmt.Println(checker.InputSummary())
+	if finishErr := checker.FinishInput(); err == nil {
+		err = finishErr
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "check error: %v\n", err)
+		os.Exit(1)
+	}
+
+	r := checker.Report(*top)
+	printReport(r)
+	if r.InvalidLines > 0 {
+		os.Exit(1)
+	}
+}
+
+// printReport 打印检查结果
+func printReport(r *model.FieldCheckReport) {
+	fmt.Printf("\nlines: %d, invalid: %d, feature occurrences: %d\n", r.Lines, r.InvalidLines, r.Tokens)
+
+	fmt.Println("\nresolved by:")
+	for _, s := range r.Sources {
+		fmt.Printf("  %-24s %12d  %6.2f%%\n", s.Name, s.Count, percent(s.Count, r.Tokens))
+	}
+
+	if len(r.Rules) > 0 {
+		fmt.Println("\nrule hits:")
+		for _, s := range r.Rules {
+			note := ""
+			if s.Hits == 0 {
+				note = "  (never matched)"
+			}
+			fmt.Printf("  %-40s %12d%s\n", s.Rule, s.Hits, note)
+		}
+	}
+
+	printCounts("features in default_field", r.Defaults)
+	printCounts("unresolved features", r.Failures)
+	printCounts("parse errors", r.Errors)
+
+	fmt.Printf("\nfields (%d):\n", len(r.Fields))
+	for _, f := range r.Fields {
+		name := f.Name
+		if name == "" {
+			name = "(empty)"
+		}
+		fmt.Printf("  %-24s %12d features\n", name, f.Count)
+	}
+
+	params := r.Params()
+	fmt.Printf("\nestimated model size: %d features x %d fields x k=%d x 3 + linear terms = %d parameters (%.1f MB as double)\n",
+		r.Features, len(r.Fields), r.FactorNum, params, float64(params)*8/(1<<20))
+}
+
+// printCounts 打印计数列表，为空时不打印
+func printCounts(title string, counts []model.FeatureCount) {
+	if len(counts) == 0 {
+		return
+	}
+	fmt.Printf("\n%s (top %d):\n", title, len(counts))
+	for _, c := range counts {
+		fmt.Printf("  %-40s %12d\n", c.Name, c.Count)
+	}
+}
+
+// percent 百分比
+func percent(n, total int64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(n) * 100 / float64(total)
+}
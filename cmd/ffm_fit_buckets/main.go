@@ -0,0 +1,125 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/xiongle/alphaFFM-go/pkg/frame"
+	"github.com/xiongle/alphaFFM-go/pkg/input"
+	"github.com/xiongle/alphaFFM-go/pkg/model"
+	"github.com/xiongle/alphaFFM-go/pkg/sample"
+	"github.com/xiongle/alphaFFM-go/pkg/utils"
+)
+
+func fitBucketsHelp() string {
+	return `
+usage: cat sample | ./ffm_fit_buckets [<options>]
+   or: ./ffm_fit_buckets [<options>] <file|glob> ...
+
+input files may be gzip compressed, stdin is read when no file is given
+
+learns quantile bucket boundaries for numeric features and writes them into a field config JSON
+
+options:
+-field_config <config_path>: field mapping config file, its quantile bucket rules are fitted
+-features <f1,f2,...>: additional numeric features to fit
+-buckets <n>: number of buckets for rules without their own count	default:10
+-eps <eps>: rank error of the quantile sketch	default:0.001
+-out <config_path>: set the output field config path
+-core <threads_num>: set the number of threads	default:1
+-producers <n>: number of input files read in parallel, 0 means the same as -core	default:0
+-on_error <policy>: skip invalid samples, or fail at the first one	default:skip
+-max_error_rate <rate>: fail when the fraction of invalid samples exceeds rate, 0 disables the check	default:0
+-rejected <path>: write invalid samples to path as location<TAB>reason<TAB>line
+`
+}
+
+func main() {
+	opt := &model.BucketFitOption{}
+
+	fieldConfig := flag.String("field_config", "", "field mapping config file")
+	features := flag.String("features", "", "additional numeric features")
+	buckets := flag.Int("buckets", 10, "number of buckets")
+	eps := flag.Float64("eps", 0.001, "rank error of the quantile sketch")
+	out := flag.String("out", "", "output field config path")
+	core := flag.Int("core", 1, "threads num")
+	producers := flag.Int("producers", 0, "input files read in parallel")
+	onError := flag.String("on_error", sample.OnErrorSkip, "invalid sample policy: skip or fail")
+	maxErrorRate := flag.Float64("max_error_rate", 0, "max fraction of invalid samples")
+	rejected := flag.String("rejected", "", "file for invalid samples")
+
+	flag.Parse()
+
+	inputs := []string{input.Stdin}
+	if flag.NArg() > 0 {
+		var err error
+		if inputs, err = input.Expand(flag.Args()); err != nil {
+			fmt.Fprintf(os.Stderr, "invalid input: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	opt.FieldConfigPath = *fieldConfig
+	opt.Buckets = *buckets
+	opt.Eps = *eps
+	for _, f := range strings.Split(*features, ",") {
+		if f = strings.TrimSpace(f); f != "" {
+			opt.Features = append(opt.Features, f)
+		}
+	}
+
+	opt.ErrorPolicy = sample.ErrorPolicy{OnError: *onError, MaxErrorRate: *maxErrorRate, RejectedPath: *rejected}
+	if err := opt.ErrorPolicy.Validate(); err != nil {
+		fmt.Fprintf(os.Stderr, "invalid error policy: %v\n", err)
+		fmt.Fprint(os.Stderr, fitBucketsHelp())
+		os.Exit(1)
+	}
+
+	if *out == "" {
+		fmt.Fprintln(os.Stderr, "output config path required")
+		fmt.Fprint(os.Stderr, fitBucketsHelp())
+		os.Exit(1)
+	}
+
+	fitter, err := model.NewBucketFitter(opt)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "create fitter error: %v\n", err)
+		fmt.Fprint(os.Stderr, fitBucketsHelp())
+		os.Exit(1)
+	}
+
+	ctx, cancel := utils.SignalContext()
+	defer cancel()
+	pcFrame := frame.NewPCFrame()
+	pcFrame.Init(fitter, *core)
+	if *producers <= 0 {
+		*producers = *core
+	}
+	err = pcFrame.RunFiles(ctx, inputs, *producers)
+	fmt.Println(fitter.InputSummary())
+	if finishErr := fitter.FinishInput(); err == nil {
+		err = finishErr
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "fit error: %v\n", err)
+		os.Exit(1)
+	}
+
+	cfg, results, err := fitter.Fit()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "fit error: %v\n", err)
+		os.Exit(1)
+	}
+	for _, r := range results {
+		fmt.Printf("%s: %d values, min %.6g, max %.6g, %d buckets %v\n",
+			r.Feature, r.Count, r.Min, r.Max, len(r.Boundaries)+1, r.Boundaries)
+	}
+
+	if err := cfg.SaveToJSON(*out); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to write field config: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("field config written to %s\n", *out)
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/xiongle/alphaFFM-go/pkg/frame"
+	"github.com/xiongle/alphaFFM-go/pkg/input"
+	"github.com/xiongle/alphaFFM-go/pkg/model"
+	"github.com/xiongle/alphaFFM-go/pkg/sample"
+	"github.com/xiongle/alphaFFM-go/pkg/utils"
+)
+
+func importanceHelp() string {
+	return `
+usage: cat sample | ./ffm_importance [<options>]
+   or: ./ffm_importance [<options>] <file|glob> ...
+
+input files may be gzip compressed, stdin is read when no file is given
+
+options:
+-m <model_path>: set the model path
+-mf <model_format>: set the model format, txt or bin	default:txt
+-dim <factor_num>: dim of 2-way interactions, read from the model header when omitted
+-core <threads_num>: set the number of threads	default:1
+-producers <n>: number of input files read in parallel, 0 means the same as -core	default:0
+-field_config <config_path>: field mapping config file (JSON or text format)
+-input_format <format>: ffm, libffm (label field_id:feature_id:value with integer ids), csv, tsv or jsonl	default:ffm
+-schema <schema_path>: JSON column schema for csv/tsv input (label, weight, feature and ignored columns)
+-on_error <policy>: skip invalid samples, or fail at the first one	default:skip
+-max_error_rate <rate>: fail when the fraction of invalid samples exceeds rate, 0 disables the check	default:0
+-rejected <path>: write invalid samples to path as location<TAB>reason<TAB>line
+-top <n>: number of features listed in the report, fields and field pairs are always listed in full	default:50
+-out <report_path>: write the report to a file instead of stdout, diagnostics go to stderr when the report is written to stdout
+-json: write the report as JSON
+`
+}
+
+// writeText 输出文本报告
+func writeText(w io.Writer, report *model.ImportanceReport) {
+	section := func(title string, items []model.ImportanceItem) {
+		fmt.Fprintln(w, "==========================================")
+		fmt.Fprintln(w, title)
+		fmt.Fprintln(w, "==========================================")
+		fmt.Fprintf(w, "%-5s %-30s %14s %16s %12s\n", "rank", "name", "avg |contrib|", "avg when present", "coverage")
+		for i, item := range items {
+			coverage := 0.0
+			if report.Samples > 0 {
+				coverage = 100 * float64(item.Occurrences) / float64(report.Samples)
+			}
+			fmt.Fprintf(w, "%-5d %-30s %14.6g %16.6g %11.2f%%\n", i+1, item.Name, item.AvgAbs, item.AvgAbsPresent, coverage)
+		}
+		fmt.Fprintln(w)
+	}
+
+	fmt.Fprintf(w, "samples: %d\n\n", report.Samples)
+	section("Fields", report.Fields)
+	section("Field pairs", report.FieldPairs)
+	section(fmt.Sprintf("Top %d features", len(report.Features)), report.Features)
+}
+
+func main() {
+	opt := &model.ImportanceOption{}
+
+	modelPath := flag.String("m", "", "model path")
+	modelFormat := flag.String("mf", "txt", "model format")
+	dim := flag.Int("dim", 0, "factor num, 0 reads it from the model header")
+	core := flag.Int("core", 1, "threads num")
+	producers := flag.Int("producers", 0, "input files read in parallel")
+	fieldConfig := flag.String("field_config", "", "field mapping config file")
+	inputFormat := flag.String("input_format", sample.InputFormatFFM, "input format: ffm, libffm, csv, tsv or jsonl")
+	schema := flag.String("schema", "", "column schema for csv/tsv input")
+	onError := flag.String("on_error", sample.OnErrorSkip, "invalid sample policy: skip or fail")
+	maxErrorRate := flag.Float64("max_error_rate", 0, "max fraction of invalid samples")
+	rejected := flag.String("rejected", "", "file for invalid samples")
+	top := flag.Int("top", 50, "number of features listed")
+	out := flag.String("out", "", "report path")
+	asJSON := flag.Bool("json", false, "write report as JSON")
+
+	flag.Parse()
+
+	// 报告写到标准输出时，加载模型、处理进度、坏行汇总等诊断信息都改写到标准错误，标准输出只有报告（-json 时可直接解析）
+	reportOut := os.Stdout
+	if *out == "" {
+		os.Stdout = os.Stderr
+	}
+
+	inputs := []string{input.Stdin}
+	if flag.NArg() > 0 {
+		var err error
+		if inputs, err = input.Expand(flag.Args()); err != nil {
+			fmt.Fprintf(os.Stderr, "invalid input: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	opt.ModelPath = *modelPath
+	opt.ModelFormat = *modelFormat
+	opt.FactorNum = *dim
+	opt.FieldConfigPath = *fieldConfig
+	opt.InputFormat = *inputFormat
+	opt.SchemaPath = *schema
+	opt.ErrorPolicy = sample.ErrorPolicy{OnError: *onError, MaxErrorRate: *maxErrorRate, RejectedPath: *rejected}
+	if err := opt.ErrorPolicy.Validate(); err != nil {
+		fmt.Fprintf(os.Stderr, "invalid error policy: %v\n", err)
+		fmt.Fprint(os.Stderr, importanceHelp())
+		os.Exit(1)
+	}
+	if err := model.CheckInput(opt.InputFormat, opt.SchemaPath); err != nil {
+		fmt.Fprintf(os.Stderr, "invalid input: %v\n", err)
+		fmt.Fprint(os.Stderr, importanceHelp())
+		os.Exit(1)
+	}
+
+	if opt.ModelPath == "" {
+		fmt.Fprintln(os.Stderr, "model path required")
+		fmt.Fprint(os.Stderr, importanceHelp())
+		os.Exit(1)
+	}
+
+	task, err := model.NewFFMImportance(opt)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "create importance task error: %v\n", err)
+		os.Exit(1)
+	}
+
+	ctx, cancel := utils.SignalContext()
+	defer cancel()
+	pcFrame := frame.NewPCFrame()
+	pcFrame.Init(task, *core)
+	if *producers <= 0 {
+		*producers = *core
+	}
+	err = pcFrame.RunFiles(ctx, inputs, *producers)
+	fmt.Println(task.InputSummary())
+	if finishErr := task.FinishInput(); err == nil {
+		err = finishErr
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "importance error: %v\n", err)
+		os.Exit(1)
+	}
+
+	var w io.Writer = reportOut
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "open report file error: %v\n", err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		w = f
+	}
+
+	report := task.Report(*top)
+	if *asJSON {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report); err != nil {
+			fmt.Fprintf(os.Stderr, "write report error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+	writeText(w, report)
+}
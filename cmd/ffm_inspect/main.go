@@ -0,0 +1,151 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/xiongle/alphaFFM-go/pkg/model"
+)
+
+func inspectHelp() string {
+	return `
+usage: ./ffm_inspect -m <model_path> [<options>]
+
+options:
+-m <model_path>: set the model path
+-mf <model_format>: set the model format, txt or bin	default:txt
+-dim <factor_num>: dim of 2-way interactions, read from the model header when omitted
+-feature <feature>: dump wi and the per-field vectors of a single feature (use "bias" for the bias)
+`
+}
+
+// formatBytes 格式化字节数
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for v := n / unit; v >= unit; v /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// ratio 计算比例
+func ratio(a, b int64) float64 {
+	if b == 0 {
+		return 0
+	}
+	return float64(a) / float64(b)
+}
+
+// formatVector 格式化向量
+func formatVector(v []float64) string {
+	parts := make([]string, len(v))
+	for i, x := range v {
+		parts[i] = fmt.Sprintf("%.6g", x)
+	}
+	return strings.Join(parts, " ")
+}
+
+func printStats(m *model.FFMModel) {
+	stats := m.Stats()
+
+	fmt.Println("==========================================")
+	fmt.Println("Model summary")
+	fmt.Println("==========================================")
+	if meta := m.Meta; meta != nil {
+		fmt.Printf("format version:   %d\n", meta.FormatVersion)
+		fmt.Printf("number type:      %s\n", meta.NumberType)
+		fmt.Printf("trained samples:  %d\n", meta.SampleCount)
+		fmt.Printf("trained at:       %s\n", meta.TrainedAt)
+		if meta.FieldConfigHash != "" {
+			fmt.Printf("field config:     %s\n", meta.FieldConfigHash)
+		}
+	}
+	fmt.Printf("factor num:       %d\n", stats.FactorNum)
+	fmt.Printf("features:         %d\n", stats.NumFeatures)
+	fmt.Printf("fields:           %d (%s)\n", stats.NumFields, strings.Join(m.FieldNames, " "))
+	fmt.Printf("bias:             %.6g\n", m.MuBias.Wi)
+	fmt.Printf("non-zero wi:      %d (%.2f%%)\n", stats.NonZeroWi, 100*ratio(stats.NonZeroWi, int64(stats.NumFeatures)))
+	fmt.Printf("non-zero feature: %d (%.2f%%)\n", stats.NonZeroFeatures, 100*ratio(stats.NonZeroFeatures, int64(stats.NumFeatures)))
+	fmt.Printf("memory estimate:  train %s, predict %s\n", formatBytes(stats.TrainMemoryBytes), formatBytes(stats.PredictMemBytes))
+	fmt.Printf("|wi| histogram:   %s\n", stats.WiHist)
+	fmt.Println()
+
+	fmt.Println("==========================================")
+	fmt.Println("Per-field vector blocks v_{i,f}")
+	fmt.Println("==========================================")
+	fmt.Printf("%-20s %10s %10s %10s\n", "field", "blocks", "non-zero", "ratio")
+	for _, fs := range stats.Fields {
+		fmt.Printf("%-20s %10d %10d %9.2f%%\n", fs.Field, fs.Blocks, fs.NonZeroBlocks, 100*ratio(fs.NonZeroBlocks, fs.Blocks))
+	}
+	fmt.Println()
+	for _, fs := range stats.Fields {
+		fmt.Printf("[%s]\n", fs.Field)
+		fmt.Printf("  |v| histogram:    %s\n", fs.ValueHist)
+		fmt.Printf("  ||v|| histogram:  %s\n", fs.NormHist)
+	}
+}
+
+func printFeature(m *model.FFMModel, feature string) bool {
+	dump := m.DumpFeature(feature)
+	if dump == nil {
+		return false
+	}
+	fmt.Printf("feature: %s\n", dump.Feature)
+	if len(dump.Tasks) > 0 {
+		// 多任务模型的 wi 属于第一个任务，其余任务依次列出
+		fmt.Printf("  task %s\n", m.Tasks[0])
+	}
+	fmt.Printf("  wi:  %.6g\n", dump.Wi)
+	fmt.Printf("  w_n: %.6g\n", dump.WNi)
+	fmt.Printf("  w_z: %.6g\n", dump.WZi)
+	for _, td := range dump.Tasks {
+		fmt.Printf("  task %s\n", td.Task)
+		fmt.Printf("  wi:  %.6g\n", td.W)
+		fmt.Printf("  w_n: %.6g\n", td.N)
+		fmt.Printf("  w_z: %.6g\n", td.Z)
+	}
+	for _, fd := range dump.Fields {
+		fmt.Printf("  [%s]\n", fd.Field)
+		fmt.Printf("    v:   %s\n", formatVector(fd.Vi))
+		fmt.Printf("    v_n: %s\n", formatVector(fd.VNi))
+		fmt.Printf("    v_z: %s\n", formatVector(fd.VZi))
+	}
+	return true
+}
+
+func main() {
+	modelPath := flag.String("m", "", "model path")
+	modelFormat := flag.String("mf", "txt", "model format")
+	dim := flag.Int("dim", 0, "factor num, 0 reads it from the model header")
+	feature := flag.String("feature", "", "feature to dump")
+
+	flag.Parse()
+
+	if *modelPath == "" {
+		fmt.Fprintln(os.Stderr, "model path required")
+		fmt.Fprint(os.Stderr, inspectHelp())
+		os.Exit(1)
+	}
+
+	m := model.NewFFMModel(*dim, 0.0, 0.0)
+	if err := m.LoadModel(*modelPath, *modelFormat); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to load model: %v\n", err)
+		os.Exit(1)
+	}
+
+	if *feature != "" {
+		if !printFeature(m, *feature) {
+			fmt.Fprintf(os.Stderr, "feature %s not found in model\n", *feature)
+			os.Exit(1)
+		}
+		return
+	}
+	printStats(m)
+}
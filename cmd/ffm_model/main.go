@@ -0,0 +1,240 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/xiongle/alphaFFM-go/pkg/model"
+)
+
+func modelHelp() string {
+	return `
+usage: ./ffm_model <command> [<options>]
+
+commands:
+diff: compare two models
+  ./ffm_model diff [<options>] <old_model> <new_model>
+  -mf <model_format>: model format of both models, txt or bin	default:txt
+  -dim <factor_num>: dim of 2-way interactions, read from the model header when omitted
+  -top <n>: number of most changed features to list	default:20
+  -list <n>: max number of added/removed features to list	default:20
+
+merge: average or sum several models, including the FTRL n/z state
+  ./ffm_model merge [<options>] <model1> <model2> ...
+  -out <model_path>: set the merged model path
+  -mf <model_format>: model format of the input models, txt or bin	default:txt
+  -of <model_format>: model format of the merged model, txt or bin	default:txt
+  -dim <factor_num>: dim of 2-way interactions, read from the model header when omitted
+  -op <op>: avg or sum	default:avg
+
+import-libffm: convert a libffm binary model to our model
+  ./ffm_model import-libffm [<options>] <libffm_model>
+  -out <model_path>: set the converted model path
+  -of <model_format>: model format of the converted model, txt or bin	default:txt
+
+export-libffm: convert a model trained with -input_format libffm to a libffm binary model
+  ./ffm_model export-libffm [<options>] <model>
+  -out <model_path>: set the libffm model path
+  -mf <model_format>: model format of the input model, txt or bin	default:txt
+  -dim <factor_num>: dim of 2-way interactions, read from the model header when omitted
+`
+}
+
+func loadModel(path, format string, dim int) *model.FFMModel {
+	m := model.NewFFMModel(dim, 0.0, 0.0)
+	if err := m.LoadModel(path, format); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to load model %s: %v\n", path, err)
+		os.Exit(1)
+	}
+	return m
+}
+
+// printList 输出列表，最多输出 limit 个
+func printList(title string, items []string, limit int) {
+	fmt.Printf("%s: %d\n", title, len(items))
+	for i, item := range items {
+		if i >= limit {
+			fmt.Printf("  ... %d more\n", len(items)-limit)
+			break
+		}
+		fmt.Printf("  %s\n", item)
+	}
+}
+
+func runDiff(args []string) {
+	fs := flag.NewFlagSet("diff", flag.ExitOnError)
+	modelFormat := fs.String("mf", "txt", "model format")
+	dim := fs.Int("dim", 0, "factor num")
+	top := fs.Int("top", 20, "top changed features")
+	list := fs.Int("list", 20, "max added/removed features listed")
+	fs.Parse(args)
+
+	if fs.NArg() != 2 {
+		fmt.Fprintln(os.Stderr, "diff requires exactly two models")
+		fmt.Fprint(os.Stderr, modelHelp())
+		os.Exit(1)
+	}
+
+	oldModel := loadModel(fs.Arg(0), *modelFormat, *dim)
+	newModel := loadModel(fs.Arg(1), *modelFormat, *dim)
+	diff, err := model.DiffModels(oldModel, newModel, *top)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "diff error: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Println("==========================================")
+	fmt.Printf("old: %s (%d features, %d fields)\n", fs.Arg(0), len(oldModel.MuMap), len(oldModel.FieldNames))
+	fmt.Printf("new: %s (%d features, %d fields)\n", fs.Arg(1), len(newModel.MuMap), len(newModel.FieldNames))
+	fmt.Println("==========================================")
+	fmt.Printf("common features: %d\n", diff.CommonFeatures)
+	printList("added features", diff.AddedFeatures, *list)
+	printList("removed features", diff.RemovedFeatures, *list)
+	printList("added fields", diff.AddedFields, *list)
+	printList("removed fields", diff.RemovedFields, *list)
+	fmt.Printf("bias delta: %.6g\n", diff.BiasDelta)
+	fmt.Printf("wi L2 drift: %.6g\n", diff.WiL2)
+	fmt.Println()
+
+	fmt.Println("per-field vector drift:")
+	fmt.Printf("  %-20s %12s %12s %10s\n", "field", "L2 drift", "old norm", "relative")
+	for _, d := range diff.FieldDrifts {
+		fmt.Printf("  %-20s %12.6g %12.6g %10.4g\n", d.Field, d.L2, d.OldNorm, d.Relative())
+	}
+	fmt.Println()
+
+	fmt.Printf("top %d changed features:\n", len(diff.TopChanged))
+	fmt.Printf("  %-30s %12s %12s\n", "feature", "L2 change", "wi delta")
+	for _, c := range diff.TopChanged {
+		fmt.Printf("  %-30s %12.6g %12.6g\n", c.Feature, c.L2, c.WiDelta)
+	}
+}
+
+func runMerge(args []string) {
+	fs := flag.NewFlagSet("merge", flag.ExitOnError)
+	out := fs.String("out", "", "merged model path")
+	modelFormat := fs.String("mf", "txt", "input model format")
+	outFormat := fs.String("of", "txt", "output model format")
+	dim := fs.Int("dim", 0, "factor num")
+	op := fs.String("op", model.MergeAvg, "avg or sum")
+	fs.Parse(args)
+
+	if *out == "" {
+		fmt.Fprintln(os.Stderr, "merged model path required")
+		fmt.Fprint(os.Stderr, modelHelp())
+		os.Exit(1)
+	}
+	if fs.NArg() < 2 {
+		fmt.Fprintln(os.Stderr, "merge requires at least two models")
+		fmt.Fprint(os.Stderr, modelHelp())
+		os.Exit(1)
+	}
+
+	models := make([]*model.FFMModel, 0, fs.NArg())
+	for _, path := range fs.Args() {
+		fmt.Printf("load model %s...\n", path)
+		models = append(models, loadModel(path, *modelFormat, *dim))
+	}
+
+	merged, err := model.MergeModels(models, *op)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "merge error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("merged %d models (%s): %d features, %d fields\n", len(models), *op, len(merged.MuMap), len(merged.FieldNames))
+
+	if err := merged.OutputModel(*out, *outFormat); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to output model: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Println("model outputting finished")
+}
+
+func runImportLibFFM(args []string) {
+	fs := flag.NewFlagSet("import-libffm", flag.ExitOnError)
+	out := fs.String("out", "", "converted model path")
+	outFormat := fs.String("of", "txt", "output model format")
+	fs.Parse(args)
+
+	if *out == "" || fs.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "import-libffm requires one libffm model and -out")
+		fmt.Fprint(os.Stderr, modelHelp())
+		os.Exit(1)
+	}
+
+	l, err := model.LoadLibFFMModel(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to load libffm model %s: %v\n", fs.Arg(0), err)
+		os.Exit(1)
+	}
+	fmt.Printf("libffm model: n=%d, m=%d, k=%d, normalization=%v\n", l.N, l.M, l.K, l.Normalization)
+	if l.Normalization {
+		fmt.Println("Warning: the libffm model was trained with instance-wise normalization, set \"instance_norm\": true in the field config (-field_config) when predicting or training with this model, otherwise scores will differ")
+	}
+
+	m := l.ToFFMModel()
+	fmt.Printf("converted: %d features, %d fields\n", len(m.MuMap), len(m.FieldNames))
+	if err := m.OutputModel(*out, *outFormat); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to output model: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Println("model outputting finished")
+}
+
+func runExportLibFFM(args []string) {
+	fs := flag.NewFlagSet("export-libffm", flag.ExitOnError)
+	out := fs.String("out", "", "libffm model path")
+	modelFormat := fs.String("mf", "txt", "input model format")
+	dim := fs.Int("dim", 0, "factor num")
+	fs.Parse(args)
+
+	if *out == "" || fs.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "export-libffm requires one model and -out")
+		fmt.Fprint(os.Stderr, modelHelp())
+		os.Exit(1)
+	}
+
+	m := loadModel(fs.Arg(0), *modelFormat, *dim)
+	export, err := model.ExportLibFFM(m)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "export error: %v\n", err)
+		os.Exit(1)
+	}
+	if export.DroppedBias != 0 || export.DroppedLinear > 0 {
+		fmt.Printf("Warning: libffm has no bias or linear terms, dropped bias %.6g and %d non-zero linear weights\n",
+			export.DroppedBias, export.DroppedLinear)
+	}
+
+	l := export.Model
+	fmt.Printf("libffm model: n=%d, m=%d, k=%d\n", l.N, l.M, l.K)
+	if err := l.Save(*out); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to output libffm model: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Println("model outputting finished")
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, modelHelp())
+		os.Exit(1)
+	}
+
+	switch os.Args[1] {
+	case "diff":
+		runDiff(os.Args[2:])
+	case "merge":
+		runMerge(os.Args[2:])
+	case "import-libffm":
+		runImportLibFFM(os.Args[2:])
+	case "export-libffm":
+		runExportLibFFM(os.Args[2:])
+	case "-h", "-help", "--help", "help":
+		fmt.Print(modelHelp())
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command: %s\n", os.Args[1])
+		fmt.Fprint(os.Stderr, modelHelp())
+		os.Exit(1)
+	}
+}
@@ -6,23 +6,38 @@ import (
 	"os"
 
 	"github.com/xiongle/alphaFFM-go/pkg/frame"
+	"github.com/xiongle/alphaFFM-go/pkg/input"
+	"github.com/xiongle/alphaFFM-go/pkg/metrics"
 	"github.com/xiongle/alphaFFM-go/pkg/model"
+	"github.com/xiongle/alphaFFM-go/pkg/sample"
 	"github.com/xiongle/alphaFFM-go/pkg/simd"
+	"github.com/xiongle/alphaFFM-go/pkg/utils"
 )
 
 func predictHelp() string {
 	return `
 usage: cat sample | ./ffm_predict [<options>]
+   or: ./ffm_predict [<options>] <file|glob> ...
+
+input files may be gzip compressed, stdin is read when no file is given
 
 options:
 -m <model_path>: set the model path
 -mf <model_format>: set the model format, txt or bin	default:txt
--dim <factor_num>: dim of 2-way interactions	default:8
+-dim <factor_num>: dim of 2-way interactions, read from the model header when omitted
 -core <threads_num>: set the number of threads	default:1
+-producers <n>: number of input files read in parallel, 0 means the same as -core	default:0
 -out <predict_path>: set the predict path
 -mnt <model_number_type>: double or float	default:double
 -simd <simd_type>: SIMD optimization type (scalar, blas)	default:scalar
 -field_config <config_path>: field mapping config file (JSON or text format)
+-input_format <format>: ffm, libffm (label field_id:feature_id:value with integer ids), csv, tsv or jsonl	default:ffm
+-schema <schema_path>: JSON column schema for csv/tsv input (label, weight, feature and ignored columns)
+-on_error <policy>: skip invalid samples, or fail at the first one	default:skip
+-max_error_rate <rate>: fail when the fraction of invalid samples exceeds rate, 0 disables the check	default:0
+-rejected <path>: write invalid samples to path as location<TAB>reason<TAB>line
+-explain <top_n>: output one JSON per sample decomposing the logit into bias, per-feature and pairwise terms, keeping the top_n contributors (single-task models only)	default:0
+-metrics_addr <host:port>: serve Prometheus metrics at http://host:port/metrics while running
 `
 }
 
@@ -32,15 +47,32 @@ func main() {
 
 	modelPath := flag.String("m", "", "model path")
 	modelFormat := flag.String("mf", "txt", "model format")
-	dim := flag.Int("dim", 8, "factor num")
+	dim := flag.Int("dim", 0, "factor num, 0 reads it from the model header")
 	core := flag.Int("core", 1, "threads num")
+	producers := flag.Int("producers", 0, "input files read in parallel")
 	out := flag.String("out", "", "predict path")
 	mnt := flag.String("mnt", "double", "model number type")
 	simdType := flag.String("simd", "scalar", "SIMD optimization type")
 	fieldConfig := flag.String("field_config", "", "field mapping config file")
+	inputFormat := flag.String("input_format", sample.InputFormatFFM, "input format: ffm, libffm, csv, tsv or jsonl")
+	schema := flag.String("schema", "", "column schema for csv/tsv input")
+	onError := flag.String("on_error", sample.OnErrorSkip, "invalid sample policy: skip or fail")
+	maxErrorRate := flag.Float64("max_error_rate", 0, "max fraction of invalid samples")
+	rejected := flag.String("rejected", "", "file for invalid samples")
+	explain := flag.Int("explain", 0, "explain top n contributors per sample")
+	metricsAddr := flag.String("metrics_addr", "", "metrics listen address")
 
 	flag.Parse()
 
+	inputs := []string{input.Stdin}
+	if flag.NArg() > 0 {
+		var err error
+		if inputs, err = input.Expand(flag.Args()); err != nil {
+			fmt.Fprintf(os.Stderr, "invalid input: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
 	// 设置选项
 	opt.ModelPath = *modelPath
 	opt.ModelFormat = *modelFormat
@@ -49,6 +81,20 @@ func main() {
 	opt.PredictPath = *out
 	opt.ModelNumberType = *mnt
 	opt.FieldConfigPath = *fieldConfig
+	opt.InputFormat = *inputFormat
+	opt.SchemaPath = *schema
+	opt.ErrorPolicy = sample.ErrorPolicy{OnError: *onError, MaxErrorRate: *maxErrorRate, RejectedPath: *rejected}
+	if err := opt.ErrorPolicy.Validate(); err != nil {
+		fmt.Fprintf(os.Stderr, "invalid error policy: %v\n", err)
+		fmt.Fprint(os.Stderr, predictHelp())
+		os.Exit(1)
+	}
+	if err := model.CheckInput(opt.InputFormat, opt.SchemaPath); err != nil {
+		fmt.Fprintf(os.Stderr, "invalid input: %v\n", err)
+		fmt.Fprint(os.Stderr, predictHelp())
+		os.Exit(1)
+	}
+	opt.ExplainTopN = *explain
 	
 	// 解析SIMD类型
 	parsedSIMD, err := simd.ParseVectorOpsType(*simdType)
@@ -81,9 +127,29 @@ func main() {
 	defer predictor.Close()
 
 	// 运行预测框架
+	ctx, cancel := utils.SignalContext()
+	defer cancel()
 	pcFrame := frame.NewPCFrame()
 	pcFrame.Init(predictor, opt.ThreadsNum)
-	if err := pcFrame.Run(os.Stdin); err != nil {
+	if *metricsAddr != "" {
+		addr, err := metrics.Serve(*metricsAddr)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+		predictor.RegisterMetrics(metrics.Default)
+		pcFrame.RegisterMetrics(metrics.Default)
+		fmt.Printf("metrics available at http://%s/metrics\n", addr)
+	}
+	if *producers <= 0 {
+		*producers = opt.ThreadsNum
+	}
+	err = pcFrame.RunFiles(ctx, inputs, *producers)
+	fmt.Println(predictor.InputSummary())
+	if finishErr := predictor.FinishInput(); err == nil {
+		err = finishErr
+	}
+	if err != nil {
 		fmt.Fprintf(os.Stderr, "prediction error: %v\n", err)
 		os.Exit(1)
 	}
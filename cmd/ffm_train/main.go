@@ -1,6 +1,8 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"math/rand"
@@ -10,13 +12,20 @@ import (
 	"time"
 
 	"github.com/xiongle/alphaFFM-go/pkg/frame"
+	"github.com/xiongle/alphaFFM-go/pkg/input"
+	"github.com/xiongle/alphaFFM-go/pkg/metrics"
 	"github.com/xiongle/alphaFFM-go/pkg/model"
+	"github.com/xiongle/alphaFFM-go/pkg/sample"
 	"github.com/xiongle/alphaFFM-go/pkg/simd"
+	"github.com/xiongle/alphaFFM-go/pkg/utils"
 )
 
 func trainHelp() string {
 	return `
 usage: cat sample | ./ffm_train [<options>]
+   or: ./ffm_train [<options>] <file|glob> ...
+
+input files may be gzip compressed, stdin is read when no file is given
 
 options:
 -m <model_path>: set the output model path
@@ -32,12 +41,27 @@ options:
 -v_l1 <v_L1_reg>: L1 regularization parameter of v	default:0.1
 -v_l2 <v_L2_reg>: L2 regularization parameter of v	default:5.0
 -core <threads_num>: set the number of threads	default:1
+-producers <n>: number of input files read in parallel, 0 means the same as -core	default:0
 -im <initial_model_path>: set the initial model path
 -imf <initial_model_format>: set the initial model format, txt or bin	default:txt
 -fvs <force_v_sparse>: if fvs is 1, set vi = 0 whenever wi = 0	default:0
 -mnt <model_number_type>: double or float	default:double
 -simd <simd_type>: SIMD optimization type (scalar, blas)	default:scalar
 -field_config <config_path>: field mapping config file (JSON or text format)
+-input_format <format>: ffm, libffm (label field_id:feature_id:value with integer ids), csv, tsv or jsonl	default:ffm
+-schema <schema_path>: JSON column schema for csv/tsv input (label, weight, feature and ignored columns)
+-on_error <policy>: skip invalid samples, or fail at the first one	default:skip
+-max_error_rate <rate>: fail when the fraction of invalid samples exceeds rate, 0 disables the check	default:0
+-rejected <path>: write invalid samples to path as location<TAB>reason<TAB>line
+-checkpoint_dir <dir>: directory for periodic checkpoints
+-checkpoint_every <n|duration>: write a checkpoint every n lines (e.g. 1000000) or every duration (e.g. 10m)
+-resume: resume from the latest checkpoint in checkpoint_dir and skip the input lines it already covers
+-progress_every <n|duration>: report progress every n samples (e.g. 1000000) or every duration (e.g. 30s), 0 disables	default:200000
+-progress_format <format>: progress output format, text or json (one JSON object per line)	default:text
+-progress_window <n>: number of recent samples for the rolling logloss and AUC	default:100000
+-progress_out <path>: write progress reports to path instead of stdout
+-metrics_addr <host:port>: serve Prometheus metrics at http://host:port/metrics while running
+-tasks <names>: comma separated task names for multi-task training (e.g. click,conv,like), labels are given as 1,0,- in the same order, - for missing
 `
 }
 
@@ -84,15 +108,39 @@ func main() {
 	vL1 := flag.Float64("v_l1", 0.1, "v L1")
 	vL2 := flag.Float64("v_l2", 5.0, "v L2")
 	core := flag.Int("core", 1, "threads num")
+	producers := flag.Int("producers", 0, "input files read in parallel")
 	initModelPath := flag.String("im", "", "initial model path")
 	initModelFormat := flag.String("imf", "txt", "initial model format")
 	fvs := flag.Int("fvs", 0, "force v sparse")
 	mnt := flag.String("mnt", "double", "model number type")
 	simdType := flag.String("simd", "scalar", "SIMD optimization type")
 	fieldConfig := flag.String("field_config", "", "field mapping config file")
+	inputFormat := flag.String("input_format", sample.InputFormatFFM, "input format: ffm, libffm, csv, tsv or jsonl")
+	schema := flag.String("schema", "", "column schema for csv/tsv input")
+	onError := flag.String("on_error", sample.OnErrorSkip, "invalid sample policy: skip or fail")
+	maxErrorRate := flag.Float64("max_error_rate", 0, "max fraction of invalid samples")
+	rejected := flag.String("rejected", "", "file for invalid samples")
+	checkpointDir := flag.String("checkpoint_dir", "", "checkpoint dir")
+	checkpointEvery := flag.String("checkpoint_every", "", "checkpoint interval, lines or duration")
+	resume := flag.Bool("resume", false, "resume from latest checkpoint")
+	progressEvery := flag.String("progress_every", "200000", "progress interval, samples or duration")
+	progressFormat := flag.String("progress_format", model.ProgressText, "progress format: text or json")
+	progressWindow := flag.Int("progress_window", model.DefaultProgressWindow, "samples for rolling metrics")
+	progressOut := flag.String("progress_out", "", "progress output file")
+	metricsAddr := flag.String("metrics_addr", "", "metrics listen address")
+	tasks := flag.String("tasks", "", "comma separated task names for multi-task training")
 
 	flag.Parse()
 
+	inputs := []string{input.Stdin}
+	if flag.NArg() > 0 {
+		var err error
+		if inputs, err = input.Expand(flag.Args()); err != nil {
+			fmt.Fprintf(os.Stderr, "invalid input: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
 	// 解析dim参数
 	k0, k1, k2, err := parseDim(*dimStr)
 	if err != nil {
@@ -122,7 +170,70 @@ func main() {
 	opt.ForceVSparse = *fvs == 1
 	opt.ModelNumberType = *mnt
 	opt.FieldConfigPath = *fieldConfig
-	
+	opt.InputFormat = *inputFormat
+	opt.SchemaPath = *schema
+	opt.Inputs = inputs
+	opt.ErrorPolicy = sample.ErrorPolicy{OnError: *onError, MaxErrorRate: *maxErrorRate, RejectedPath: *rejected}
+	if err := opt.ErrorPolicy.Validate(); err != nil {
+		fmt.Fprintf(os.Stderr, "invalid error policy: %v\n", err)
+		fmt.Fprint(os.Stderr, trainHelp())
+		os.Exit(1)
+	}
+	if err := model.CheckInput(opt.InputFormat, opt.SchemaPath); err != nil {
+		fmt.Fprintf(os.Stderr, "invalid input: %v\n", err)
+		fmt.Fprint(os.Stderr, trainHelp())
+		os.Exit(1)
+	}
+	opt.CheckpointDir = *checkpointDir
+	opt.Resume = *resume
+
+	// 解析检查点间隔
+	opt.CheckpointLines, opt.CheckpointInterval, err = utils.ParseInterval(*checkpointEvery)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid checkpoint_every: %v\n", err)
+		fmt.Fprint(os.Stderr, trainHelp())
+		os.Exit(1)
+	}
+	if (opt.CheckpointLines > 0 || opt.CheckpointInterval > 0 || opt.Resume) && opt.CheckpointDir == "" {
+		fmt.Fprintln(os.Stderr, "checkpoint_every and resume require checkpoint_dir")
+		fmt.Fprint(os.Stderr, trainHelp())
+		os.Exit(1)
+	}
+
+	// 解析多任务选项
+	if opt.Tasks, err = model.ParseTasks(*tasks); err == nil {
+		err = model.CheckTaskInput(opt.Tasks, *inputFormat)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid tasks: %v\n", err)
+		fmt.Fprint(os.Stderr, trainHelp())
+		os.Exit(1)
+	}
+
+	// 解析进度报告选项
+	opt.Progress.EveryLines, opt.Progress.Every, err = utils.ParseInterval(*progressEvery)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid progress_every: %v\n", err)
+		fmt.Fprint(os.Stderr, trainHelp())
+		os.Exit(1)
+	}
+	opt.Progress.Format = *progressFormat
+	opt.Progress.Window = *progressWindow
+	if err := opt.Progress.Validate(); err != nil {
+		fmt.Fprintf(os.Stderr, "invalid progress option: %v\n", err)
+		fmt.Fprint(os.Stderr, trainHelp())
+		os.Exit(1)
+	}
+	if *progressOut != "" && opt.Progress.Enabled() {
+		f, err := os.Create(*progressOut)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to open progress output: %v\n", err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		opt.Progress.Output = f
+	}
+
 	// 解析SIMD类型
 	parsedSIMD, err := simd.ParseVectorOpsType(*simdType)
 	if err != nil {
@@ -146,6 +257,25 @@ func main() {
 	// 创建训练器
 	trainer := model.NewFFMTrainer(opt)
 
+	// 从检查点恢复
+	var skipLines int64
+	if opt.Resume {
+		skipLines, err = trainer.ResumeFromCheckpoint(opt.CheckpointDir)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to resume from checkpoint: %v\n", err)
+			os.Exit(1)
+		}
+		if skipLines > 0 {
+			fmt.Printf("resumed from checkpoint at line %d\n", skipLines)
+			if opt.BInit {
+				fmt.Println("initial model ignored, training continues from checkpoint")
+				opt.BInit = false
+			}
+		} else {
+			fmt.Println("no checkpoint found, training from scratch")
+		}
+	}
+
 	// 如果需要加载初始模型
 	if opt.BInit {
 		fmt.Println("load model...")
@@ -157,12 +287,53 @@ func main() {
 	}
 
 	// 运行训练框架
+	ctx, cancel := utils.SignalContext()
+	defer cancel()
 	pcFrame := frame.NewPCFrame()
 	pcFrame.Init(trainer, opt.ThreadsNum)
-	if err := pcFrame.Run(os.Stdin); err != nil {
+	if *metricsAddr != "" {
+		addr, err := metrics.Serve(*metricsAddr)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+		trainer.RegisterMetrics(metrics.Default)
+		pcFrame.RegisterMetrics(metrics.Default)
+		fmt.Printf("metrics available at http://%s/metrics\n", addr)
+	}
+	pcFrame.SetSkipLines(skipLines)
+	if opt.Progress.Enabled() {
+		pcFrame.SetLogNum(0)
+	}
+	if opt.CheckpointLines > 0 || opt.CheckpointInterval > 0 {
+		pcFrame.SetCheckpoint(opt.CheckpointLines, opt.CheckpointInterval, func(lines int64) error {
+			return trainer.SaveCheckpoint(opt.CheckpointDir, lines)
+		})
+	}
+	if *producers <= 0 {
+		*producers = opt.ThreadsNum
+	}
+	err = pcFrame.RunFiles(ctx, inputs, *producers)
+	trainer.FinishProgress()
+	fmt.Println(trainer.InputSummary())
+	if summary := trainer.ValidationSummary(); summary != "" {
+		fmt.Println(summary)
+	}
+	// 被中断时模型只包含已分发批次的训练结果，仍然输出，便于之后继续训练
+	interrupted := errors.Is(err, context.Canceled)
+	if interrupted {
+		err = nil
+	}
+	if finishErr := trainer.FinishInput(); err == nil {
+		err = finishErr
+	}
+	if err != nil {
 		fmt.Fprintf(os.Stderr, "training error: %v\n", err)
 		os.Exit(1)
 	}
+	if summary := trainer.FieldMappingSummary(); summary != "" {
+		fmt.Print(summary)
+	}
 
 	// 输出模型
 	fmt.Println("output model...")
@@ -171,5 +342,9 @@ func main() {
 		os.Exit(1)
 	}
 	fmt.Println("model outputting finished")
+	if interrupted {
+		fmt.Println("training interrupted, the model covers the input consumed before the signal")
+		os.Exit(130)
+	}
 }
 
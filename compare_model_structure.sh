@@ -42,9 +42,10 @@ echo ""
 # FFM 统计
 FFM_LINES=$(wc -l < /data/xiongle/alphaFFM-go/ffm_compare.txt)
 FFM_SIZE=$(wc -c < /data/xiongle/alphaFFM-go/ffm_compare.txt)
-FFM_FIELDS=$(head -1 /data/xiongle/alphaFFM-go/ffm_compare.txt | awk '{print NF-1}')
-FFM_BIAS_FIELDS=$(head -2 /data/xiongle/alphaFFM-go/ffm_compare.txt | tail -1 | awk '{print NF}')
-FFM_FEAT_FIELDS=$(head -3 /data/xiongle/alphaFFM-go/ffm_compare.txt | tail -1 | awk '{print NF}')
+# FFM 模型第一行是 META 元信息头
+FFM_FIELDS=$(head -2 /data/xiongle/alphaFFM-go/ffm_compare.txt | tail -1 | awk '{print NF-1}')
+FFM_BIAS_FIELDS=$(head -3 /data/xiongle/alphaFFM-go/ffm_compare.txt | tail -1 | awk '{print NF}')
+FFM_FEAT_FIELDS=$(head -4 /data/xiongle/alphaFFM-go/ffm_compare.txt | tail -1 | awk '{print NF}')
 
 echo "FFM 模型:"
 echo "  文件大小: $FFM_SIZE bytes"
@@ -38,7 +38,7 @@ echo ""
 
 # 6. 查看模型中的域
 echo "6. 模型中的域定义："
-head -1 model_with_numeric.txt
+grep -m1 "^FIELDS" model_with_numeric.txt
 echo ""
 echo "   说明："
 echo "   - field_12, field_13, field_14: 自动从大数字特征提取"
@@ -29,7 +29,7 @@ echo ""
 
 # 4. 查看模型中的域
 echo "4. 模型中的域定义:"
-head -1 model_with_config.txt
+grep -m1 "^FIELDS" model_with_config.txt
 echo ""
 
 # 5. 预测
@@ -0,0 +1,85 @@
+package config
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+)
+
+// 分桶类型
+const (
+	BucketBoundaries = "boundaries" // 固定边界（默认）
+	BucketLog        = "log"        // 对数分桶
+	BucketQuantile   = "quantile"   // 分位数边界，由 ffm_fit_buckets 在预扫描中学习并写入 Boundaries
+)
+
+// BucketRule 数值分桶规则
+//   - boundaries / quantile: v < Boundaries[0] 为桶0，Boundaries[i-1] <= v < Boundaries[i] 为桶i，
+//     v >= 最后一个边界为桶 len(Boundaries)
+//   - log: |v| < 1 为桶0，否则桶号为 1+floor(log_base(|v|))，负数取负的桶号
+type BucketRule struct {
+	Type       string    `json:"type,omitempty"`
+	Boundaries []float64 `json:"boundaries,omitempty"`
+	Base       float64   `json:"base,omitempty"` // 对数分桶的底数，默认 e
+
+	// Buckets 分位数分桶的桶数，仅供 ffm_fit_buckets 使用
+	Buckets int `json:"buckets,omitempty"`
+}
+
+// Validate 验证分桶规则并补全默认值
+func (r *BucketRule) Validate() error {
+	switch r.Type {
+	case "":
+		r.Type = BucketBoundaries
+		return r.Validate()
+	case BucketBoundaries, BucketQuantile:
+		if len(r.Boundaries) == 0 {
+			if r.Type == BucketQuantile {
+				return fmt.Errorf("quantile bucket boundaries are empty, learn them with ffm_fit_buckets first")
+			}
+			return fmt.Errorf("bucket boundaries cannot be empty")
+		}
+		for i := 1; i < len(r.Boundaries); i++ {
+			if r.Boundaries[i] <= r.Boundaries[i-1] {
+				return fmt.Errorf("bucket boundaries must be strictly increasing: %v", r.Boundaries)
+			}
+		}
+	case BucketLog:
+		if r.Base == 0 {
+			r.Base = math.E
+		}
+		if r.Base <= 1 {
+			return fmt.Errorf("log bucket base must be greater than 1: %v", r.Base)
+		}
+	default:
+		return fmt.Errorf("unsupported bucket type: %s (must be boundaries, log or quantile)", r.Type)
+	}
+	return nil
+}
+
+// Bucket 返回值所在的桶
+func (r *BucketRule) Bucket(v float64) int {
+	if r.Type == BucketLog {
+		a := math.Abs(v)
+		if a < 1 {
+			return 0
+		}
+		b := 1 + int(math.Floor(math.Log(a)/math.Log(r.Base)))
+		if v < 0 {
+			return -b
+		}
+		return b
+	}
+	return sort.Search(len(r.Boundaries), func(i int) bool {
+		return r.Boundaries[i] > v
+	})
+}
+
+// BucketFeature 分桶后的特征名，例如 age 的第5个桶为 age_b5，负数桶 -2 为 age_bn2
+func BucketFeature(name string, bucket int) string {
+	if bucket < 0 {
+		return name + "_bn" + strconv.Itoa(-bucket)
+	}
+	return name + "_b" + strconv.Itoa(bucket)
+}
@@ -0,0 +1,61 @@
+package config
+
+import (
+	"math"
+	"testing"
+)
+
+func TestBucketRule(t *testing.T) {
+	fixed := &BucketRule{Boundaries: []float64{18, 25, 35}}
+	if err := fixed.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	for v, want := range map[float64]int{0: 0, 18: 1, 24.9: 1, 25: 2, 35: 3, 80: 3} {
+		if got := fixed.Bucket(v); got != want {
+			t.Errorf("fixed bucket(%v): got %d, want %d", v, got, want)
+		}
+	}
+
+	log2 := &BucketRule{Type: BucketLog, Base: 2}
+	if err := log2.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	for v, want := range map[float64]int{0.5: 0, 1: 1, 3: 2, 4: 3, -4: -3} {
+		if got := log2.Bucket(v); got != want {
+			t.Errorf("log bucket(%v): got %d, want %d", v, got, want)
+		}
+	}
+	if got := BucketFeature("age", -3); got != "age_bn3" {
+		t.Errorf("negative bucket feature: got %s", got)
+	}
+
+	for _, r := range []*BucketRule{
+		{Boundaries: []float64{1, 1}},
+		{Type: BucketQuantile},
+		{Type: BucketLog, Base: 0.5},
+		{Type: "sqrt"},
+	} {
+		if err := r.Validate(); err == nil {
+			t.Errorf("expected error for %+v", r)
+		}
+	}
+}
+
+func TestDiscretize(t *testing.T) {
+	c := NewFieldConfig()
+	c.FeatureToField["age"] = "user"
+	c.Buckets = map[string]*BucketRule{"age": {Boundaries: []float64{18, 25, 35}}}
+	if err := c.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	if f, v, err := c.Discretize("age", 37.2); err != nil || f != "age_b3" || v != 1 {
+		t.Errorf("age: got %s %v %v", f, v, err)
+	}
+	if f, v, _ := c.Discretize("price", 9.5); f != "price" || v != 9.5 {
+		t.Errorf("unbucketed feature changed: %s %v", f, v)
+	}
+	if _, _, err := c.Discretize("age", math.NaN()); err == nil {
+		t.Error("expected error for NaN")
+	}
+}
@@ -0,0 +1,135 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// 列类型
+const (
+	ColumnCategorical = "categorical" // 类别列: 特征名为 列名=取值，值为1
+	ColumnNumeric     = "numeric"     // 数值列: 特征名为列名，值为列值；配置了分桶时转为类别特征 列名_b桶号
+)
+
+// CSVColumn 单列的定义
+type CSVColumn struct {
+	Name string `json:"name"`
+	// Field 列所属的域，为空时使用列名
+	Field string `json:"field"`
+	// Type categorical 或 numeric，默认 categorical
+	Type string `json:"type"`
+	// Separator 类别列的多值分隔符（如 "|"），为空表示单值
+	Separator string `json:"separator"`
+	// Buckets 数值列的分桶规则
+	Buckets *BucketRule `json:"buckets,omitempty"`
+}
+
+// CSVSchema CSV/TSV 输入的列定义
+// 输入中的每一列都必须是标签列、权重列、已定义的特征列或忽略列之一，
+// 出现未声明的列时报错，避免上游改了日志格式后静默地训练出错误的模型
+type CSVSchema struct {
+	// Delimiter 列分隔符，为空时按输入格式决定（csv 为逗号，tsv 为制表符）
+	Delimiter string `json:"delimiter"`
+	// Header 输入首行是否为表头
+	Header bool `json:"header"`
+	// ColumnNames 没有表头时的列名（按列顺序）
+	ColumnNames []string `json:"column_names"`
+	// Label 标签列，值大于0为正样本
+	Label string `json:"label"`
+	// Weight 样本权重列，为空表示权重都为1
+	Weight string `json:"weight"`
+	// Columns 特征列
+	Columns []CSVColumn `json:"columns"`
+	// Ignore 忽略的列
+	Ignore []string `json:"ignore"`
+}
+
+// LoadCSVSchema 从JSON文件加载并验证列定义
+func LoadCSVSchema(path string) (*CSVSchema, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open schema file: %v", err)
+	}
+	defer file.Close()
+
+	s := &CSVSchema{}
+	decoder := json.NewDecoder(file)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(s); err != nil {
+		return nil, fmt.Errorf("failed to parse schema file: %v", err)
+	}
+	if err := s.Validate(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Validate 验证列定义并补全默认值
+func (s *CSVSchema) Validate() error {
+	if s.Label == "" {
+		return fmt.Errorf("schema label column is required")
+	}
+	if len(s.Delimiter) > 1 {
+		return fmt.Errorf("schema delimiter must be a single character: %q", s.Delimiter)
+	}
+	if !s.Header && len(s.ColumnNames) == 0 {
+		return fmt.Errorf("schema without header requires column_names")
+	}
+
+	seen := map[string]bool{s.Label: true}
+	if s.Weight != "" {
+		if seen[s.Weight] {
+			return fmt.Errorf("column %s declared more than once", s.Weight)
+		}
+		seen[s.Weight] = true
+	}
+	for i := range s.Columns {
+		c := &s.Columns[i]
+		if c.Name == "" {
+			return fmt.Errorf("schema column %d has no name", i)
+		}
+		if strings.ContainsAny(c.Name+c.Field, " \t") {
+			return fmt.Errorf("column %s: name and field cannot contain whitespace", c.Name)
+		}
+		if seen[c.Name] {
+			return fmt.Errorf("column %s declared more than once", c.Name)
+		}
+		seen[c.Name] = true
+
+		if c.Field == "" {
+			c.Field = c.Name
+		}
+		switch c.Type {
+		case "":
+			c.Type = ColumnCategorical
+		case ColumnCategorical, ColumnNumeric:
+		default:
+			return fmt.Errorf("column %s: unsupported type %s (must be categorical or numeric)", c.Name, c.Type)
+		}
+		if c.Buckets != nil {
+			if c.Type != ColumnNumeric {
+				return fmt.Errorf("column %s: buckets require numeric type", c.Name)
+			}
+			if err := c.Buckets.Validate(); err != nil {
+				return fmt.Errorf("column %s: %v", c.Name, err)
+			}
+		}
+		if c.Separator != "" && c.Type != ColumnCategorical {
+			return fmt.Errorf("column %s: separator requires categorical type", c.Name)
+		}
+	}
+	for _, name := range s.Ignore {
+		if seen[name] {
+			return fmt.Errorf("column %s declared more than once", name)
+		}
+		seen[name] = true
+	}
+	for _, name := range s.ColumnNames {
+		if !seen[name] {
+			return fmt.Errorf("column %s is not declared in schema (add it to columns or ignore)", name)
+		}
+	}
+	return nil
+}
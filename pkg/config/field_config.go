@@ -2,11 +2,14 @@ package config
 
 import (
 	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"math"
 	"os"
-	"strconv"
 	"strings"
+	"sync"
 )
 
 // FieldConfig 域配置
@@ -19,18 +22,64 @@ type FieldConfig struct {
 	// value: 域名
 	FeatureToField map[string]string `json:"feature_to_field"`
 	
+	// Rules 有序的域映射规则（exact、prefix、suffix、regex、split、range），先于 FeatureToField 匹配
+	Rules []*FieldRule `json:"rules,omitempty"`
+	
 	// DefaultField 默认域名（当特征无法匹配到任何规则时使用）
 	DefaultField string `json:"default_field"`
 	
 	// UsePrefix 是否使用前缀匹配（true: 前缀匹配, false: 完全匹配）
 	UsePrefix bool `json:"use_prefix"`
 	
-	// NumericFieldThreshold 数字特征阈值，大于此值时自动提取高32位作为域ID
-	// 默认值: 4294967296 (2^32)
+	// NumericFieldThreshold 数字特征阈值，全数字且大于等于此值的特征按位布局自动提取域ID
+	// 默认值: 1000000（高于位布局中域1的起点时按0处理）；0 表示使用第一个非0域ID的起点（默认布局下为 2^32）
 	NumericFieldThreshold uint64 `json:"numeric_field_threshold"`
 	
 	// NumericFieldPrefix 数字域的前缀（如 "field_"），生成的域名格式为 prefix + 域ID
 	NumericFieldPrefix string `json:"numeric_field_prefix"`
+
+	// NumericFieldBits 数字特征中作为域ID的高位位数（1-63），默认32（高32位为域ID）
+	NumericFieldBits int `json:"numeric_field_bits,omitempty"`
+
+	// NumericFieldMask 自定义域ID掩码（如 "0x00FF000000000000"），域ID = (特征 & 掩码) >> 掩码末尾0的个数
+	// 必须是连续的1，不能与 NumericFieldBits 同时使用
+	NumericFieldMask string `json:"numeric_field_mask,omitempty"`
+
+	// Buckets 数值特征的分桶规则，key 为特征名
+	// 解析时 age:37.2 转为同一域中的类别特征 age_b5，值为1
+	Buckets map[string]*BucketRule `json:"buckets,omitempty"`
+
+	// FieldNorm 按域归一化同一域内所有特征的值，key 为域名（"*" 表示所有域），
+	// value 为 l1（除以绝对值之和）、l2（除以L2范数）或 count（除以特征个数）
+	FieldNorm map[string]string `json:"field_norm,omitempty"`
+
+	// InstanceNorm 样本级L2归一化: 所有特征值除以整个样本的L2范数（libffm 默认的归一化方式）
+	InstanceNorm bool `json:"instance_norm,omitempty"`
+
+	layout      numericLayout // Validate 时计算的数字特征位布局
+	matcher     *fieldMatcher // Validate 时编译的域映射
+	matcherOnce sync.Once     // 未经 Validate 的配置在第一次查找时编译域映射
+	matcherErr  error         // 未经 Validate 的配置编译域映射规则时的错误
+}
+
+// 域归一化方式
+const (
+	NormL1    = "l1"
+	NormL2    = "l2"
+	NormCount = "count"
+)
+
+// FieldNormFor 获取域的归一化方式，没有配置时返回空
+func (c *FieldConfig) FieldNormFor(field string) string {
+	if norm, ok := c.FieldNorm[field]; ok {
+		return norm
+	}
+	return c.FieldNorm["*"]
+}
+
+// HasNormalization 是否配置了任何归一化
+func (c *FieldConfig) HasNormalization() bool {
+	return len(c.FieldNorm) > 0 || c.InstanceNorm
 }
 
 // NewFieldConfig 创建默认配置
@@ -40,11 +89,29 @@ func NewFieldConfig() *FieldConfig {
 		FeatureToField:        make(map[string]string),
 		DefaultField:          "",
 		UsePrefix:             true,
-		NumericFieldThreshold: 1000000,
+		NumericFieldThreshold: DefaultNumericFieldThreshold,
 		NumericFieldPrefix:    "field_",
 	}
 }
 
+// Clone 复制配置（map 和规则与原配置共享），不复制 Validate 的编译结果，副本使用前须重新 Validate
+func (c *FieldConfig) Clone() *FieldConfig {
+	return &FieldConfig{
+		Mode:                  c.Mode,
+		FeatureToField:        c.FeatureToField,
+		Rules:                 c.Rules,
+		DefaultField:          c.DefaultField,
+		UsePrefix:             c.UsePrefix,
+		NumericFieldThreshold: c.NumericFieldThreshold,
+		NumericFieldPrefix:    c.NumericFieldPrefix,
+		NumericFieldBits:      c.NumericFieldBits,
+		NumericFieldMask:      c.NumericFieldMask,
+		Buckets:               c.Buckets,
+		FieldNorm:             c.FieldNorm,
+		InstanceNorm:          c.InstanceNorm,
+	}
+}
+
 // LoadFromJSON 从JSON文件加载配置
 func (c *FieldConfig) LoadFromJSON(path string) error {
 	file, err := os.Open(path)
@@ -122,68 +189,56 @@ func (c *FieldConfig) SaveToJSON(path string) error {
 	return nil
 }
 
+// Hash 计算配置的摘要（用于在模型中记录训练时使用的配置）
+// 对配置的规范化JSON（map按key排序）取SHA-256，返回前16位十六进制
+func (c *FieldConfig) Hash() string {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:8])
+}
+
 // GetFieldForFeature 根据特征名获取对应的域名
-// 返回: (域名, 错误)
-// 三种处理规则:
-// 1. 大数字特征 (>= threshold): 自动提取高32位作为域ID
-// 2. 小特征: 必须在配置文件中找到映射，否则报错
-// 3. explicit模式: 返回空（使用样本中的域名）
+// 返回: (域名, 错误)，规则见 ResolveField
 func (c *FieldConfig) GetFieldForFeature(feature string) (string, error) {
-	// 如果是 explicit 模式，不进行映射（样本中已包含域信息）
-	if c.Mode == "explicit" {
-		return "", nil
-	}
-
-	// 规则1: 检查是否是大数字特征（高32位编码域ID）
-	// 只有全数字且大于等于阈值时才自动提取
-	if numFeature, err := strconv.ParseUint(feature, 10, 64); err == nil {
-		if numFeature >= c.NumericFieldThreshold {
-			// 提取高32位作为域ID
-			fieldID := uint32(numFeature >> 32)
-			return fmt.Sprintf("%s%d", c.NumericFieldPrefix, fieldID), nil
-		}
-	}
+	return ResolveField(c, feature)
+}
 
-	// 规则2: 小特征必须在配置文件中找到映射
-	if c.UsePrefix {
-		// 前缀匹配：按长度从长到短尝试匹配
-		maxLen := 0
-		matchedField := ""
-		for prefix, field := range c.FeatureToField {
-			if strings.HasPrefix(feature, prefix) && len(prefix) > maxLen {
-				maxLen = len(prefix)
-				matchedField = field
-			}
-		}
-		if matchedField != "" {
-			return matchedField, nil
-		}
-	} else {
-		// 完全匹配
-		if field, ok := c.FeatureToField[feature]; ok {
-			return field, nil
-		}
+// mappedField 按配置映射查找小特征的域名，同时返回命中来源
+func (c *FieldConfig) mappedField(feature string) (string, string, error) {
+	matcher, err := c.compiledMatcher()
+	if err != nil {
+		return "", "", err
 	}
-
-	// 使用默认域名
-	if c.DefaultField != "" {
-		return c.DefaultField, nil
+	if field, src, ok := matcher.match(feature, c.DefaultField); ok {
+		// regex、split 规则的域名取自特征名，匹配时才能检查
+		if !validFieldName(field) {
+			return "", "", fmt.Errorf("field name %q of feature '%s' (from %s) cannot contain whitespace or ':'",
+				field, feature, matcher.source(src))
+		}
+		return field, matcher.source(src), nil
 	}
 
 	// 小特征必须有配置，否则报错
-	return "", fmt.Errorf("feature '%s' not found in config and not a large numeric feature (>= %d)", 
-		feature, c.NumericFieldThreshold)
+	return "", "", fmt.Errorf("feature '%s' not found in config and not a large numeric feature (>= %d)", 
+		feature, c.numericThreshold(c.numericLayout()))
 }
 
-// extractFieldFromFeature 从特征名自动提取域名
-func (c *FieldConfig) extractFieldFromFeature(feature string) string {
-	// 按下划线分割
-	parts := strings.Split(feature, "_")
-	if len(parts) > 1 {
-		return parts[0]
+// compiledMatcher 返回编译好的域映射
+// 未经 Validate 的配置在第一次调用时编译一次，并发调用安全；
+// 规则无效时每次查找都返回该错误，而不是忽略规则改用其他映射
+func (c *FieldConfig) compiledMatcher() (*fieldMatcher, error) {
+	c.matcherOnce.Do(func() {
+		if c.matcher == nil {
+			c.matcherErr = c.validateRules()
+		}
+	})
+	if c.matcher == nil {
+		return nil, fmt.Errorf("invalid field config: %v", c.matcherErr)
 	}
-	// 默认：每个特征自成一个域
-	return feature
+	return c.matcher, nil
 }
 
 // Validate 验证配置
@@ -197,10 +252,47 @@ func (c *FieldConfig) Validate() error {
 		return fmt.Errorf("invalid mode: %s (must be one of: explicit, config)", c.Mode)
 	}
 
-	if c.Mode == "config" && len(c.FeatureToField) == 0 {
-		return fmt.Errorf("config mode requires feature_to_field mapping")
+	if c.Mode == "config" && len(c.FeatureToField) == 0 && len(c.Rules) == 0 {
+		return fmt.Errorf("config mode requires feature_to_field mapping or rules")
+	}
+
+	if err := c.validateNumericField(); err != nil {
+		return err
+	}
+	if err := c.validateRules(); err != nil {
+		return err
+	}
+
+	for field, norm := range c.FieldNorm {
+		switch norm {
+		case NormL1, NormL2, NormCount:
+		default:
+			return fmt.Errorf("field %s: unsupported normalization %s (must be l1, l2 or count)", field, norm)
+		}
+	}
+
+	for feature, rule := range c.Buckets {
+		if rule == nil {
+			return fmt.Errorf("bucket rule for %s is empty", feature)
+		}
+		if err := rule.Validate(); err != nil {
+			return fmt.Errorf("bucket rule for %s: %v", feature, err)
+		}
 	}
 
 	return nil
 }
 
+// Discretize 按分桶规则把数值特征转为类别特征
+// 没有为该特征配置分桶规则时原样返回
+func (c *FieldConfig) Discretize(feature string, value float64) (string, float64, error) {
+	rule, ok := c.Buckets[feature]
+	if !ok {
+		return feature, value, nil
+	}
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return "", 0, fmt.Errorf("invalid numeric value for bucketed feature %s: %v", feature, value)
+	}
+	return BucketFeature(feature, rule.Bucket(value)), 1.0, nil
+}
+
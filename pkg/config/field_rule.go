@@ -0,0 +1,351 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"unicode"
+)
+
+// 域映射规则类型
+const (
+	RuleExact  = "exact"  // 特征名等于 Pattern
+	RulePrefix = "prefix" // 特征名以 Pattern 开头
+	RuleSuffix = "suffix" // 特征名以 Pattern 结尾
+	RuleRegex  = "regex"  // 特征名匹配正则 Pattern，Field 可以用 $1、${name} 引用分组
+	RuleSplit  = "split"  // 按 Delimiter 分割特征名，取第 Index 段作为域名
+	RuleRange  = "range"  // 全数字特征，Min <= 特征 < Max，Max 不能超过数字特征阈值
+)
+
+// FieldRule 有序的域映射规则，按声明顺序第一个匹配的规则生效
+type FieldRule struct {
+	Type      string `json:"type"`
+	Pattern   string `json:"pattern,omitempty"`
+	Field     string `json:"field,omitempty"`
+	Delimiter string `json:"delimiter,omitempty"` // split 的分隔符，默认 "_"
+	Index     int    `json:"index,omitempty"`     // split 取第几段，默认0
+	Min       uint64 `json:"min,omitempty"`
+	Max       uint64 `json:"max,omitempty"`
+
+	re *regexp.Regexp
+}
+
+// Name 规则的可读描述，用于命中统计
+func (r *FieldRule) Name() string {
+	switch r.Type {
+	case RuleSplit:
+		return fmt.Sprintf("split(%q)[%d]", r.Delimiter, r.Index)
+	case RuleRange:
+		if r.Max == 0 {
+			return fmt.Sprintf("range[%d,inf) -> %s", r.Min, r.Field)
+		}
+		return fmt.Sprintf("range[%d,%d) -> %s", r.Min, r.Max, r.Field)
+	default:
+		return fmt.Sprintf("%s(%s) -> %s", r.Type, r.Pattern, r.Field)
+	}
+}
+
+// validate 验证规则、补全默认值并编译正则
+func (r *FieldRule) validate() error {
+	switch r.Type {
+	case RuleExact, RulePrefix, RuleSuffix, RuleRegex:
+		if r.Pattern == "" {
+			return fmt.Errorf("%s rule requires pattern", r.Type)
+		}
+		if r.Type == RuleRegex {
+			re, err := regexp.Compile(r.Pattern)
+			if err != nil {
+				return fmt.Errorf("invalid regex %s: %v", r.Pattern, err)
+			}
+			r.re = re
+		}
+	case RuleSplit:
+		if r.Delimiter == "" {
+			r.Delimiter = "_"
+		}
+		if r.Index < 0 {
+			return fmt.Errorf("split rule index cannot be negative: %d", r.Index)
+		}
+		if r.Field != "" {
+			return fmt.Errorf("split rule takes the field name from the feature, field must be empty")
+		}
+		return nil
+	case RuleRange:
+		if r.Max != 0 && r.Max <= r.Min {
+			return fmt.Errorf("range rule requires max > min: [%d, %d)", r.Min, r.Max)
+		}
+	default:
+		return fmt.Errorf("unsupported rule type: %s (must be exact, prefix, suffix, regex, split or range)", r.Type)
+	}
+	if r.Field == "" {
+		return fmt.Errorf("%s rule requires field", r.Type)
+	}
+	if !validFieldName(r.Field) {
+		return fmt.Errorf("field name cannot contain whitespace or ':': %q", r.Field)
+	}
+	return nil
+}
+
+// validFieldName 域名不能含空白字符或 ':'，否则无法写入文本模型的 FIELDS 行和 FFM 格式样本
+func validFieldName(field string) bool {
+	return strings.IndexFunc(field, func(r rune) bool { return unicode.IsSpace(r) || r == ':' }) < 0
+}
+
+// match 对需要逐条尝试的规则（regex、split、range）求域名
+func (r *FieldRule) match(feature string) (string, bool) {
+	switch r.Type {
+	case RuleRegex:
+		m := r.re.FindStringSubmatchIndex(feature)
+		if m == nil {
+			return "", false
+		}
+		field := string(r.re.ExpandString(nil, r.Field, feature, m))
+		return field, field != ""
+	case RuleSplit:
+		parts := strings.Split(feature, r.Delimiter)
+		if len(parts) < 2 || r.Index >= len(parts) || parts[r.Index] == "" {
+			return "", false
+		}
+		return parts[r.Index], true
+	case RuleRange:
+		id, err := strconv.ParseUint(feature, 10, 64)
+		if err != nil || id < r.Min || (r.Max != 0 && id >= r.Max) {
+			return "", false
+		}
+		return r.Field, true
+	}
+	return "", false
+}
+
+// trieNode 字节前缀树节点，rule 为在此结束的规则中序号最小的一个（-1 表示没有）
+type trieNode struct {
+	children map[byte]*trieNode
+	rule     int
+	mapped   string // FeatureToField 中以此结束的前缀对应的域
+}
+
+func newTrieNode() *trieNode {
+	return &trieNode{rule: -1}
+}
+
+// insert 插入键，返回键结束处的节点
+func (n *trieNode) insert(key string, reverse bool) *trieNode {
+	for i := 0; i < len(key); i++ {
+		b := key[i]
+		if reverse {
+			b = key[len(key)-1-i]
+		}
+		if n.children == nil {
+			n.children = make(map[byte]*trieNode)
+		}
+		child, ok := n.children[b]
+		if !ok {
+			child = newTrieNode()
+			n.children[b] = child
+		}
+		n = child
+	}
+	return n
+}
+
+// RuleStat 单条规则的命中次数
+type RuleStat struct {
+	Rule string
+	Hits int64
+}
+
+// fieldMatcher 编译后的域映射
+// prefix/suffix/exact 规则和 FeatureToField 放入前缀树（suffix 用反向树），
+// 其余规则按顺序逐条尝试，但只尝试序号小于前缀树命中结果的规则，保持"第一个匹配生效"的语义
+type fieldMatcher struct {
+	rules     []*FieldRule
+	prefix    *trieNode
+	suffix    *trieNode
+	exact     map[string]int
+	slow      []int // regex、split、range 规则的序号
+	usePrefix bool
+	mapped    map[string]string // UsePrefix 为 false 时的 FeatureToField 完全匹配
+
+	ruleHits    []int64
+	mappedHits  int64
+	defaultHits int64
+}
+
+// newFieldMatcher 编译规则和 FeatureToField，规则须已通过验证
+func newFieldMatcher(c *FieldConfig) *fieldMatcher {
+	m := &fieldMatcher{
+		rules:     c.Rules,
+		prefix:    newTrieNode(),
+		suffix:    newTrieNode(),
+		exact:     make(map[string]int),
+		usePrefix: c.UsePrefix,
+		mapped:    c.FeatureToField,
+		ruleHits:  make([]int64, len(c.Rules)),
+	}
+	for i, r := range c.Rules {
+		switch r.Type {
+		case RulePrefix:
+			if n := m.prefix.insert(r.Pattern, false); n.rule < 0 {
+				n.rule = i
+			}
+		case RuleSuffix:
+			if n := m.suffix.insert(r.Pattern, true); n.rule < 0 {
+				n.rule = i
+			}
+		case RuleExact:
+			if _, ok := m.exact[r.Pattern]; !ok {
+				m.exact[r.Pattern] = i
+			}
+		default:
+			m.slow = append(m.slow, i)
+		}
+	}
+	if c.UsePrefix {
+		for prefix, field := range c.FeatureToField {
+			if prefix != "" {
+				m.prefix.insert(prefix, false).mapped = field
+			}
+		}
+	}
+	return m
+}
+
+// 非规则命中时 match 返回的来源
+const (
+	matchMapped  = -1 // FeatureToField
+	matchDefault = -2 // 默认域
+)
+
+// match 查找特征的域名，依次为: 有序规则、FeatureToField、默认域
+// 返回的 src 为命中规则的序号，或 matchMapped、matchDefault
+func (m *fieldMatcher) match(feature, defaultField string) (field string, src int, ok bool) {
+	best := -1
+	better := func(i int) {
+		if i >= 0 && (best < 0 || i < best) {
+			best = i
+		}
+	}
+
+	// 前缀树: 沿特征名向下走，同时记录最长的 FeatureToField 前缀
+	mapped := ""
+	n := m.prefix
+	for i := 0; n != nil; i++ {
+		better(n.rule)
+		if n.mapped != "" {
+			mapped = n.mapped
+		}
+		if i == len(feature) {
+			break
+		}
+		n = n.children[feature[i]]
+	}
+	for i, n := len(feature)-1, m.suffix; n != nil; i-- {
+		better(n.rule)
+		if i < 0 {
+			break
+		}
+		n = n.children[feature[i]]
+	}
+	if i, ok := m.exact[feature]; ok {
+		better(i)
+	}
+
+	if best >= 0 {
+		field = m.rules[best].Field
+	}
+	for _, i := range m.slow {
+		if best >= 0 && i > best {
+			break
+		}
+		if f, ok := m.rules[i].match(feature); ok {
+			best, field = i, f
+			break
+		}
+	}
+	if best >= 0 {
+		atomic.AddInt64(&m.ruleHits[best], 1)
+		return field, best, true
+	}
+
+	if !m.usePrefix {
+		mapped = m.mapped[feature]
+	}
+	if mapped != "" {
+		atomic.AddInt64(&m.mappedHits, 1)
+		return mapped, matchMapped, true
+	}
+	if defaultField != "" {
+		atomic.AddInt64(&m.defaultHits, 1)
+		return defaultField, matchDefault, true
+	}
+	return "", 0, false
+}
+
+// source 命中来源的名称
+func (m *fieldMatcher) source(src int) string {
+	switch src {
+	case matchMapped:
+		return SourceMapping
+	case matchDefault:
+		return SourceDefault
+	}
+	return fmt.Sprintf("#%d %s", src, m.rules[src].Name())
+}
+
+// stats 命中统计，规则按声明顺序，之后是 FeatureToField 和默认域
+func (m *fieldMatcher) stats() []RuleStat {
+	stats := make([]RuleStat, 0, len(m.rules)+2)
+	for i := range m.rules {
+		stats = append(stats, RuleStat{Rule: m.source(i), Hits: atomic.LoadInt64(&m.ruleHits[i])})
+	}
+	stats = append(stats,
+		RuleStat{Rule: m.source(matchMapped), Hits: atomic.LoadInt64(&m.mappedHits)},
+		RuleStat{Rule: m.source(matchDefault), Hits: atomic.LoadInt64(&m.defaultHits)},
+	)
+	return stats
+}
+
+// RuleStats 返回域映射规则的命中统计，配置未经 Validate 时返回 nil
+func (c *FieldConfig) RuleStats() []RuleStat {
+	if c.matcher == nil {
+		return nil
+	}
+	return c.matcher.stats()
+}
+
+// FormatRuleStats 格式化命中统计，用于训练结束时的汇总输出
+func FormatRuleStats(stats []RuleStat) string {
+	var b strings.Builder
+	b.WriteString("field mapping rule hits:\n")
+	width := 0
+	for _, s := range stats {
+		if len(s.Rule) > width {
+			width = len(s.Rule)
+		}
+	}
+	for _, s := range stats {
+		fmt.Fprintf(&b, "  %-*s %d\n", width, s.Rule, s.Hits)
+	}
+	return b.String()
+}
+
+// validateRules 验证并编译域映射规则
+func (c *FieldConfig) validateRules() error {
+	for i, r := range c.Rules {
+		if r == nil {
+			return fmt.Errorf("field rule #%d is empty", i)
+		}
+		if err := r.validate(); err != nil {
+			return fmt.Errorf("field rule #%d: %v", i, err)
+		}
+		// 不小于阈值的全数字特征先按位布局确定域，不会走到规则，这部分范围永远不会命中
+		if threshold := c.numericThreshold(c.numericLayout()); r.Type == RuleRange && (r.Max == 0 || r.Max > threshold) {
+			return fmt.Errorf("field rule #%d: %s reaches numeric_field_threshold %d, features >= %d get their field from the numeric layout before rules are matched, set max <= %d",
+				i, r.Name(), threshold, threshold, threshold)
+		}
+	}
+	c.matcher = newFieldMatcher(c)
+	return nil
+}
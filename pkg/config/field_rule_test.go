@@ -0,0 +1,166 @@
+package config
+
+import (
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestFieldRules(t *testing.T) {
+	cfg := NewFieldConfig()
+	cfg.FeatureToField = map[string]string{"age": "user", "ag": "other"}
+	cfg.DefaultField = "misc"
+	cfg.Rules = []*FieldRule{
+		{Type: RuleExact, Pattern: "age_bucket", Field: "bucket"},
+		{Type: RuleRegex, Pattern: `^tag(\d+)$`, Field: "tag_$1"},
+		{Type: RulePrefix, Pattern: "tag", Field: "tags"},
+		{Type: RuleSuffix, Pattern: "_ctx", Field: "context"},
+		{Type: RuleRange, Min: 100, Max: 200, Field: "item"},
+		{Type: RuleSplit},
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	for feature, want := range map[string]string{
+		"age_bucket": "bucket",
+		"tag12":      "tag_12", // regex 先于 prefix
+		"tagx":       "tags",
+		"hour_ctx":   "context", // suffix 先于 split
+		"150":        "item",
+		"250":        "misc",
+		"user_id":    "user", // split
+		"age":        "user", // 最长前缀
+		"agx":        "other",
+		"zzz":        "misc",
+	} {
+		got, err := cfg.GetFieldForFeature(feature)
+		if err != nil || got != want {
+			t.Errorf("%s: got %q, %v, want %q", feature, got, err, want)
+		}
+	}
+
+	stats := cfg.RuleStats()
+	want := []int64{1, 1, 1, 1, 1, 1, 2, 2}
+	if len(stats) != len(want) {
+		t.Fatalf("stats: got %+v", stats)
+	}
+	for i, w := range want {
+		if stats[i].Hits != w {
+			t.Errorf("stat %s: got %d, want %d", stats[i].Rule, stats[i].Hits, w)
+		}
+	}
+	if s := FormatRuleStats(stats); !strings.Contains(s, "#1 regex(^tag(\\d+)$) -> tag_$1") {
+		t.Errorf("formatted stats: %s", s)
+	}
+
+	// 完全匹配模式
+	cfg.UsePrefix = false
+	cfg.Rules = nil
+	cfg.DefaultField = ""
+	if err := cfg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := cfg.GetFieldForFeature("age"); err != nil || got != "user" {
+		t.Errorf("exact mapping: got %q, %v", got, err)
+	}
+	if _, err := cfg.GetFieldForFeature("age_x"); err == nil {
+		t.Error("expected error for unmapped feature")
+	}
+}
+
+func TestFieldRulesWithoutValidate(t *testing.T) {
+	cfg := NewFieldConfig()
+	cfg.Rules = []*FieldRule{{Type: RuleRegex, Pattern: `^tag(\d+)$`, Field: "tag_$1"}}
+
+	// 未经 Validate 的配置只编译一次，多个解析线程同时查找时命中统计不丢失
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				if got, err := cfg.GetFieldForFeature("tag7"); err != nil || got != "tag_7" {
+					t.Errorf("got %q, %v, want tag_7", got, err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if stats := cfg.RuleStats(); len(stats) == 0 || stats[0].Hits != 400 {
+		t.Errorf("stats: got %+v, want 400 hits for the regex rule", stats)
+	}
+
+	// 无效规则不会被静默忽略而改用 feature_to_field 或 default_field
+	bad := NewFieldConfig()
+	bad.FeatureToField = map[string]string{"tag": "tags"}
+	bad.DefaultField = "other"
+	bad.Rules = []*FieldRule{{Type: RuleRegex, Pattern: `^tag(\d+$`, Field: "tag_$1"}}
+	for i := 0; i < 2; i++ {
+		if got, err := bad.GetFieldForFeature("tag7"); err == nil || !strings.Contains(err.Error(), "field rule #0") {
+			t.Errorf("invalid rule: got %q, %v, want the rule error", got, err)
+		}
+	}
+}
+
+func TestFieldRuleValidate(t *testing.T) {
+	for _, r := range []*FieldRule{
+		{Type: "glob", Pattern: "a*", Field: "a"},
+		{Type: RulePrefix, Field: "a"},
+		{Type: RulePrefix, Pattern: "a"},
+		{Type: RuleRegex, Pattern: "(", Field: "a"},
+		{Type: RuleRange, Min: 10, Max: 5, Field: "a"},
+		{Type: RuleSplit, Field: "a"},
+		{Type: RuleExact, Pattern: "a", Field: "a b"},
+	} {
+		cfg := NewFieldConfig()
+		cfg.Rules = []*FieldRule{r}
+		if err := cfg.Validate(); err == nil {
+			t.Errorf("%+v: expected error", r)
+		}
+	}
+
+	// 范围到达数字特征阈值时，阈值以上的部分先按位布局确定域，规则永远不会命中
+	for _, r := range []*FieldRule{
+		{Type: RuleRange, Min: 100, Field: "a"},
+		{Type: RuleRange, Min: 100, Max: DefaultNumericFieldThreshold + 1, Field: "a"},
+		{Type: RuleRange, Min: DefaultNumericFieldThreshold, Max: 2 * DefaultNumericFieldThreshold, Field: "a"},
+	} {
+		cfg := NewFieldConfig()
+		cfg.Rules = []*FieldRule{r}
+		if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "numeric_field_threshold") {
+			t.Errorf("%s: expected threshold error, got %v", r.Name(), err)
+		}
+	}
+	cfg := NewFieldConfig()
+	cfg.Rules = []*FieldRule{{Type: RuleRange, Min: 100, Max: DefaultNumericFieldThreshold, Field: "a"}}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("range below the threshold: %v", err)
+	}
+	// 显式配置的阈值同样生效
+	cfg.NumericFieldThreshold = 5000
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error for range above numeric_field_threshold 5000")
+	}
+}
+
+func TestFieldRulesExpandedNames(t *testing.T) {
+	cfg := NewFieldConfig()
+	cfg.Rules = []*FieldRule{
+		{Type: RuleRegex, Pattern: `^([^|]+)\|`, Field: "$1"},
+		{Type: RuleSplit},
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := cfg.GetFieldForFeature("user|u1"); err != nil || got != "user" {
+		t.Errorf("user|u1: got %q, %v", got, err)
+	}
+	// 从特征名取出的域名含空白字符或 ':' 时报错
+	for _, feature := range []string{"us:er|u1", "us er|u1", "a:b_c"} {
+		if got, err := cfg.GetFieldForFeature(feature); err == nil {
+			t.Errorf("%q: got field %q, expected error", feature, got)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package config
+
+import (
+	"fmt"
+	"math/bits"
+	"strconv"
+	"strings"
+)
+
+// DefaultNumericFieldBits 默认的域ID位数: 高32位为域ID，低32位为特征ID
+const DefaultNumericFieldBits = 32
+
+// DefaultNumericFieldThreshold 默认的数字特征阈值
+// 位布局中域1的起点低于此值时（如48位以上的 numeric_field_bits），默认阈值按0处理，使用域1的起点
+const DefaultNumericFieldThreshold = 1000000
+
+// SpecialField 负数特征（缺失值标记或特殊编码）所在的域
+const SpecialField = "special"
+
+// defaultFieldConfig 没有域配置文件时使用的数字特征规则
+var defaultFieldConfig = NewFieldConfig()
+
+// numericLayout 数字特征的位布局: 域ID = (特征 & mask) >> shift
+type numericLayout struct {
+	mask  uint64
+	shift uint
+}
+
+// parseNumericLayout 根据 NumericFieldBits / NumericFieldMask 计算位布局
+func (c *FieldConfig) parseNumericLayout() (numericLayout, error) {
+	if c.NumericFieldMask != "" {
+		if c.NumericFieldBits != 0 {
+			return numericLayout{}, fmt.Errorf("numeric_field_bits and numeric_field_mask cannot be used together")
+		}
+		mask, err := strconv.ParseUint(c.NumericFieldMask, 0, 64)
+		if err != nil {
+			return numericLayout{}, fmt.Errorf("invalid numeric_field_mask %s: %v", c.NumericFieldMask, err)
+		}
+		if mask == 0 {
+			return numericLayout{}, fmt.Errorf("numeric_field_mask cannot be zero")
+		}
+		shift := uint(bits.TrailingZeros64(mask))
+		// 掩码必须是连续的1，否则域ID的取值范围没有意义
+		if v := mask >> shift; v&(v+1) != 0 {
+			return numericLayout{}, fmt.Errorf("numeric_field_mask must be a contiguous run of bits: %s", c.NumericFieldMask)
+		}
+		return numericLayout{mask: mask, shift: shift}, nil
+	}
+
+	n := c.NumericFieldBits
+	if n == 0 {
+		n = DefaultNumericFieldBits
+	}
+	if n < 1 || n > 63 {
+		return numericLayout{}, fmt.Errorf("numeric_field_bits must be between 1 and 63: %d", c.NumericFieldBits)
+	}
+	shift := uint(64 - n)
+	return numericLayout{mask: ^uint64(0) << shift, shift: shift}, nil
+}
+
+// numericLayout 获取位布局，优先使用 Validate 时缓存的结果
+func (c *FieldConfig) numericLayout() numericLayout {
+	if c.layout.mask != 0 {
+		return c.layout
+	}
+	layout, err := c.parseNumericLayout()
+	if err != nil {
+		// 未经 Validate 的无效配置按默认布局处理
+		layout, _ = defaultFieldConfig.parseNumericLayout()
+	}
+	return layout
+}
+
+// numericThreshold 数字特征阈值，0 表示使用第一个非0域ID的起点（1 << shift）
+// 默认阈值高于该起点时同样使用起点，只有显式配置的阈值才会与位布局冲突
+func (c *FieldConfig) numericThreshold(layout numericLayout) uint64 {
+	start := uint64(1) << layout.shift
+	if c.NumericFieldThreshold == 0 || (c.NumericFieldThreshold == DefaultNumericFieldThreshold && start < DefaultNumericFieldThreshold) {
+		return start
+	}
+	return c.NumericFieldThreshold
+}
+
+// validateNumericField 验证数字特征规则，并缓存位布局
+func (c *FieldConfig) validateNumericField() error {
+	layout, err := c.parseNumericLayout()
+	if err != nil {
+		return err
+	}
+	// 阈值高于第一个非0域ID的起点时，低于阈值的编码特征会被当作小特征去查映射
+	if start := uint64(1) << layout.shift; c.numericThreshold(layout) > start {
+		return fmt.Errorf("numeric_field_threshold %d conflicts with the field layout: features of field 1 start at %d, lower the threshold (or set 0 to derive it)",
+			c.NumericFieldThreshold, start)
+	}
+	if strings.ContainsAny(c.NumericFieldPrefix, " \t:") {
+		return fmt.Errorf("numeric_field_prefix cannot contain whitespace or ':': %q", c.NumericFieldPrefix)
+	}
+	c.layout = layout
+	return nil
+}
+
+// NumericField 按数字特征规则提取域名
+// 特征不是全数字或小于阈值时返回 false
+func (c *FieldConfig) NumericField(feature string) (string, bool) {
+	if feature == "" || feature[0] < '0' || feature[0] > '9' {
+		return "", false
+	}
+	id, err := strconv.ParseUint(feature, 10, 64)
+	if err != nil {
+		return "", false
+	}
+	layout := c.numericLayout()
+	if id < c.numericThreshold(layout) {
+		return "", false
+	}
+	return c.NumericFieldPrefix + strconv.FormatUint((id&layout.mask)>>layout.shift, 10), true
+}
+
+// 域的来源，由 ResolveFieldSource 返回；有序规则命中时为 "#序号 规则描述"
+const (
+	SourceSpecial  = "special"          // 负数特征
+	SourceNumeric  = "numeric"          // 大数字特征按位布局提取
+	SourceExplicit = "explicit"         // explicit 模式不做映射，域为空
+	SourceMapping  = "feature_to_field" // FeatureToField 映射
+	SourceDefault  = "default_field"    // 默认域
+)
+
+// ResolveField 确定FM格式特征（feature:value）的域，解析器和 GetFieldForFeature 使用同一套规则:
+//  1. 负数特征: special 域
+//  2. 大数字特征（>= 阈值）: 按位布局提取域ID，没有配置时使用默认规则（高32位）
+//  3. explicit 模式: 返回空（使用样本中的域名）
+//  4. 其他特征: 有序规则、配置映射，找不到时使用默认域，否则报错
+func ResolveField(c *FieldConfig, feature string) (string, error) {
+	field, _, err := ResolveFieldSource(c, feature)
+	return field, err
+}
+
+// ResolveFieldSource 与 ResolveField 相同，同时返回域的来源（用于检查配置的覆盖情况）
+func ResolveFieldSource(c *FieldConfig, feature string) (field, source string, err error) {
+	if strings.HasPrefix(feature, "-") {
+		return SpecialField, SourceSpecial, nil
+	}
+
+	numeric := c
+	if numeric == nil {
+		numeric = defaultFieldConfig
+	}
+	if field, ok := numeric.NumericField(feature); ok {
+		return field, SourceNumeric, nil
+	}
+
+	if c == nil {
+		return "", "", fmt.Errorf("small feature '%s' requires field config file, use -field_config option or use FFM format (field:feature:value)", feature)
+	}
+	if c.Mode == "explicit" {
+		return "", SourceExplicit, nil
+	}
+	return c.mappedField(feature)
+}
@@ -0,0 +1,98 @@
+package config
+
+import "testing"
+
+func TestResolveField(t *testing.T) {
+	// 没有配置时使用默认规则: 高32位为域ID
+	for feature, want := range map[string]string{
+		"51539607553": "field_12",
+		"1000000":     "field_0",
+		"-1":          SpecialField,
+	} {
+		if got, err := ResolveField(nil, feature); err != nil || got != want {
+			t.Errorf("%s: got %q, %v, want %q", feature, got, err, want)
+		}
+	}
+	if _, err := ResolveField(nil, "64"); err == nil {
+		t.Error("expected error for small feature without config")
+	}
+
+	cfg := NewFieldConfig()
+	cfg.FeatureToField = map[string]string{"age": "user"}
+	cfg.NumericFieldBits = 16
+	cfg.NumericFieldThreshold = 0
+	cfg.NumericFieldPrefix = "f"
+	if err := cfg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	// 0x000C000000000001: 高16位为12
+	for feature, want := range map[string]string{
+		"3377699720527873": "f12",
+		"age":              "user",
+	} {
+		if got, err := cfg.GetFieldForFeature(feature); err != nil || got != want {
+			t.Errorf("%s: got %q, %v, want %q", feature, got, err, want)
+		}
+	}
+	// 低于 2^48 的数字不是编码特征
+	if _, err := cfg.GetFieldForFeature("51539607553"); err == nil {
+		t.Error("expected error for numeric feature below the derived threshold")
+	}
+
+	cfg.NumericFieldBits = 0
+	cfg.NumericFieldMask = "0x0000FF0000000000"
+	if err := cfg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	// 0x00AB0C0000000001: 掩码取出 0x0C
+	if got, _ := cfg.GetFieldForFeature("48145415157055489"); got != "f12" {
+		t.Errorf("mask layout: got %q", got)
+	}
+}
+
+func TestValidateNumericField(t *testing.T) {
+	for name, mutate := range map[string]func(c *FieldConfig){
+		"bits out of range":   func(c *FieldConfig) { c.NumericFieldBits = 64 },
+		"bits and mask":       func(c *FieldConfig) { c.NumericFieldBits = 8; c.NumericFieldMask = "0xFF" },
+		"invalid mask":        func(c *FieldConfig) { c.NumericFieldMask = "zz" },
+		"zero mask":           func(c *FieldConfig) { c.NumericFieldMask = "0" },
+		"non-contiguous mask": func(c *FieldConfig) { c.NumericFieldMask = "0xF0F0" },
+		"threshold too high":  func(c *FieldConfig) { c.NumericFieldThreshold = 1 << 33 },
+		"mask below threshold": func(c *FieldConfig) {
+			c.NumericFieldMask = "0xFF"
+			c.NumericFieldThreshold = 1 << 20
+		},
+		"prefix with colon": func(c *FieldConfig) { c.NumericFieldPrefix = "f:" },
+	} {
+		cfg := NewFieldConfig()
+		cfg.Mode = "explicit"
+		mutate(cfg)
+		if err := cfg.Validate(); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestDefaultThresholdWithNarrowLayout(t *testing.T) {
+	// 48位域ID: 域1从 2^16 开始，低于默认阈值，默认阈值按0处理
+	cfg := NewFieldConfig()
+	cfg.FeatureToField = map[string]string{"100": "small"}
+	cfg.NumericFieldBits = 48
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+	// 3<<16 + 5 = 196613
+	for feature, want := range map[string]string{
+		"196613": "field_3",
+		"100":    "small",
+	} {
+		if got, err := cfg.GetFieldForFeature(feature); err != nil || got != want {
+			t.Errorf("%s: got %q, %v, want %q", feature, got, err, want)
+		}
+	}
+
+	// 默认布局下默认阈值不变
+	if got, _ := NewFieldConfig().GetFieldForFeature("1000000"); got != "field_0" {
+		t.Errorf("default layout: got %q, want field_0", got)
+	}
+}
@@ -0,0 +1,82 @@
+package eval
+
+import (
+	"math"
+	"sync"
+)
+
+// DefaultAUCBins 累计 AUC 的默认分桶数，同一个桶内的正负样本按同分计算
+const DefaultAUCBins = 100000
+
+// aucLogitRange AUC 按logit均匀分桶，覆盖 [-aucLogitRange, aucLogitRange]，之外的预测落入两端的桶
+// 按概率均匀分桶时，集中在百分之几以内的点击率预测只占很少的桶，大量样本按同分计算，AUC 偏向0.5
+const aucLogitRange = 16.0
+
+// Accumulator 全部预测的累计 logloss 和分桶近似的 AUC，内存与样本数无关，并发安全
+type Accumulator struct {
+	mu     sync.Mutex
+	pos    []float64 // 每个概率桶内正样本的权重
+	neg    []float64
+	loss   float64
+	weight float64
+	count  int64
+}
+
+// NewAccumulator 创建累计指标，bins 为 AUC 的logit分桶数
+func NewAccumulator(bins int) *Accumulator {
+	if bins <= 0 {
+		bins = DefaultAUCBins
+	}
+	return &Accumulator{pos: make([]float64, bins), neg: make([]float64, bins)}
+}
+
+// Add 加入一批预测
+func (a *Accumulator) Add(points []Point) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	bins := len(a.pos)
+	for _, p := range points {
+		bin := aucBin(p.Prob, bins)
+		if p.Positive {
+			a.pos[bin] += p.Weight
+		} else {
+			a.neg[bin] += p.Weight
+		}
+		a.loss += p.Weight * LogLoss(p.Prob, p.Positive)
+		a.weight += p.Weight
+	}
+	a.count += int64(len(points))
+}
+
+// aucBin 预测概率所在的logit桶
+func aucBin(prob float64, bins int) int {
+	logit := math.Log(prob) - math.Log1p(-prob)
+	x := (logit + aucLogitRange) / (2 * aucLogitRange) * float64(bins)
+	if !(x >= 0) { // 包括 NaN
+		return 0
+	}
+	if x >= float64(bins) {
+		return bins - 1
+	}
+	return int(x)
+}
+
+// Metrics 累计的样本数、加权平均 logloss 和 AUC，没有样本时指标为 NaN，只有一类样本时 AUC 为 NaN
+func (a *Accumulator) Metrics() (count int64, logloss, auc float64) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.weight == 0 {
+		return a.count, math.NaN(), math.NaN()
+	}
+	var pos, neg, area float64
+	for i := range a.pos {
+		area += a.pos[i] * (neg + a.neg[i]/2)
+		pos += a.pos[i]
+		neg += a.neg[i]
+	}
+	auc = math.NaN()
+	if pos > 0 && neg > 0 {
+		auc = area / (pos * neg)
+	}
+	return a.count, a.loss / a.weight, auc
+}
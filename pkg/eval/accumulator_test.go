@@ -0,0 +1,77 @@
+package eval
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestAccumulator(t *testing.T) {
+	a := NewAccumulator(1000)
+	if n, ll, auc := a.Metrics(); n != 0 || !math.IsNaN(ll) || !math.IsNaN(auc) {
+		t.Errorf("empty metrics = %d, %v, %v", n, ll, auc)
+	}
+
+	points := []Point{
+		{Prob: 0.1, Positive: false, Weight: 1},
+		{Prob: 0.4, Positive: true, Weight: 1},
+		{Prob: 0.35, Positive: false, Weight: 1},
+		{Prob: 0.8, Positive: true, Weight: 1},
+		{Prob: 1.0, Positive: true, Weight: 1},
+	}
+	a.Add(points[:2])
+	a.Add(points[2:])
+	n, ll, auc := a.Metrics()
+	if n != 5 {
+		t.Errorf("count = %d, want 5", n)
+	}
+	var want float64
+	for _, p := range points {
+		want += LogLoss(p.Prob, p.Positive)
+	}
+	if math.Abs(ll-want/5) > 1e-12 {
+		t.Errorf("logloss = %v, want %v", ll, want/5)
+	}
+	// 分桶足够细时与精确 AUC 一致
+	if exact := AUC(points); math.Abs(auc-exact) > 1e-12 {
+		t.Errorf("AUC = %v, want %v", auc, exact)
+	}
+}
+
+func TestAccumulatorSkewedProbabilities(t *testing.T) {
+	// 点击率预测集中在 [0.001, 0.02]，预测越高越可能是正样本
+	// 第二组集中在基准点击率附近，按概率均匀分桶时几乎都落入同一个桶
+	rng := rand.New(rand.NewSource(1))
+	for _, gen := range []func() float64{
+		func() float64 { return 0.001 * math.Pow(20, rng.Float64()) },
+		func() float64 { return 0.01 + 0.0002*rng.Float64() },
+	} {
+		points := make([]Point, 100000)
+		lo, hi := math.Inf(1), math.Inf(-1)
+		probs := make([]float64, len(points))
+		for i := range probs {
+			probs[i] = gen()
+			lo, hi = math.Min(lo, probs[i]), math.Max(hi, probs[i])
+		}
+		for i, prob := range probs {
+			points[i] = Point{Prob: prob, Positive: rng.Float64() < (prob-lo)/(hi-lo), Weight: 1}
+		}
+		a := NewAccumulator(DefaultAUCBins)
+		a.Add(points)
+		_, _, auc := a.Metrics()
+		if exact := AUC(points); math.Abs(auc-exact) > 1e-3 {
+			t.Errorf("predictions in [%v, %v]: AUC = %v, exact %v", lo, hi, auc, exact)
+		}
+	}
+
+	// 超出logit范围的预测落入两端的桶
+	if b := aucBin(0, 10); b != 0 {
+		t.Errorf("aucBin(0) = %d", b)
+	}
+	if b := aucBin(1, 10); b != 9 {
+		t.Errorf("aucBin(1) = %d", b)
+	}
+	if b := aucBin(math.NaN(), 10); b != 0 {
+		t.Errorf("aucBin(NaN) = %d", b)
+	}
+}
@@ -0,0 +1,120 @@
+package eval
+
+import (
+	"math"
+	"sort"
+	"sync"
+)
+
+// probEpsilon 计算 logloss 时对概率的截断，避免 log(0)
+const probEpsilon = 1e-15
+
+// Sigmoid 把模型输出的 logit 转换为正样本概率
+func Sigmoid(x float64) float64 {
+	return 1.0 / (1.0 + math.Exp(-x))
+}
+
+// LogLoss 单个样本的 logloss，prob 为正样本概率
+func LogLoss(prob float64, positive bool) float64 {
+	prob = math.Max(probEpsilon, math.Min(1-probEpsilon, prob))
+	if positive {
+		return -math.Log(prob)
+	}
+	return -math.Log(1 - prob)
+}
+
+// Point 一个带标签的预测
+type Point struct {
+	Prob     float64 // 正样本概率
+	Positive bool
+	Weight   float64
+}
+
+// AUC 计算加权 AUC，得分相同的正负样本各算一半；只有一类样本时返回 NaN
+func AUC(points []Point) float64 {
+	sorted := make([]Point, len(points))
+	copy(sorted, points)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Prob < sorted[j].Prob })
+
+	var pos, neg, area float64
+	for i := 0; i < len(sorted); {
+		// 同分的一组样本
+		var groupPos, groupNeg float64
+		j := i
+		for ; j < len(sorted) && sorted[j].Prob == sorted[i].Prob; j++ {
+			if sorted[j].Positive {
+				groupPos += sorted[j].Weight
+			} else {
+				groupNeg += sorted[j].Weight
+			}
+		}
+		area += groupPos * (neg + groupNeg/2)
+		pos += groupPos
+		neg += groupNeg
+		i = j
+	}
+	if pos == 0 || neg == 0 {
+		return math.NaN()
+	}
+	return area / (pos * neg)
+}
+
+// Window 最近 size 个预测的滑动窗口，并发安全
+type Window struct {
+	mu     sync.Mutex
+	points []Point
+	next   int
+	full   bool
+}
+
+// NewWindow 创建滑动窗口
+func NewWindow(size int) *Window {
+	if size <= 0 {
+		size = 1
+	}
+	return &Window{points: make([]Point, size)}
+}
+
+// Add 加入一个预测，窗口满时覆盖最早的预测
+func (w *Window) Add(p Point) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.points[w.next] = p
+	w.next++
+	if w.next == len(w.points) {
+		w.next = 0
+		w.full = true
+	}
+}
+
+// Len 窗口中的预测个数
+func (w *Window) Len() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.len()
+}
+
+func (w *Window) len() int {
+	if w.full {
+		return len(w.points)
+	}
+	return w.next
+}
+
+// Metrics 窗口内的加权平均 logloss 和 AUC，窗口为空时两者都是 NaN
+func (w *Window) Metrics() (logloss, auc float64) {
+	w.mu.Lock()
+	points := make([]Point, w.len())
+	copy(points, w.points[:len(points)])
+	w.mu.Unlock()
+
+	var loss, weights float64
+	for _, p := range points {
+		loss += p.Weight * LogLoss(p.Prob, p.Positive)
+		weights += p.Weight
+	}
+	if weights == 0 {
+		return math.NaN(), math.NaN()
+	}
+	return loss / weights, AUC(points)
+}
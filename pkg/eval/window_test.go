@@ -0,0 +1,56 @@
+package eval
+
+import (
+	"math"
+	"testing"
+)
+
+func TestAUC(t *testing.T) {
+	points := []Point{
+		{Prob: 0.1, Positive: false, Weight: 1},
+		{Prob: 0.4, Positive: true, Weight: 1},
+		{Prob: 0.35, Positive: false, Weight: 1},
+		{Prob: 0.8, Positive: true, Weight: 1},
+	}
+	// 正样本得分高于3/4的正负对
+	if got := AUC(points); math.Abs(got-1.0) > 1e-12 {
+		t.Errorf("AUC = %v, want 1", got)
+	}
+
+	points[2].Prob = 0.9
+	if got := AUC(points); math.Abs(got-0.5) > 1e-12 {
+		t.Errorf("AUC = %v, want 0.5", got)
+	}
+
+	// 同分各算一半
+	tied := []Point{{Prob: 0.5, Positive: true, Weight: 1}, {Prob: 0.5, Positive: false, Weight: 1}}
+	if got := AUC(tied); got != 0.5 {
+		t.Errorf("tied AUC = %v, want 0.5", got)
+	}
+
+	if got := AUC(points[:1]); !math.IsNaN(got) {
+		t.Errorf("single class AUC = %v, want NaN", got)
+	}
+}
+
+func TestWindow(t *testing.T) {
+	w := NewWindow(2)
+	if ll, auc := w.Metrics(); !math.IsNaN(ll) || !math.IsNaN(auc) {
+		t.Errorf("empty window metrics = %v, %v, want NaN", ll, auc)
+	}
+
+	w.Add(Point{Prob: 0.01, Positive: true, Weight: 1}) // 被覆盖
+	w.Add(Point{Prob: 0.8, Positive: true, Weight: 1})
+	w.Add(Point{Prob: 0.2, Positive: false, Weight: 3})
+	if w.Len() != 2 {
+		t.Fatalf("Len = %d, want 2", w.Len())
+	}
+	ll, auc := w.Metrics()
+	want := (-math.Log(0.8) - 3*math.Log(0.8)) / 4
+	if math.Abs(ll-want) > 1e-12 {
+		t.Errorf("logloss = %v, want %v", ll, want)
+	}
+	if auc != 1 {
+		t.Errorf("AUC = %v, want 1", auc)
+	}
+}
@@ -2,9 +2,15 @@ package frame
 
 import (
 	"bufio"
+	"context"
 	"fmt"
 	"io"
 	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/xiongle/alphaFFM-go/pkg/input"
+	"github.com/xiongle/alphaFFM-go/pkg/metrics"
 )
 
 // Task 任务接口
@@ -12,15 +18,59 @@ type Task interface {
 	RunTask(dataBuffer []string) error
 }
 
+// HeaderTask 需要输入首行表头的任务（如带表头的CSV/TSV）
+// NeedHeader 返回 true 时，生产者把输入首行交给 SetHeader 而不是作为样本分发
+type HeaderTask interface {
+	Task
+	NeedHeader() bool
+	SetHeader(line string) error
+}
+
+// Batch 一批输入行，行在同一个输入中连续
+type Batch struct {
+	Source    string // 输入名（文件路径，读取 io.Reader 时为 "input"）
+	FirstLine int64  // 第一行在输入中的行号，从1开始
+	Lines     []string
+}
+
+// Line 第 i 行在输入中的行号
+func (b *Batch) Line(i int) int64 {
+	return b.FirstLine + int64(i)
+}
+
+// BatchTask 需要知道输入位置的任务（如报告坏行的行号）
+// 实现了该接口的任务由 RunBatch 代替 RunTask 处理
+type BatchTask interface {
+	Task
+	RunBatch(batch *Batch) error
+}
+
+// CheckpointFunc 检查点回调
+// lines 为调用时已被完整处理的输入行数（从输入开头计数，包含被跳过的行）
+type CheckpointFunc func(lines int64) error
+
 // PCFrame 生产者-消费者框架
 type PCFrame struct {
-	task       Task
-	threadNum  int
-	bufSize    int
-	logNum     int
-	buffer     chan []string
-	done       chan struct{}
-	wg         sync.WaitGroup
+	task      Task
+	threadNum int
+	bufSize   int
+	logNum    int
+	buffer    chan *Batch
+	wg        sync.WaitGroup
+	ctx       context.Context // 取消后生产者停止读取，消费者处理完已在队列中的批次后退出
+
+	skipLines    int64          // 开头跳过的行数（用于断点续训）
+	ckptLines    int64          // 每处理多少行触发一次检查点
+	ckptInterval time.Duration  // 每隔多长时间触发一次检查点
+	ckptFunc     CheckpointFunc // 检查点回调
+	pending      sync.WaitGroup // 已发送但尚未处理完成的批次
+	sent         int64          // 已发送的样本行数
+
+	header    string     // 第一个输入的表头
+	headerSet bool       // 是否已读取表头
+	headerMu  sync.Mutex // 保护表头
+	err       error      // 生产者或任务遇到的第一个致命错误
+	errMu     sync.Mutex // 保护 err
 }
 
 // NewPCFrame 创建PC框架
@@ -35,15 +85,105 @@ func NewPCFrame() *PCFrame {
 func (f *PCFrame) Init(task Task, threadNum int) {
 	f.task = task
 	f.threadNum = threadNum
-	f.buffer = make(chan []string, 2) // 缓冲2批数据
-	f.done = make(chan struct{})
+	f.buffer = make(chan *Batch, 2) // 缓冲2批数据
+}
+
+// SetLogNum 设置每分发多少行输出一次 "N lines finished"，0 表示不输出
+// 任务自己报告进度（如训练进度报告）时关闭
+func (f *PCFrame) SetLogNum(n int) {
+	f.logNum = n
+}
+
+// RegisterMetrics 注册队列深度指标，须在 Init 之后调用
+func (f *PCFrame) RegisterMetrics(r *metrics.Registry) {
+	r.NewGaugeFunc("alphaffm_queue_depth", "Batches waiting in the producer-consumer queue.", func() float64 {
+		return float64(len(f.buffer))
+	})
+}
+
+// SetSkipLines 设置开头需要跳过的行数
+// 断点续训时用于跳过检查点之前已经训练过的输入
+func (f *PCFrame) SetSkipLines(n int64) {
+	f.skipLines = n
+}
+
+// SetCheckpoint 设置检查点
+// everyLines 和 every 任一满足即触发；触发时生产者暂停分发，等待所有已分发的批次
+// 处理完成后再调用 fn，因此 fn 看到的模型与 lines 严格对应
+func (f *PCFrame) SetCheckpoint(everyLines int64, every time.Duration, fn CheckpointFunc) {
+	f.ckptLines = everyLines
+	f.ckptInterval = every
+	f.ckptFunc = fn
+}
+
+// Run 运行框架，从单个输入读取
+// ctx 取消时停止读取，已分发的批次处理完成后返回 ctx.Err()
+func (f *PCFrame) Run(ctx context.Context, reader io.Reader) error {
+	return f.run(ctx, func() {
+		st := f.newProducerState()
+		f.produce(reader, "input", st)
+		f.finalCheckpoint(st)
+	})
+}
+
+// RunFiles 运行框架，从多个文件读取（自动解压gzip）
+// producers > 1 时多个文件由多个生产者并行读取，样本在文件之间交错；
+// 设置了跳过行数或检查点时按顺序读取，行号在文件之间连续计数，保证检查点的行偏移有意义
+// ctx 取消时与 Run 相同
+func (f *PCFrame) RunFiles(ctx context.Context, paths []string, producers int) error {
+	if producers > len(paths) {
+		producers = len(paths)
+	}
+	if producers > 1 && (f.skipLines > 0 || f.ckptFunc != nil) {
+		fmt.Println("Warning: checkpoint requires ordered input, reading files sequentially")
+		producers = 1
+	}
+	if producers <= 1 {
+		return f.run(ctx, func() {
+			st := f.newProducerState()
+			for _, path := range paths {
+				if !f.readFile(path, st) {
+					break
+				}
+			}
+			f.finalCheckpoint(st)
+		})
+	}
+
+	queue := make(chan string, len(paths))
+	for _, path := range paths {
+		queue <- path
+	}
+	close(queue)
+	return f.run(ctx, func() {
+		var wg sync.WaitGroup
+		for i := 0; i < producers; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				st := f.newProducerState()
+				for path := range queue {
+					if !f.readFile(path, st) {
+						break
+					}
+				}
+			}()
+		}
+		wg.Wait()
+	})
 }
 
-// Run 运行框架
-func (f *PCFrame) Run(reader io.Reader) error {
+// run 启动生产者和消费者并等待完成
+// 返回第一个致命错误；没有致命错误但被取消时返回 ctx.Err()
+func (f *PCFrame) run(ctx context.Context, produce func()) error {
+	f.ctx = ctx
 	// 启动生产者
 	f.wg.Add(1)
-	go f.producer(reader)
+	go func() {
+		defer f.wg.Done()
+		defer close(f.buffer)
+		produce()
+	}()
 
 	// 启动消费者
 	for i := 0; i < f.threadNum; i++ {
@@ -53,13 +193,94 @@ func (f *PCFrame) Run(reader io.Reader) error {
 
 	// 等待所有goroutine完成
 	f.wg.Wait()
+	if err := f.getErr(); err != nil {
+		return err
+	}
+	return ctx.Err()
+}
+
+// stopped 是否应停止读取: 遇到致命错误或被取消
+func (f *PCFrame) stopped() bool {
+	return f.getErr() != nil || f.ctx.Err() != nil
+}
+
+// setErr 记录第一个致命错误
+func (f *PCFrame) setErr(err error) {
+	f.errMu.Lock()
+	defer f.errMu.Unlock()
+	if f.err == nil {
+		f.err = err
+	}
+}
+
+// getErr 获取致命错误
+func (f *PCFrame) getErr() error {
+	f.errMu.Lock()
+	defer f.errMu.Unlock()
+	return f.err
+}
+
+// producerState 单个生产者的读取状态
+type producerState struct {
+	lineNum      int64 // 已读取的行数（顺序读取多个文件时连续计数）
+	sentLine     int64 // 最后一个已分发批次结束时的 lineNum，取消时的检查点位置
+	source       string
+	firstLine    int64 // 当前批次第一行在输入中的行号
+	batch        []string
+	lastCkptLine int64
+	lastCkptTime time.Time
+}
+
+func (f *PCFrame) newProducerState() *producerState {
+	return &producerState{
+		batch:        make([]string, 0, f.bufSize),
+		sentLine:     f.skipLines,
+		lastCkptLine: f.skipLines,
+		lastCkptTime: time.Now(),
+	}
+}
+
+// readFile 读取一个文件，返回是否继续读取后续文件
+func (f *PCFrame) readFile(path string, st *producerState) bool {
+	reader, err := input.Open(path)
+	if err != nil {
+		f.setErr(err)
+		return false
+	}
+	defer reader.Close()
+	return f.produce(reader, path, st)
+}
+
+// checkHeader 处理输入首行表头
+// 第一个读到的表头交给任务，其余输入的表头必须与之完全相同
+func (f *PCFrame) checkHeader(task HeaderTask, line, name string) error {
+	f.headerMu.Lock()
+	defer f.headerMu.Unlock()
+	if !f.headerSet {
+		if err := task.SetHeader(line); err != nil {
+			return fmt.Errorf("%s: input header: %v", name, err)
+		}
+		f.header = line
+		f.headerSet = true
+		return nil
+	}
+	if line != f.header {
+		return fmt.Errorf("%s: input header differs from the first input", name)
+	}
 	return nil
 }
 
-// producer 生产者线程
-func (f *PCFrame) producer(reader io.Reader) {
-	defer f.wg.Done()
-	defer close(f.buffer)
+// cancelCheckInterval 生产者每读取多少行检查一次是否被取消
+const cancelCheckInterval = 1024
+
+// produce 从一个输入读取行并分批发送，返回是否继续读取后续输入
+// 批次不跨越输入，读完一个输入时发送剩余的行；被取消时丢弃未发送的行
+// 每次发送后检查是否需要写检查点，因此小于一个批次的输入和缓慢的流同样会按间隔写检查点
+func (f *PCFrame) produce(reader io.Reader, name string, st *producerState) bool {
+	st.source = name
+	if f.stopped() {
+		return false
+	}
 
 	scanner := bufio.NewScanner(reader)
 	// 设置更大的缓冲区 (10MB) 以支持超长特征行
@@ -68,44 +289,157 @@ func (f *PCFrame) producer(reader io.Reader) {
 	buf := make([]byte, maxScanTokenSize)
 	scanner.Buffer(buf, maxScanTokenSize)
 
-	lineNum := 0
-	batch := make([]string, 0, f.bufSize)
+	headerTask, needHeader := f.task.(HeaderTask)
+	needHeader = needHeader && headerTask.NeedHeader()
+	var fileLines int64
 
 	for scanner.Scan() {
 		line := scanner.Text()
-		lineNum++
+		st.lineNum++
+		fileLines++
+		if fileLines%cancelCheckInterval == 0 && f.ctx.Err() != nil {
+			return false
+		}
 
-		batch = append(batch, line)
+		// 表头在跳过已处理的行之前读取，断点续训时同样需要
+		if needHeader && fileLines == 1 {
+			if err := f.checkHeader(headerTask, line, name); err != nil {
+				f.setErr(err)
+				return false
+			}
+			continue
+		}
 
-		if len(batch) >= f.bufSize {
-			// 发送批次
-			f.buffer <- batch
-			batch = make([]string, 0, f.bufSize)
+		// 跳过已经处理过的行
+		if st.lineNum <= f.skipLines {
+			if st.lineNum == f.skipLines {
+				fmt.Printf("skipped %d lines already covered by checkpoint\n", st.lineNum)
+			}
+			continue
+		}
+
+		if len(st.batch) == 0 {
+			st.firstLine = fileLines
+		}
+		st.batch = append(st.batch, line)
 
-			if lineNum%f.logNum == 0 {
-				fmt.Printf("%d lines finished\n", lineNum)
+		// 批次已满，或按时间的检查点已到期（缓慢的流可能很久攒不满一个批次）时发送
+		if len(st.batch) >= f.bufSize || f.checkpointDue(0, st.lastCkptTime) {
+			if !f.flushCheckpoint(st) {
+				return false
 			}
 		}
 	}
 
-	// 发送最后一批
-	if len(batch) > 0 {
-		f.buffer <- batch
+	if err := scanner.Err(); err != nil {
+		f.setErr(fmt.Errorf("%s: error reading input: %v", name, err))
+		return false
+	}
+	if needHeader && fileLines == 0 {
+		f.setErr(fmt.Errorf("%s: input header: empty input", name))
+		return false
 	}
+	// 批次不跨越输入，发送剩余的行
+	return f.flushCheckpoint(st)
+}
 
-	if err := scanner.Err(); err != nil {
-		fmt.Printf("Error reading input: %v\n", err)
+// flushCheckpoint 发送当前批次，到达检查点间隔时在已发送的位置写检查点
+// 返回是否继续读取
+func (f *PCFrame) flushCheckpoint(st *producerState) bool {
+	f.flush(st)
+	if f.stopped() {
+		return false
+	}
+	if st.sentLine > st.lastCkptLine && f.checkpointDue(st.sentLine-st.lastCkptLine, st.lastCkptTime) {
+		f.checkpoint(st.sentLine)
+		st.lastCkptLine = st.sentLine
+		st.lastCkptTime = time.Now()
+	}
+	return true
+}
+
+// flush 发送当前批次，被取消时丢弃
+func (f *PCFrame) flush(st *producerState) {
+	if len(st.batch) == 0 {
+		return
+	}
+	if f.stopped() {
+		st.batch = st.batch[:0]
+		return
+	}
+	if f.send(&Batch{Source: st.source, FirstLine: st.firstLine, Lines: st.batch}) {
+		st.sentLine = st.lineNum
+	}
+	st.batch = make([]string, 0, f.bufSize)
+}
+
+// send 发送一个批次给消费者，队列已满时等待；等待期间被取消则丢弃批次并返回 false
+func (f *PCFrame) send(batch *Batch) bool {
+	f.pending.Add(1)
+	select {
+	case f.buffer <- batch:
+	case <-f.ctx.Done():
+		f.pending.Done()
+		return false
+	}
+
+	n := int64(len(batch.Lines))
+	total := atomic.AddInt64(&f.sent, n)
+	if f.logNum > 0 && total/int64(f.logNum) > (total-n)/int64(f.logNum) {
+		fmt.Printf("%d lines finished\n", total/int64(f.logNum)*int64(f.logNum))
+	}
+	return true
+}
+
+// checkpointDue 判断是否需要触发检查点
+func (f *PCFrame) checkpointDue(linesSince int64, lastTime time.Time) bool {
+	if f.ckptFunc == nil {
+		return false
+	}
+	if f.ckptLines > 0 && linesSince >= f.ckptLines {
+		return true
+	}
+	return f.ckptInterval > 0 && time.Since(lastTime) >= f.ckptInterval
+}
+
+// checkpoint 等待已分发批次全部处理完成后调用检查点回调
+func (f *PCFrame) checkpoint(lines int64) {
+	f.pending.Wait()
+	if err := f.ckptFunc(lines); err != nil {
+		fmt.Printf("Warning: checkpoint at line %d failed: %v\n", lines, err)
+	}
+}
+
+// finalCheckpoint 被取消时等待已分发的批次处理完成，在最后一个已分发批次处写检查点
+// 之后用 -resume 重新启动即可从中断处继续，不会重复或遗漏样本
+func (f *PCFrame) finalCheckpoint(st *producerState) {
+	if f.ckptFunc == nil || f.ctx.Err() == nil || f.getErr() != nil {
+		return
+	}
+	if st.sentLine > st.lastCkptLine {
+		f.checkpoint(st.sentLine)
 	}
 }
 
 // consumer 消费者线程
+// 任务返回错误时记为致命错误，生产者随后停止读取，已在队列中的批次被丢弃；
+// 被取消时已在队列中的批次仍会处理完，保证模型与已分发的输入一致
 func (f *PCFrame) consumer() {
 	defer f.wg.Done()
 
+	batchTask, withBatch := f.task.(BatchTask)
 	for batch := range f.buffer {
-		if err := f.task.RunTask(batch); err != nil {
-			fmt.Printf("Error processing batch: %v\n", err)
+		if f.getErr() == nil {
+			var err error
+			if withBatch {
+				err = batchTask.RunBatch(batch)
+			} else {
+				err = f.task.RunTask(batch.Lines)
+			}
+			if err != nil {
+				f.setErr(err)
+			}
 		}
+		f.pending.Done()
 	}
 }
-
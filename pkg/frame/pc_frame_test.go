@@ -0,0 +1,183 @@
+package frame
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// countTask 统计处理的行数，处理到 cancelAt 行时调用 cancel
+type countTask struct {
+	mu       sync.Mutex
+	lines    int64
+	cancelAt int64
+	cancel   context.CancelFunc
+}
+
+func (t *countTask) RunTask(dataBuffer []string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.lines += int64(len(dataBuffer))
+	if t.cancel != nil && t.lines >= t.cancelAt {
+		t.cancel()
+	}
+	return nil
+}
+
+func testInput(n int) string {
+	return strings.Repeat("1 a:1\n", n)
+}
+
+func TestRunCompletes(t *testing.T) {
+	task := &countTask{}
+	f := NewPCFrame()
+	f.Init(task, 2)
+	f.bufSize = 10
+	if err := f.Run(context.Background(), strings.NewReader(testInput(95))); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if task.lines != 95 {
+		t.Errorf("processed %d lines, want 95", task.lines)
+	}
+}
+
+func TestRunCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	task := &countTask{cancelAt: 20, cancel: cancel}
+
+	var ckpts []int64
+	f := NewPCFrame()
+	f.Init(task, 1)
+	f.bufSize = 10
+	f.SetCheckpoint(1000000, 0, func(lines int64) error {
+		ckpts = append(ckpts, lines)
+		return nil
+	})
+
+	err := f.Run(ctx, strings.NewReader(testInput(5000)))
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Run error = %v, want context.Canceled", err)
+	}
+	if task.lines >= 5000 || task.lines%10 != 0 {
+		t.Errorf("processed %d lines, want a prefix of whole batches", task.lines)
+	}
+	// 取消时的检查点恰好覆盖已处理的行
+	if len(ckpts) != 1 || ckpts[0] != task.lines {
+		t.Errorf("checkpoints = %v, want [%d]", ckpts, task.lines)
+	}
+}
+
+func TestRunCanceledBeforeStart(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	task := &countTask{}
+	f := NewPCFrame()
+	f.Init(task, 1)
+	if err := f.Run(ctx, strings.NewReader(testInput(10))); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Run error = %v, want context.Canceled", err)
+	}
+	if task.lines != 0 {
+		t.Errorf("processed %d lines after cancel, want 0", task.lines)
+	}
+}
+
+func TestSkipLinesCheckpointOffset(t *testing.T) {
+	task := &countTask{}
+	var ckpts []int64
+	f := NewPCFrame()
+	f.Init(task, 1)
+	f.bufSize = 10
+	f.SetSkipLines(30)
+	f.SetCheckpoint(20, 0, func(lines int64) error {
+		// 回调时已分发的批次全部处理完成
+		task.mu.Lock()
+		processed := task.lines
+		task.mu.Unlock()
+		if processed != lines-30 {
+			t.Errorf("checkpoint at line %d after %d processed lines", lines, processed)
+		}
+		ckpts = append(ckpts, lines)
+		return nil
+	})
+
+	if err := f.Run(context.Background(), strings.NewReader(testInput(95))); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if task.lines != 65 {
+		t.Errorf("processed %d lines, want 65", task.lines)
+	}
+	// 检查点的行号从输入开头计数，包含跳过的行
+	if want := []int64{50, 70, 90}; !reflect.DeepEqual(ckpts, want) {
+		t.Errorf("checkpoints = %v, want %v", ckpts, want)
+	}
+}
+
+func TestCheckpointSmallFiles(t *testing.T) {
+	// 每个文件都小于一个批次，检查点在文件结束发送剩余行后触发
+	dir := t.TempDir()
+	var paths []string
+	for i := 0; i < 6; i++ {
+		path := filepath.Join(dir, fmt.Sprintf("part-%d", i))
+		if err := os.WriteFile(path, []byte(testInput(7)), 0644); err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, path)
+	}
+
+	task := &countTask{}
+	var ckpts []int64
+	f := NewPCFrame()
+	f.Init(task, 2)
+	f.bufSize = 10
+	f.SetCheckpoint(20, 0, func(lines int64) error {
+		ckpts = append(ckpts, lines)
+		return nil
+	})
+	if err := f.RunFiles(context.Background(), paths, 1); err != nil {
+		t.Fatalf("RunFiles: %v", err)
+	}
+	if task.lines != 42 {
+		t.Errorf("processed %d lines, want 42", task.lines)
+	}
+	if want := []int64{21, 42}; !reflect.DeepEqual(ckpts, want) {
+		t.Errorf("checkpoints = %v, want %v", ckpts, want)
+	}
+}
+
+func TestCheckpointSlowStream(t *testing.T) {
+	// 缓慢的流攒不满一个批次，按时间的检查点到期时发送已读的行并写检查点
+	r, w := io.Pipe()
+	go func() {
+		for i := 0; i < 3; i++ {
+			if i > 0 {
+				time.Sleep(50 * time.Millisecond)
+			}
+			io.WriteString(w, "1 a:1\n")
+		}
+		w.Close()
+	}()
+
+	task := &countTask{}
+	var ckpts []int64
+	f := NewPCFrame()
+	f.Init(task, 1)
+	f.SetCheckpoint(0, 10*time.Millisecond, func(lines int64) error {
+		ckpts = append(ckpts, lines)
+		return nil
+	})
+	if err := f.Run(context.Background(), r); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if want := []int64{2, 3}; !reflect.DeepEqual(ckpts, want) {
+		t.Errorf("checkpoints = %v, want %v", ckpts, want)
+	}
+}
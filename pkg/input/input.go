@@ -0,0 +1,117 @@
+package input
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// 压缩格式的魔数
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// Stdin 表示标准输入的路径
+const Stdin = "-"
+
+// readCloser 组合解压读取器和底层文件的关闭
+type readCloser struct {
+	io.Reader
+	closers []io.Closer
+}
+
+func (r *readCloser) Close() error {
+	var first error
+	for _, c := range r.closers {
+		if err := c.Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// Open 打开输入文件，根据文件头自动识别并解压gzip（包括多段拼接的gzip）
+// path 为 "-" 时读取标准输入；zstd 文件会给出明确的错误而不是按文本读取出乱码
+func Open(path string) (io.ReadCloser, error) {
+	var file io.ReadCloser
+	if path == Stdin {
+		file = io.NopCloser(os.Stdin)
+	} else {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		file = f
+	}
+	return wrap(path, file)
+}
+
+// wrap 根据文件头包装解压读取器
+func wrap(path string, file io.ReadCloser) (io.ReadCloser, error) {
+	br := bufio.NewReaderSize(file, 1<<20)
+	head, err := br.Peek(4)
+	if err != nil && err != io.EOF {
+		file.Close()
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+
+	switch {
+	case bytes.HasPrefix(head, gzipMagic):
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			file.Close()
+			return nil, fmt.Errorf("%s: invalid gzip data: %v", path, err)
+		}
+		return &readCloser{Reader: gz, closers: []io.Closer{gz, file}}, nil
+	case bytes.HasPrefix(head, zstdMagic):
+		file.Close()
+		return nil, fmt.Errorf("%s: zstd compressed input is not supported, decompress it first (e.g. zstd -dc %s | ...)", path, path)
+	default:
+		return &readCloser{Reader: br, closers: []io.Closer{file}}, nil
+	}
+}
+
+// Expand 展开路径列表中的glob模式
+// 每个模式的匹配结果按文件名排序，模式之间保持给出的顺序，重复的文件只保留第一次；
+// 没有任何匹配的模式报错，避免拼错路径时静默地少读数据
+func Expand(patterns []string) ([]string, error) {
+	seen := make(map[string]bool)
+	var paths []string
+	for _, pattern := range patterns {
+		if pattern == Stdin {
+			if !seen[pattern] {
+				seen[pattern] = true
+				paths = append(paths, pattern)
+			}
+			continue
+		}
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid input pattern %s: %v", pattern, err)
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("no input files match %s", pattern)
+		}
+		sort.Strings(matches)
+		for _, path := range matches {
+			info, err := os.Stat(path)
+			if err != nil {
+				return nil, err
+			}
+			if info.IsDir() {
+				return nil, fmt.Errorf("input %s is a directory", path)
+			}
+			if !seen[path] {
+				seen[path] = true
+				paths = append(paths, path)
+			}
+		}
+	}
+	return paths, nil
+}
@@ -0,0 +1,92 @@
+package input
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeFile(t *testing.T, path string, data []byte) {
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func readAll(t *testing.T, path string) string {
+	r, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	data, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestOpen(t *testing.T) {
+	dir := t.TempDir()
+
+	plain := filepath.Join(dir, "plain.txt")
+	writeFile(t, plain, []byte("1 a:b:1\n"))
+	if got := readAll(t, plain); got != "1 a:b:1\n" {
+		t.Errorf("plain: got %q", got)
+	}
+
+	// 多段拼接的gzip
+	var buf bytes.Buffer
+	for _, part := range []string{"1 a:b:1\n", "0 a:c:1\n"} {
+		gz := gzip.NewWriter(&buf)
+		gz.Write([]byte(part))
+		gz.Close()
+	}
+	compressed := filepath.Join(dir, "data.gz")
+	writeFile(t, compressed, buf.Bytes())
+	if got := readAll(t, compressed); got != "1 a:b:1\n0 a:c:1\n" {
+		t.Errorf("gzip: got %q", got)
+	}
+
+	zstd := filepath.Join(dir, "data.zst")
+	writeFile(t, zstd, []byte{0x28, 0xb5, 0x2f, 0xfd, 0, 0})
+	if _, err := Open(zstd); err == nil {
+		t.Error("expected error for zstd input")
+	}
+
+	empty := filepath.Join(dir, "empty.txt")
+	writeFile(t, empty, nil)
+	if got := readAll(t, empty); got != "" {
+		t.Errorf("empty: got %q", got)
+	}
+}
+
+func TestExpand(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"b.txt", "a.txt", "c.gz"} {
+		writeFile(t, filepath.Join(dir, name), nil)
+	}
+
+	paths, err := Expand([]string{filepath.Join(dir, "*.txt"), filepath.Join(dir, "c.gz"), filepath.Join(dir, "a.txt")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"a.txt", "b.txt", "c.gz"}
+	if len(paths) != len(want) {
+		t.Fatalf("got %v", paths)
+	}
+	for i := range want {
+		if filepath.Base(paths[i]) != want[i] {
+			t.Errorf("path %d: got %s, want %s", i, paths[i], want[i])
+		}
+	}
+
+	if _, err := Expand([]string{filepath.Join(dir, "*.csv")}); err == nil {
+		t.Error("expected error for pattern without matches")
+	}
+	if _, err := Expand([]string{dir}); err == nil {
+		t.Error("expected error for directory")
+	}
+}
@@ -0,0 +1,291 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Default 默认的指标注册表，Serve 输出其中的指标
+var Default = NewRegistry()
+
+// collector 可以按 Prometheus 文本格式输出的指标
+type collector interface {
+	name() string
+	write(w io.Writer)
+}
+
+// Registry 指标注册表，同名的计数器和直方图只能注册一次
+type Registry struct {
+	mu         sync.Mutex
+	collectors map[string]collector
+}
+
+// NewRegistry 创建指标注册表
+func NewRegistry() *Registry {
+	return &Registry{collectors: make(map[string]collector)}
+}
+
+// register 注册指标，重名时 panic（指标名在代码中固定，重名是编程错误）
+func (r *Registry) register(c collector) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.collectors[c.name()]; ok {
+		panic(fmt.Sprintf("metrics: duplicate metric %s", c.name()))
+	}
+	r.collectors[c.name()] = c
+}
+
+// Write 按 Prometheus 文本格式（0.0.4）输出所有指标，按名称排序
+func (r *Registry) Write(w io.Writer) {
+	r.mu.Lock()
+	collectors := make([]collector, 0, len(r.collectors))
+	for _, c := range r.collectors {
+		collectors = append(collectors, c)
+	}
+	r.mu.Unlock()
+
+	sort.Slice(collectors, func(i, j int) bool { return collectors[i].name() < collectors[j].name() })
+	for _, c := range collectors {
+		c.write(w)
+	}
+}
+
+// Handler 返回输出注册表中指标的 HTTP handler
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		r.Write(w)
+	})
+}
+
+// Serve 在 addr 上启动指标服务（/metrics），在后台运行，返回实际监听的地址
+func Serve(addr string) (string, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return "", fmt.Errorf("metrics listen error: %v", err)
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", Default.Handler())
+	go func() {
+		if err := http.Serve(ln, mux); err != nil {
+			fmt.Printf("Warning: metrics server stopped: %v\n", err)
+		}
+	}()
+	return ln.Addr().String(), nil
+}
+
+// header 输出 HELP 和 TYPE 行
+func header(w io.Writer, name, help, typ string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, escapeHelp(help), name, typ)
+}
+
+func escapeHelp(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(s)
+}
+
+func escapeLabel(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`).Replace(s)
+}
+
+func formatValue(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// Counter 只增不减的计数器
+type Counter struct {
+	metric string
+	help   string
+	v      int64
+}
+
+// NewCounter 创建并注册计数器
+func (r *Registry) NewCounter(name, help string) *Counter {
+	c := &Counter{metric: name, help: help}
+	r.register(c)
+	return c
+}
+
+// Add 增加计数，n 不能为负
+func (c *Counter) Add(n int64) {
+	atomic.AddInt64(&c.v, n)
+}
+
+// Inc 计数加1
+func (c *Counter) Inc() {
+	c.Add(1)
+}
+
+// Value 当前计数
+func (c *Counter) Value() int64 {
+	return atomic.LoadInt64(&c.v)
+}
+
+func (c *Counter) name() string { return c.metric }
+
+func (c *Counter) write(w io.Writer) {
+	header(w, c.metric, c.help, "counter")
+	fmt.Fprintf(w, "%s %d\n", c.metric, c.Value())
+}
+
+// CounterVec 按一个标签区分的一组计数器
+type CounterVec struct {
+	metric string
+	help   string
+	label  string
+
+	mu       sync.RWMutex
+	counters map[string]*int64
+}
+
+// NewCounterVec 创建并注册带标签的计数器
+func (r *Registry) NewCounterVec(name, help, label string) *CounterVec {
+	c := &CounterVec{metric: name, help: help, label: label, counters: make(map[string]*int64)}
+	r.register(c)
+	return c
+}
+
+// Add 增加标签取值为 value 的计数
+func (c *CounterVec) Add(value string, n int64) {
+	c.mu.RLock()
+	v, ok := c.counters[value]
+	c.mu.RUnlock()
+	if !ok {
+		c.mu.Lock()
+		if v, ok = c.counters[value]; !ok {
+			v = new(int64)
+			c.counters[value] = v
+		}
+		c.mu.Unlock()
+	}
+	atomic.AddInt64(v, n)
+}
+
+// Value 标签取值为 value 的计数
+func (c *CounterVec) Value(value string) int64 {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if v, ok := c.counters[value]; ok {
+		return atomic.LoadInt64(v)
+	}
+	return 0
+}
+
+func (c *CounterVec) name() string { return c.metric }
+
+func (c *CounterVec) write(w io.Writer) {
+	c.mu.RLock()
+	values := make([]string, 0, len(c.counters))
+	for value := range c.counters {
+		values = append(values, value)
+	}
+	c.mu.RUnlock()
+	sort.Strings(values)
+
+	header(w, c.metric, c.help, "counter")
+	for _, value := range values {
+		fmt.Fprintf(w, "%s{%s=\"%s\"} %d\n", c.metric, c.label, escapeLabel(value), c.Value(value))
+	}
+}
+
+// GaugeFunc 输出时调用函数取值的仪表
+type GaugeFunc struct {
+	metric string
+	help   string
+	fn     func() float64
+}
+
+// NewGaugeFunc 注册仪表，同名的旧仪表被替换
+// 模型、队列等对象在运行时创建，取值函数在创建后注册
+func (r *Registry) NewGaugeFunc(name, help string, fn func() float64) *GaugeFunc {
+	g := &GaugeFunc{metric: name, help: help, fn: fn}
+	r.mu.Lock()
+	r.collectors[name] = g
+	r.mu.Unlock()
+	return g
+}
+
+func (g *GaugeFunc) name() string { return g.metric }
+
+func (g *GaugeFunc) write(w io.Writer) {
+	header(w, g.metric, g.help, "gauge")
+	fmt.Fprintf(w, "%s %s\n", g.metric, formatValue(g.fn()))
+}
+
+// ExponentialBuckets 生成 count 个桶的上界: start, start*factor, ...
+func ExponentialBuckets(start, factor float64, count int) []float64 {
+	buckets := make([]float64, count)
+	for i := range buckets {
+		buckets[i] = start
+		start *= factor
+	}
+	return buckets
+}
+
+// Histogram 直方图，记录每个桶（<= 上界）的累计次数、总和与总次数
+// Observe 不加锁，多个线程逐样本记录时没有竞争
+type Histogram struct {
+	metric  string
+	help    string
+	buckets []float64
+
+	counts  []int64 // 每个桶（非累计）的次数，最后一个为 +Inf
+	sumBits uint64  // 总和的 float64 位表示
+	count   int64
+}
+
+// NewHistogram 创建并注册直方图，buckets 须升序
+func (r *Registry) NewHistogram(name, help string, buckets []float64) *Histogram {
+	h := &Histogram{metric: name, help: help, buckets: buckets, counts: make([]int64, len(buckets)+1)}
+	r.register(h)
+	return h
+}
+
+// Observe 记录一个观测值
+func (h *Histogram) Observe(v float64) {
+	atomic.AddInt64(&h.counts[sort.SearchFloat64s(h.buckets, v)], 1)
+	for {
+		old := atomic.LoadUint64(&h.sumBits)
+		sum := math.Float64bits(math.Float64frombits(old) + v)
+		if atomic.CompareAndSwapUint64(&h.sumBits, old, sum) {
+			break
+		}
+	}
+	atomic.AddInt64(&h.count, 1)
+}
+
+// Count 观测次数
+func (h *Histogram) Count() int64 {
+	return atomic.LoadInt64(&h.count)
+}
+
+func (h *Histogram) name() string { return h.metric }
+
+func (h *Histogram) write(w io.Writer) {
+	// 输出期间可能有新的观测，+Inf 桶用各桶之和，保证桶计数单调
+	header(w, h.metric, h.help, "histogram")
+	var cumulative int64
+	for i, bound := range h.buckets {
+		cumulative += atomic.LoadInt64(&h.counts[i])
+		fmt.Fprintf(w, "%s_bucket{le=\"%s\"} %d\n", h.metric, formatValue(bound), cumulative)
+	}
+	cumulative += atomic.LoadInt64(&h.counts[len(h.buckets)])
+	sum := math.Float64frombits(atomic.LoadUint64(&h.sumBits))
+	fmt.Fprintf(w, "%s_bucket{le=\"+Inf\"} %d\n", h.metric, cumulative)
+	fmt.Fprintf(w, "%s_sum %s\n%s_count %d\n", h.metric, formatValue(sum), h.metric, cumulative)
+}
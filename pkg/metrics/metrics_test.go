@@ -0,0 +1,80 @@
+package metrics
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestRegistryWrite(t *testing.T) {
+	r := NewRegistry()
+	c := r.NewCounter("test_events_total", "Events.")
+	c.Add(2)
+	c.Inc()
+	v := r.NewCounterVec("test_errors_total", "Errors by reason.", "reason")
+	v.Add("bad \"quote\"", 1)
+	v.Add("empty", 2)
+	r.NewGaugeFunc("test_depth", "Depth.", func() float64 { return 1 })
+	r.NewGaugeFunc("test_depth", "Depth.", func() float64 { return 4 }) // 替换旧的取值函数
+	h := r.NewHistogram("test_latency_seconds", "Latency.", []float64{0.1, 1})
+	h.Observe(0.05)
+	h.Observe(0.1)
+	h.Observe(5)
+
+	var buf bytes.Buffer
+	r.Write(&buf)
+	want := `# HELP test_depth Depth.
+# TYPE test_depth gauge
+test_depth 4
+# HELP test_errors_total Errors by reason.
+# TYPE test_errors_total counter
+test_errors_total{reason="bad \"quote\""} 1
+test_errors_total{reason="empty"} 2
+# HELP test_events_total Events.
+# TYPE test_events_total counter
+test_events_total 3
+# HELP test_latency_seconds Latency.
+# TYPE test_latency_seconds histogram
+test_latency_seconds_bucket{le="0.1"} 2
+test_latency_seconds_bucket{le="1"} 2
+test_latency_seconds_bucket{le="+Inf"} 3
+test_latency_seconds_sum 5.15
+test_latency_seconds_count 3
+`
+	if buf.String() != want {
+		t.Errorf("output:\n%s\nwant:\n%s", buf.String(), want)
+	}
+}
+
+func TestDuplicateCounterPanics(t *testing.T) {
+	r := NewRegistry()
+	r.NewCounter("dup_total", "")
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic for duplicate metric")
+		}
+	}()
+	r.NewCounter("dup_total", "")
+}
+
+func TestServe(t *testing.T) {
+	Default.NewGaugeFunc("test_serve_up", "Up.", func() float64 { return 1 })
+	addr, err := Serve("127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Serve: %v", err)
+	}
+	resp, err := http.Get("http://" + addr + "/metrics")
+	if err != nil {
+		t.Fatalf("GET: %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if !strings.Contains(string(body), "test_serve_up 1\n") {
+		t.Errorf("response missing gauge:\n%s", body)
+	}
+	if ct := resp.Header.Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("Content-Type = %q", ct)
+	}
+}
@@ -0,0 +1,126 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/xiongle/alphaFFM-go/pkg/sample"
+)
+
+// trainBatchSize Train 每攒多少个样本训练一次，并在批次之间检查 ctx
+const trainBatchSize = 1000
+
+// ErrModelNotLoaded 预测模型中没有 bias（未加载或为空）
+var ErrModelNotLoaded = errors.New("model not loaded")
+
+// Train 从迭代器读取样本并训练，直到迭代器返回 io.EOF，返回本次训练的样本数
+// 用于在进程内直接训练，不经过文本解析；样本须带有域（FFM 格式），权重为0的样本不更新模型
+// 训练器的域配置中有分桶或归一化设置时，样本像文本输入一样先分桶、再归一化（见 sample.Prepare）
+// 多任务训练时每个样本的 Labels 个数须与任务数一致
+// 样本无效或迭代器出错时停止并返回错误，之前的样本已经训练；ctx 取消时返回 ctx.Err()
+// 多个 goroutine 可以同时对同一个训练器调用 Train
+func (t *FFMTrainer) Train(ctx context.Context, it sample.SampleIterator) (int64, error) {
+	var trained int64
+	batch := make([]*sample.FFMSample, 0, trainBatchSize)
+	flush := func() {
+		t.trainSamples(batch)
+		samplesTotal.Add(taskTrain, int64(len(batch)))
+		trained += int64(len(batch))
+		batch = batch[:0]
+	}
+
+	for {
+		if len(batch) == 0 {
+			if err := ctx.Err(); err != nil {
+				return trained, err
+			}
+		}
+		s, err := it.Next()
+		if err == nil && s == nil {
+			err = errors.New("nil sample")
+		} else if err == nil {
+			if err = s.Validate(); err == nil {
+				err = sample.CheckTaskLabels(s, t.model.NumTasks())
+			}
+			if err == nil {
+				s, err = sample.Prepare(s, t.fieldConfig)
+			}
+		} else if err != io.EOF {
+			err = fmt.Errorf("read sample error: %w", err)
+		}
+		if err != nil {
+			index := trained + int64(len(batch))
+			flush()
+			if err == io.EOF {
+				return trained, nil
+			}
+			return trained, fmt.Errorf("sample %d: %w", index, err)
+		}
+		batch = append(batch, s)
+		if len(batch) == trainBatchSize {
+			flush()
+		}
+	}
+}
+
+// prepare 校验样本并按 FieldConfig 分桶和归一化
+func (m *PredictModel) prepare(s *sample.FFMSample) (*sample.FFMSample, error) {
+	if err := sample.ValidateFeatures(s.X); err != nil {
+		return nil, err
+	}
+	return sample.Prepare(s, m.FieldConfig)
+}
+
+// Score 计算单个样本的预测概率，不认识的特征被忽略
+// 设置了 FieldConfig 时先按其分桶和归一化，与训练时的处理一致
+func (m *PredictModel) Score(s sample.FFMSample) (float64, error) {
+	if m.MuBias == nil {
+		return 0, ErrModelNotLoaded
+	}
+	p, err := m.prepare(&s)
+	if err != nil {
+		return 0, err
+	}
+	return m.GetScore(toPredictInput(p), m.MuBias.Wi), nil
+}
+
+// ScoreBatch 计算一批样本的预测概率，结果与输入一一对应
+// 任一样本无效时返回错误，错误信息中带有样本序号
+func (m *PredictModel) ScoreBatch(samples []sample.FFMSample) ([]float64, error) {
+	if m.MuBias == nil {
+		return nil, ErrModelNotLoaded
+	}
+	scores := make([]float64, len(samples))
+	for i := range samples {
+		p, err := m.prepare(&samples[i])
+		if err != nil {
+			return nil, fmt.Errorf("sample %d: %w", i, err)
+		}
+		scores[i] = m.GetScore(toPredictInput(p), m.MuBias.Wi)
+	}
+	return scores, nil
+}
+
+// ScoreTasks 计算单个样本在每个任务上的预测概率，顺序与 Tasks 一致；单任务模型返回一个概率
+func (m *PredictModel) ScoreTasks(s sample.FFMSample) ([]float64, error) {
+	if m.MuBias == nil {
+		return nil, ErrModelNotLoaded
+	}
+	p, err := m.prepare(&s)
+	if err != nil {
+		return nil, err
+	}
+	return m.GetTaskScores(toPredictInput(p)), nil
+}
+
+// OpenPredictModel 加载预测模型，隐向量维度从模型头读取
+// 模型训练时使用了带分桶或归一化的域配置时，须把同一配置设置到 FieldConfig，否则打分的输入与训练时不一致
+func OpenPredictModel(modelPath, modelFormat string) (*PredictModel, error) {
+	m := NewPredictModel(0)
+	if err := m.LoadModel(modelPath, modelFormat); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
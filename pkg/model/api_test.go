@@ -0,0 +1,187 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/xiongle/alphaFFM-go/pkg/sample"
+)
+
+// apiSamples 用户 u1 只点击 i1，用户 u2 只点击 i2
+func apiSamples(n int) []*sample.FFMSample {
+	samples := make([]*sample.FFMSample, 0, n)
+	for i := 0; i < n; i++ {
+		user, item := "u1", "i1"
+		if i%2 == 1 {
+			user = "u2"
+		}
+		if i%4 >= 2 {
+			item = "i2"
+		}
+		samples = append(samples, sample.NewFFMSample((user == "u1") == (item == "i1"), []sample.FeatureValue{
+			{Field: "user", Feature: user, Value: 1},
+			{Field: "item", Feature: item, Value: 1},
+		}))
+	}
+	return samples
+}
+
+func TestTrainAndScore(t *testing.T) {
+	opt := NewTrainerOption()
+	opt.FactorNum = 4
+	opt.WL1, opt.VL1 = 0, 0
+	trainer := NewFFMTrainer(opt)
+
+	n, err := trainer.Train(context.Background(), sample.NewSliceIterator(apiSamples(4000)))
+	if err != nil {
+		t.Fatalf("Train: %v", err)
+	}
+	if n != 4000 {
+		t.Errorf("trained %d samples, want 4000", n)
+	}
+
+	path := filepath.Join(t.TempDir(), "model.txt")
+	if err := trainer.OutputModel(path, "txt"); err != nil {
+		t.Fatalf("OutputModel: %v", err)
+	}
+	m, err := OpenPredictModel(path, "txt")
+	if err != nil {
+		t.Fatalf("OpenPredictModel: %v", err)
+	}
+
+	pair := func(user, item string) sample.FFMSample {
+		return *sample.NewFFMSample(true, []sample.FeatureValue{
+			{Field: "user", Feature: user, Value: 1},
+			{Field: "item", Feature: item, Value: 1},
+		})
+	}
+	scores, err := m.ScoreBatch([]sample.FFMSample{pair("u1", "i1"), pair("u1", "i2"), pair("u2", "i2")})
+	if err != nil {
+		t.Fatalf("ScoreBatch: %v", err)
+	}
+	if scores[0] <= scores[1] || scores[2] <= scores[1] {
+		t.Errorf("scores %v do not reflect the interactions", scores)
+	}
+	score, err := m.Score(pair("u1", "i1"))
+	if err != nil || score != scores[0] {
+		t.Errorf("Score = %v, %v, want %v", score, err, scores[0])
+	}
+
+	bad := pair("u1", "i1")
+	bad.X[0].Value = math.NaN()
+	if _, err := m.ScoreBatch([]sample.FFMSample{pair("u1", "i1"), bad}); err == nil {
+		t.Error("expected error for NaN feature value")
+	}
+	if _, err := NewPredictModel(4).Score(pair("u1", "i1")); !errors.Is(err, ErrModelNotLoaded) {
+		t.Errorf("empty model error = %v, want ErrModelNotLoaded", err)
+	}
+}
+
+func TestTrainErrors(t *testing.T) {
+	trainer := NewFFMTrainer(NewTrainerOption())
+
+	samples := apiSamples(5)
+	samples[3].Y = 0
+	n, err := trainer.Train(context.Background(), sample.NewSliceIterator(samples))
+	var pe *sample.ParseError
+	if !errors.As(err, &pe) || pe.Reason != sample.ReasonInvalidLabel {
+		t.Fatalf("Train error = %v, want invalid label", err)
+	}
+	if n != 3 {
+		t.Errorf("trained %d samples before the invalid one, want 3", n)
+	}
+
+	// 域为空或名字中有空白字符的样本写出的模型无法再加载
+	for _, fv := range []sample.FeatureValue{
+		{Field: "", Feature: "a", Value: 1},
+		{Field: "user", Feature: "a b", Value: 1},
+		{Field: "us\u00a0er", Feature: "a", Value: 1},
+	} {
+		bad := []*sample.FFMSample{sample.NewFFMSample(true, []sample.FeatureValue{fv})}
+		n, err := NewFFMTrainer(NewTrainerOption()).Train(context.Background(), sample.NewSliceIterator(bad))
+		if !errors.As(err, &pe) || pe.Reason != sample.ReasonInvalidFeature || n != 0 {
+			t.Errorf("%+v: Train = %d, %v, want invalid feature", fv, n, err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := trainer.Train(ctx, sample.NewSliceIterator(apiSamples(5))); !errors.Is(err, context.Canceled) {
+		t.Errorf("Train error = %v, want context.Canceled", err)
+	}
+}
+
+func TestTrainPreparesSamples(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "field_config.json")
+	cfg := `{"mode":"explicit","instance_norm":true,"buckets":{"age":{"boundaries":[18,30,50]}}}`
+	if err := os.WriteFile(path, []byte(cfg), 0644); err != nil {
+		t.Fatal(err)
+	}
+	newTrainer := func() *FFMTrainer {
+		opt := NewTrainerOption()
+		opt.FactorNum = 2
+		opt.FieldConfigPath = path
+		return NewFFMTrainer(opt)
+	}
+
+	// 同样的样本分别以文本和 FFMSample 输入，隐向量用相同的随机序列初始化
+	var lines []string
+	var samples []*sample.FFMSample
+	for i, s := range apiSamples(200) {
+		age := float64(10 + i%60)
+		lines = append(lines, fmt.Sprintf("%d user:%s:2 item:%s:0.5 ctx:age:%g",
+			s.Y, s.X[0].Feature, s.X[1].Feature, age))
+		samples = append(samples, sample.NewFFMSample(s.Y > 0, []sample.FeatureValue{
+			{Field: "user", Feature: s.X[0].Feature, Value: 2},
+			{Field: "item", Feature: s.X[1].Feature, Value: 0.5},
+			{Field: "ctx", Feature: "age", Value: age},
+		}))
+	}
+	cli, api := newTrainer(), newTrainer()
+	rand.Seed(1)
+	if err := cli.RunTask(lines); err != nil {
+		t.Fatalf("RunTask: %v", err)
+	}
+	rand.Seed(1)
+	if _, err := api.Train(context.Background(), sample.NewSliceIterator(samples)); err != nil {
+		t.Fatalf("Train: %v", err)
+	}
+
+	if len(api.model.MuMap) != len(cli.model.MuMap) || api.model.MuMap["age"] != nil {
+		t.Fatalf("api model has %d features, cli %d", len(api.model.MuMap), len(cli.model.MuMap))
+	}
+	if api.model.MuBias.Wi != cli.model.MuBias.Wi {
+		t.Errorf("bias: api %v, cli %v", api.model.MuBias.Wi, cli.model.MuBias.Wi)
+	}
+	for feature, cu := range cli.model.MuMap {
+		au := api.model.MuMap[feature]
+		if au == nil || au.Wi != cu.Wi || !reflect.DeepEqual(au.ViMap, cu.ViMap) {
+			t.Errorf("feature %s differs between api and cli training", feature)
+		}
+	}
+
+	// 训练器交出的预测模型带有域配置，打分同样先分桶和归一化
+	pm := api.PredictModel()
+	raw := *samples[0]
+	score, err := pm.Score(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	prepared, err := sample.Prepare(&raw, pm.FieldConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := pm.GetScore(toPredictInput(prepared), pm.MuBias.Wi); score != want {
+		t.Errorf("Score = %v, want %v", score, want)
+	}
+	if raw.X[2].Feature != "age" || raw.X[0].Value != 2 {
+		t.Errorf("Score modified the caller's sample: %+v", raw.X)
+	}
+}
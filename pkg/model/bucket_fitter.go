@@ -0,0 +1,182 @@
+package model
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/xiongle/alphaFFM-go/pkg/config"
+	"github.com/xiongle/alphaFFM-go/pkg/frame"
+	"github.com/xiongle/alphaFFM-go/pkg/sample"
+	"github.com/xiongle/alphaFFM-go/pkg/sketch"
+)
+
+// BucketFitOption 分位数边界拟合选项
+type BucketFitOption struct {
+	FieldConfigPath string             // 输入的域配置，其中 quantile 类型的分桶规则会被拟合
+	Features        []string           // 额外需要拟合的数值特征
+	Buckets         int                // 规则中没有指定桶数时使用的桶数
+	Eps             float64            // 分位数摘要的秩误差
+	ErrorPolicy     sample.ErrorPolicy // 坏样本的处理策略
+}
+
+// BucketFitResult 单个特征的拟合结果
+type BucketFitResult struct {
+	Feature    string
+	Count      int64
+	Min        float64
+	Max        float64
+	Boundaries []float64
+}
+
+// BucketFitter 扫描样本，为数值特征学习分位数分桶边界，可直接交给 PCFrame 运行
+type BucketFitter struct {
+	parserTask
+	cfg      *config.FieldConfig // 输出的配置
+	parseCfg *config.FieldConfig // 解析用的配置（不含分桶规则和归一化，以便拿到原始数值）
+	rules    map[string]*config.BucketRule
+	sketches map[string]*sketch.GK
+	mu       sync.Mutex
+}
+
+// NewBucketFitter 创建分位数边界拟合任务
+func NewBucketFitter(opt *BucketFitOption) (*BucketFitter, error) {
+	if opt.Buckets < 2 {
+		return nil, fmt.Errorf("buckets must be at least 2: %d", opt.Buckets)
+	}
+
+	bf := &BucketFitter{
+		rules:    make(map[string]*config.BucketRule),
+		sketches: make(map[string]*sketch.GK),
+	}
+
+	bf.cfg = config.NewFieldConfig()
+	if opt.FieldConfigPath == "" {
+		// 没有输入配置时样本须为FFM格式
+		bf.cfg.Mode = "explicit"
+	} else {
+		if err := bf.cfg.LoadFromJSON(opt.FieldConfigPath); err != nil {
+			if err2 := bf.cfg.LoadFromText(opt.FieldConfigPath); err2 != nil {
+				return nil, fmt.Errorf("failed to load field config from %s (JSON: %v, Text: %v)", opt.FieldConfigPath, err, err2)
+			}
+			bf.cfg.Mode = "config"
+		}
+		parseCfg := bf.cfg.Clone()
+		parseCfg.Buckets = nil
+		parseCfg.FieldNorm = nil
+		parseCfg.InstanceNorm = false
+		if err := parseCfg.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid field config: %v", err)
+		}
+		bf.parseCfg = parseCfg
+	}
+	if bf.cfg.Buckets == nil {
+		bf.cfg.Buckets = make(map[string]*config.BucketRule)
+	}
+
+	for feature, rule := range bf.cfg.Buckets {
+		if rule != nil && rule.Type == config.BucketQuantile {
+			bf.rules[feature] = rule
+		}
+	}
+	for _, feature := range opt.Features {
+		if _, ok := bf.rules[feature]; ok {
+			continue
+		}
+		rule := &config.BucketRule{Type: config.BucketQuantile, Buckets: opt.Buckets}
+		bf.cfg.Buckets[feature] = rule
+		bf.rules[feature] = rule
+	}
+	if len(bf.rules) == 0 {
+		return nil, fmt.Errorf("no features to fit, declare quantile bucket rules in the field config or list features explicitly")
+	}
+
+	for feature, rule := range bf.rules {
+		if rule.Buckets < 2 {
+			rule.Buckets = opt.Buckets
+		}
+		bf.sketches[feature] = sketch.NewGK(opt.Eps)
+	}
+
+	// 坏行按错误策略处理，最后设置以免提前返回时坏行文件未关闭
+	bf.parserTask = newSampleParser(taskFitBuckets, sample.InputFormatFFM, bf.parseCfg, "")
+	// 训练时值为0的数值在跳过0值之前分桶，拟合时同样要统计
+	bf.parserTask.parser = sample.NewZeroKeepingParser(bf.parseCfg)
+	if err := bf.setErrorPolicy(opt.ErrorPolicy); err != nil {
+		return nil, err
+	}
+	return bf, nil
+}
+
+// RunTask 处理一批数据
+func (bf *BucketFitter) RunTask(dataBuffer []string) error {
+	return bf.RunBatch(&frame.Batch{Lines: dataBuffer})
+}
+
+// RunBatch 处理一批带输入位置的数据，坏行按错误策略处理
+func (bf *BucketFitter) RunBatch(batch *frame.Batch) error {
+	samples, err := bf.parseBatch(batch)
+	if err != nil {
+		return err
+	}
+	values := make(map[string][]float64)
+	for _, s := range samples {
+		if s == nil {
+			continue
+		}
+		for _, x := range s.X {
+			if _, ok := bf.rules[x.Feature]; ok {
+				values[x.Feature] = append(values[x.Feature], x.Value)
+			}
+		}
+	}
+
+	bf.mu.Lock()
+	defer bf.mu.Unlock()
+	for feature, vs := range values {
+		sk := bf.sketches[feature]
+		for _, v := range vs {
+			sk.Insert(v)
+		}
+	}
+	return nil
+}
+
+// Fit 根据扫描结果写入分桶边界，返回更新后的配置和每个特征的拟合结果
+// 没有任何取值的特征报错；所有取值都相同的特征只有一个边界
+func (bf *BucketFitter) Fit() (*config.FieldConfig, []BucketFitResult, error) {
+	features := make([]string, 0, len(bf.rules))
+	for feature := range bf.rules {
+		features = append(features, feature)
+	}
+	sort.Strings(features)
+
+	results := make([]BucketFitResult, 0, len(features))
+	var missing []string
+	for _, feature := range features {
+		sk := bf.sketches[feature]
+		if sk.Count() == 0 {
+			missing = append(missing, feature)
+			continue
+		}
+		rule := bf.rules[feature]
+		rule.Boundaries = sk.Boundaries(rule.Buckets)
+		if len(rule.Boundaries) == 0 {
+			rule.Boundaries = []float64{sk.Max()}
+		}
+		results = append(results, BucketFitResult{
+			Feature:    feature,
+			Count:      sk.Count(),
+			Min:        sk.Min(),
+			Max:        sk.Max(),
+			Boundaries: rule.Boundaries,
+		})
+	}
+	if len(missing) > 0 {
+		return nil, nil, fmt.Errorf("no values seen for features: %v", missing)
+	}
+	if err := bf.cfg.Validate(); err != nil {
+		return nil, nil, fmt.Errorf("fitted field config is invalid: %v", err)
+	}
+	return bf.cfg, results, nil
+}
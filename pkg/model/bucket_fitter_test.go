@@ -0,0 +1,113 @@
+package model
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/xiongle/alphaFFM-go/pkg/sample"
+)
+
+func TestBucketFitter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "field_config.json")
+	cfg := `{"mode":"config","feature_to_field":{"age":"user","price":"item"},"use_prefix":true,
+		"numeric_field_threshold":1000000,"buckets":{"age":{"type":"quantile","buckets":4},"price":{"boundaries":[10,100]}}}`
+	if err := os.WriteFile(path, []byte(cfg), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	fitter, err := NewBucketFitter(&BucketFitOption{FieldConfigPath: path, Buckets: 10, Eps: 0.001})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var lines []string
+	for i := 1; i <= 100; i++ {
+		lines = append(lines, fmt.Sprintf("1 age:%d price:%d", i, i))
+	}
+	if err := fitter.RunTask(append(lines, "1 age:x")); err != nil {
+		t.Fatal(err)
+	}
+	if got := fitter.InputSummary(); !strings.HasPrefix(got, "rejected 1 of 101 lines") {
+		t.Errorf("InputSummary = %q", got)
+	}
+
+	fitted, results, err := fitter.Fit()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].Feature != "age" || results[0].Count != 100 {
+		t.Fatalf("results: %+v", results)
+	}
+	want := []float64{25, 50, 75}
+	got := fitted.Buckets["age"].Boundaries
+	if len(got) != len(want) {
+		t.Fatalf("age boundaries: got %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] < want[i]-1 || got[i] > want[i]+1 {
+			t.Errorf("age boundaries: got %v, want about %v", got, want)
+		}
+	}
+	// 非 quantile 规则保持不变
+	if b := fitted.Buckets["price"].Boundaries; len(b) != 2 || b[0] != 10 {
+		t.Errorf("price rule changed: %v", b)
+	}
+}
+
+func TestBucketFitterErrorPolicy(t *testing.T) {
+	fitter, err := NewBucketFitter(&BucketFitOption{
+		Features:    []string{"age"},
+		Buckets:     4,
+		Eps:         0.001,
+		ErrorPolicy: sample.ErrorPolicy{OnError: sample.OnErrorFail},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := fitter.RunTask([]string{"1 user:age:3", "1 user:age:x"}); err == nil {
+		t.Error("expected error with on_error=fail")
+	}
+	if _, err := NewBucketFitter(&BucketFitOption{
+		Features:    []string{"age"},
+		Buckets:     4,
+		ErrorPolicy: sample.ErrorPolicy{OnError: "ignore"},
+	}); err == nil {
+		t.Error("expected error for invalid error policy")
+	}
+}
+
+func TestBucketFitterCountsZeros(t *testing.T) {
+	fitter, err := NewBucketFitter(&BucketFitOption{Features: []string{"price"}, Buckets: 4, Eps: 0.001})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 60% 的值为0，训练时这些0同样会落入桶中
+	var lines []string
+	for i := 0; i < 100; i++ {
+		v := 0
+		if i >= 60 {
+			v = i - 59
+		}
+		lines = append(lines, fmt.Sprintf("1 item:price:%d", v))
+	}
+	if err := fitter.RunTask(lines); err != nil {
+		t.Fatal(err)
+	}
+	fitted, results, err := fitter.Fit()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].Count != 100 || results[0].Min != 0 {
+		t.Fatalf("results: %+v", results)
+	}
+	// 前两个分位点都是0，只剩第3个分位点（第75个值，约为15）；不统计0时会得到 [10 20 30]
+	b := results[0].Boundaries
+	if len(b) != 1 || b[0] < 14 || b[0] > 16 {
+		t.Errorf("price boundaries %v, want about [15]", b)
+	}
+	if rule := fitted.Buckets["price"]; rule.Bucket(0) != 0 || rule.Bucket(1) != 0 || rule.Bucket(20) != 1 {
+		t.Errorf("price buckets for 0, 1, 20: %d %d %d", rule.Bucket(0), rule.Bucket(1), rule.Bucket(20))
+	}
+}
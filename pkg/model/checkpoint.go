@@ -0,0 +1,227 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/xiongle/alphaFFM-go/pkg/input"
+)
+
+const (
+	// checkpointMetaFile 检查点元信息文件名，始终指向最新的完整检查点
+	checkpointMetaFile = "checkpoint.json"
+	// checkpointKeep 保留的检查点模型数量
+	checkpointKeep = 2
+	// checkpointPrefix 检查点模型文件名前缀
+	checkpointPrefix = "ckpt_"
+	// checkpointFormat 检查点模型格式，二进制双精度保证续训时FTRL状态不损失精度
+	checkpointFormat = "bin"
+)
+
+// CheckpointMeta 检查点元信息
+type CheckpointMeta struct {
+	ModelFile   string            `json:"model_file"`             // 检查点模型文件名（相对于检查点目录）
+	ModelFormat string            `json:"model_format"`           // 模型格式
+	Lines       int64             `json:"lines"`                  // 检查点对应的输入行偏移
+	Time        string            `json:"time"`                   // 写入时间
+	InputFormat string            `json:"input_format,omitempty"` // 输入格式
+	Inputs      []CheckpointInput `json:"inputs,omitempty"`       // 行偏移所在的输入文件列表
+	Schema      *CheckpointInput  `json:"schema,omitempty"`       // csv/tsv 的列定义文件（决定是否有表头）
+}
+
+// CheckpointInput 检查点记录的一个文件，标准输入只记录路径
+type CheckpointInput struct {
+	Path    string `json:"path"`
+	Size    int64  `json:"size,omitempty"`
+	ModTime string `json:"mod_time,omitempty"`
+}
+
+// String 用于错误信息
+func (in CheckpointInput) String() string {
+	if in.ModTime == "" {
+		return in.Path
+	}
+	return fmt.Sprintf("%s (%d bytes, modified %s)", in.Path, in.Size, in.ModTime)
+}
+
+// statCheckpointInput 记录文件的绝对路径、大小和修改时间
+func statCheckpointInput(path string) (CheckpointInput, error) {
+	if path == input.Stdin {
+		return CheckpointInput{Path: path}, nil
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return CheckpointInput{}, err
+	}
+	info, err := os.Stat(abs)
+	if err != nil {
+		return CheckpointInput{}, err
+	}
+	return CheckpointInput{Path: abs, Size: info.Size(), ModTime: info.ModTime().Format(time.RFC3339Nano)}, nil
+}
+
+// checkpointInputs 记录本次训练的输入格式、输入文件和列定义文件
+func (t *FFMTrainer) checkpointInputs(meta *CheckpointMeta) error {
+	meta.InputFormat = t.opt.InputFormat
+	meta.Inputs = nil
+	for _, path := range t.opt.Inputs {
+		in, err := statCheckpointInput(path)
+		if err != nil {
+			return fmt.Errorf("failed to stat input: %v", err)
+		}
+		meta.Inputs = append(meta.Inputs, in)
+	}
+	meta.Schema = nil
+	if t.opt.SchemaPath != "" {
+		schema, err := statCheckpointInput(t.opt.SchemaPath)
+		if err != nil {
+			return fmt.Errorf("failed to stat schema: %v", err)
+		}
+		meta.Schema = &schema
+	}
+	return nil
+}
+
+// checkInputs 核对本次训练的输入与检查点记录的一致，否则跳过的行偏移指向的是别的数据
+func (t *FFMTrainer) checkInputs(saved *CheckpointMeta) error {
+	current := &CheckpointMeta{}
+	if err := t.checkpointInputs(current); err != nil {
+		return err
+	}
+	if current.InputFormat != saved.InputFormat {
+		return fmt.Errorf("checkpoint was written for input format %q, got %q", saved.InputFormat, current.InputFormat)
+	}
+	if len(current.Inputs) != len(saved.Inputs) {
+		return fmt.Errorf("checkpoint was written for %d input files, got %d", len(saved.Inputs), len(current.Inputs))
+	}
+	for i := range saved.Inputs {
+		if current.Inputs[i] != saved.Inputs[i] {
+			return fmt.Errorf("input %d differs from checkpoint: checkpoint was written for %s, got %s",
+				i+1, saved.Inputs[i], current.Inputs[i])
+		}
+	}
+	switch {
+	case saved.Schema == nil && current.Schema == nil:
+	case saved.Schema == nil || current.Schema == nil || *saved.Schema != *current.Schema:
+		return fmt.Errorf("schema differs from checkpoint: checkpoint was written for %v, got %v", saved.Schema, current.Schema)
+	}
+	return nil
+}
+
+// SaveCheckpoint 保存检查点
+// 调用方需保证调用期间没有训练在进行（PCFrame 的检查点回调满足这一点）
+// 先原子地写入模型文件，再原子地更新 checkpoint.json，任何时刻崩溃
+// checkpoint.json 都指向一个完整的模型
+func (t *FFMTrainer) SaveCheckpoint(dir string, lines int64) error {
+	if t.model.MuBias == nil || len(t.model.FieldNames) == 0 {
+		// 还没有有效样本，无需保存
+		return nil
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create checkpoint dir: %v", err)
+	}
+
+	// 与 -mf、-mnt 无关，检查点总是写双精度二进制模型
+	modelFile := fmt.Sprintf("%s%012d.%s", checkpointPrefix, lines, checkpointFormat)
+	modelPath := filepath.Join(dir, modelFile)
+	t.updateMeta()
+	modelMeta := t.model.outputMeta()
+	modelMeta.NumberType = "double"
+	if err := t.model.writeBinModel(modelPath, modelMeta); err != nil {
+		return err
+	}
+
+	meta := &CheckpointMeta{
+		ModelFile:   modelFile,
+		ModelFormat: checkpointFormat,
+		Lines:       lines,
+		Time:        time.Now().Format(time.RFC3339),
+	}
+	if err := t.checkpointInputs(meta); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(meta, "", "  ")
+	if err != nil {
+		return err
+	}
+	err = atomicWriteFile(filepath.Join(dir, checkpointMetaFile), func(w io.Writer) error {
+		_, err := w.Write(append(data, '\n'))
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("checkpoint saved: %s (%d lines)\n", modelPath, lines)
+	pruneCheckpoints(dir, modelFile)
+	return nil
+}
+
+// pruneCheckpoints 删除较旧的检查点模型，只保留最新的 checkpointKeep 个
+func pruneCheckpoints(dir, current string) {
+	matches, err := filepath.Glob(filepath.Join(dir, checkpointPrefix+"*"))
+	if err != nil {
+		return
+	}
+	files := make([]string, 0, len(matches))
+	for _, m := range matches {
+		if !strings.Contains(filepath.Base(m), ".tmp") {
+			files = append(files, m)
+		}
+	}
+	// 文件名中的行号定长补零，字典序即时间序
+	sort.Strings(files)
+	for i := 0; i < len(files)-checkpointKeep; i++ {
+		if filepath.Base(files[i]) == current {
+			continue
+		}
+		os.Remove(files[i])
+	}
+}
+
+// LoadCheckpointMeta 读取检查点元信息
+// 目录中没有检查点时返回 (nil, nil)
+func LoadCheckpointMeta(dir string) (*CheckpointMeta, error) {
+	data, err := os.ReadFile(filepath.Join(dir, checkpointMetaFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	meta := &CheckpointMeta{}
+	if err := json.Unmarshal(data, meta); err != nil {
+		return nil, fmt.Errorf("invalid checkpoint meta: %v", err)
+	}
+	return meta, nil
+}
+
+// ResumeFromCheckpoint 从最新检查点恢复模型
+// 返回检查点对应的输入行偏移；目录中没有检查点时返回 0。
+// 输入格式、输入文件列表（路径、大小、修改时间）或列定义文件与检查点记录的不一致时报错
+func (t *FFMTrainer) ResumeFromCheckpoint(dir string) (int64, error) {
+	meta, err := LoadCheckpointMeta(dir)
+	if err != nil {
+		return 0, err
+	}
+	if meta == nil {
+		return 0, nil
+	}
+	if err := t.checkInputs(meta); err != nil {
+		return 0, err
+	}
+	modelPath := filepath.Join(dir, meta.ModelFile)
+	if err := t.model.LoadModel(modelPath, meta.ModelFormat); err != nil {
+		return 0, fmt.Errorf("failed to load checkpoint model %s: %v", modelPath, err)
+	}
+	if err := checkTasks(t.model.Tasks, t.opt.Tasks); err != nil {
+		return 0, err
+	}
+	return meta.Lines, nil
+}
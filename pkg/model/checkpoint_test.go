@@ -0,0 +1,175 @@
+package model
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/xiongle/alphaFFM-go/pkg/sample"
+)
+
+func checkpointLines(n int) []string {
+	lines := make([]string, n)
+	for i := range lines {
+		label := "1"
+		if i%3 == 0 {
+			label = "-1"
+		}
+		lines[i] = fmt.Sprintf("%s user:u%d:1 item:i%d:1", label, i%4, i%5)
+	}
+	return lines
+}
+
+func TestCheckpointSaveResume(t *testing.T) {
+	dir := t.TempDir()
+	opt := NewTrainerOption()
+	opt.FactorNum = 2
+	// 检查点与输出模型的格式和数值类型无关
+	opt.ModelNumberType = "float"
+	trainer := NewFFMTrainer(opt)
+	lines := checkpointLines(150)
+
+	// 还没有样本时不写检查点
+	if err := trainer.SaveCheckpoint(dir, 0); err != nil {
+		t.Fatalf("SaveCheckpoint: %v", err)
+	}
+	if meta, err := LoadCheckpointMeta(dir); err != nil || meta != nil {
+		t.Fatalf("LoadCheckpointMeta before training = %v, %v", meta, err)
+	}
+
+	for end := 50; end <= len(lines); end += 50 {
+		if err := trainer.RunTask(lines[end-50 : end]); err != nil {
+			t.Fatalf("RunTask: %v", err)
+		}
+		if err := trainer.SaveCheckpoint(dir, int64(end)); err != nil {
+			t.Fatalf("SaveCheckpoint(%d): %v", end, err)
+		}
+	}
+
+	// 只保留最新的 checkpointKeep 个模型
+	files, err := filepath.Glob(filepath.Join(dir, checkpointPrefix+"*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		filepath.Join(dir, checkpointPrefix+"000000000100.bin"),
+		filepath.Join(dir, checkpointPrefix+"000000000150.bin"),
+	}
+	if len(files) != checkpointKeep || files[0] != want[0] || files[1] != want[1] {
+		t.Errorf("checkpoint files %v, want %v", files, want)
+	}
+
+	// 恢复后行偏移、样本数和模型与保存时一致
+	resumed := NewFFMTrainer(opt)
+	offset, err := resumed.ResumeFromCheckpoint(dir)
+	if err != nil {
+		t.Fatalf("ResumeFromCheckpoint: %v", err)
+	}
+	if offset != 150 {
+		t.Errorf("resumed at line %d, want 150", offset)
+	}
+	if got := resumed.model.SampleCount(); got != 150 {
+		t.Errorf("resumed sample count %d, want 150", got)
+	}
+	// 恢复的FTRL状态与保存时完全一致，继续训练的结果与不中断时相同
+	for _, tr := range []*FFMTrainer{trainer, resumed} {
+		if err := tr.RunTask(lines[:50]); err != nil {
+			t.Fatalf("RunTask: %v", err)
+		}
+	}
+	saved, restored := trainer.PredictModel(), resumed.PredictModel()
+	for u := 0; u < 4; u++ {
+		s := *sample.NewFFMSample(true, []sample.FeatureValue{
+			{Field: "user", Feature: fmt.Sprintf("u%d", u), Value: 1},
+			{Field: "item", Feature: fmt.Sprintf("i%d", u), Value: 1},
+		})
+		want, _ := saved.Score(s)
+		got, err := restored.Score(s)
+		if err != nil || got != want {
+			t.Errorf("u%d: resumed score %v (%v), want %v", u, got, err, want)
+		}
+	}
+
+	// 没有检查点的目录从头开始
+	if offset, err := NewFFMTrainer(opt).ResumeFromCheckpoint(t.TempDir()); err != nil || offset != 0 {
+		t.Errorf("resume from empty dir = %d, %v", offset, err)
+	}
+}
+
+func TestPruneCheckpoints(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{
+		checkpointPrefix + "000000000010.txt",
+		checkpointPrefix + "000000000020.txt",
+		checkpointPrefix + "000000000030.txt",
+		checkpointPrefix + "000000000040.txt.tmp123", // 正在写入的临时文件不删除
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	pruneCheckpoints(dir, checkpointPrefix+"000000000030.txt")
+
+	for name, exists := range map[string]bool{
+		checkpointPrefix + "000000000010.txt":        false,
+		checkpointPrefix + "000000000020.txt":        true,
+		checkpointPrefix + "000000000030.txt":        true,
+		checkpointPrefix + "000000000040.txt.tmp123": true,
+	} {
+		if _, err := os.Stat(filepath.Join(dir, name)); (err == nil) != exists {
+			t.Errorf("%s: exists=%v, want %v", name, err == nil, exists)
+		}
+	}
+}
+
+func TestCheckpointRejectsChangedInputs(t *testing.T) {
+	dir, data := t.TempDir(), t.TempDir()
+	a, b := filepath.Join(data, "a.txt"), filepath.Join(data, "b.txt")
+	for _, path := range []string{a, b} {
+		if err := os.WriteFile(path, []byte(strings.Join(checkpointLines(10), "\n")+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	newTrainer := func(inputs ...string) *FFMTrainer {
+		opt := NewTrainerOption()
+		opt.FactorNum = 2
+		opt.Inputs = inputs
+		return NewFFMTrainer(opt)
+	}
+
+	trainer := newTrainer(a, b)
+	if err := trainer.RunTask(checkpointLines(10)); err != nil {
+		t.Fatalf("RunTask: %v", err)
+	}
+	if err := trainer.SaveCheckpoint(dir, 10); err != nil {
+		t.Fatalf("SaveCheckpoint: %v", err)
+	}
+	if offset, err := newTrainer(a, b).ResumeFromCheckpoint(dir); err != nil || offset != 10 {
+		t.Fatalf("resume with the same inputs = %d, %v", offset, err)
+	}
+
+	for name, tr := range map[string]*FFMTrainer{
+		"fewer files": newTrainer(a),
+		"reordered":   newTrainer(b, a),
+		"stdin":       newTrainer("-"),
+	} {
+		if _, err := tr.ResumeFromCheckpoint(dir); err == nil {
+			t.Errorf("%s: expected input mismatch error", name)
+		}
+	}
+	csv := newTrainer(a, b)
+	csv.opt.InputFormat = sample.InputFormatCSV
+	if _, err := csv.ResumeFromCheckpoint(dir); err == nil {
+		t.Error("expected input format mismatch error")
+	}
+
+	// 文件内容变化（大小不同）同样拒绝
+	if err := os.WriteFile(b, []byte("1 user:u1:1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := newTrainer(a, b).ResumeFromCheckpoint(dir); err == nil || !strings.Contains(err.Error(), "input 2") {
+		t.Errorf("expected error for changed input 2, got %v", err)
+	}
+}
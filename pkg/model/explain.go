@@ -0,0 +1,148 @@
+package model
+
+import (
+	"math"
+	"sort"
+)
+
+// FeatureContribution 单个特征一阶项 wi*xi 的贡献
+type FeatureContribution struct {
+	Field        string  `json:"field"`
+	Feature      string  `json:"feature"`
+	Value        float64 `json:"value"`
+	Contribution float64 `json:"contribution"`
+	Missing      bool    `json:"missing,omitempty"` // 特征不在模型中，贡献为0
+}
+
+// PairContribution 一对特征二阶项 <v_i,f_j, v_j,f_i>*xi*xj 的贡献
+type PairContribution struct {
+	FeatureA     string  `json:"feature_a"`
+	FieldA       string  `json:"field_a"`
+	FeatureB     string  `json:"feature_b"`
+	FieldB       string  `json:"field_b"`
+	Contribution float64 `json:"contribution"`
+}
+
+// FieldPairContribution 按field对聚合的二阶项贡献
+// FieldA <= FieldB（按字典序），(user,item) 与 (item,user) 聚合到同一项
+type FieldPairContribution struct {
+	FieldA       string  `json:"field_a"`
+	FieldB       string  `json:"field_b"`
+	Contribution float64 `json:"contribution"`
+	Pairs        int     `json:"pairs"`
+}
+
+// Explanation 单个样本的logit分解
+// Logit = Bias + Σ Features.Contribution + Σ Pairs.Contribution
+// 设置了topN时各列表只保留绝对值最大的topN项，Logit 和 Score 始终基于完整分解
+type Explanation struct {
+	Score      float64                 `json:"score"`
+	Logit      float64                 `json:"logit"`
+	Bias       float64                 `json:"bias"`
+	Features   []FeatureContribution   `json:"features"`
+	Pairs      []PairContribution      `json:"pairs"`
+	FieldPairs []FieldPairContribution `json:"field_pairs"`
+}
+
+// fieldPairKey 生成field对的规范化key
+func fieldPairKey(a, b string) [2]string {
+	if a > b {
+		a, b = b, a
+	}
+	return [2]string{a, b}
+}
+
+// Explain 分解预测的logit
+// 计算方式与 GetScore 完全一致；topN <= 0 时返回全部项
+func (m *PredictModel) Explain(x []struct{ Field, Feature string; Value float64 }, bias float64, topN int) *Explanation {
+	e := &Explanation{
+		Bias:       bias,
+		Logit:      bias,
+		Features:   make([]FeatureContribution, 0, len(x)),
+		Pairs:      make([]PairContribution, 0),
+		FieldPairs: make([]FieldPairContribution, 0),
+	}
+	units := make([]*PredictModelUnit, len(x))
+
+	// 一阶项
+	for i := 0; i < len(x); i++ {
+		fc := FeatureContribution{Field: x[i].Field, Feature: x[i].Feature, Value: x[i].Value}
+		if unit, ok := m.MuMap[x[i].Feature]; ok {
+			units[i] = unit
+			fc.Contribution = unit.Wi * x[i].Value
+			e.Logit += fc.Contribution
+		} else {
+			fc.Missing = true
+		}
+		e.Features = append(e.Features, fc)
+	}
+
+	// 二阶交互项
+	fieldPairs := make(map[[2]string]*FieldPairContribution)
+	var fieldPairOrder [][2]string
+	for i := 0; i < len(x); i++ {
+		if units[i] == nil {
+			continue
+		}
+		for j := i + 1; j < len(x); j++ {
+			if units[j] == nil {
+				continue
+			}
+			vi := units[i].GetOrInitVi(x[j].Field, m.FactorNum)
+			vj := units[j].GetOrInitVi(x[i].Field, m.FactorNum)
+
+			innerProduct := 0.0
+			for f := 0; f < m.FactorNum; f++ {
+				innerProduct += vi[f] * vj[f]
+			}
+			contribution := innerProduct * x[i].Value * x[j].Value
+			e.Logit += contribution
+
+			e.Pairs = append(e.Pairs, PairContribution{
+				FeatureA:     x[i].Feature,
+				FieldA:       x[i].Field,
+				FeatureB:     x[j].Feature,
+				FieldB:       x[j].Field,
+				Contribution: contribution,
+			})
+
+			key := fieldPairKey(x[i].Field, x[j].Field)
+			fp, ok := fieldPairs[key]
+			if !ok {
+				fp = &FieldPairContribution{FieldA: key[0], FieldB: key[1]}
+				fieldPairs[key] = fp
+				fieldPairOrder = append(fieldPairOrder, key)
+			}
+			fp.Contribution += contribution
+			fp.Pairs++
+		}
+	}
+	for _, key := range fieldPairOrder {
+		e.FieldPairs = append(e.FieldPairs, *fieldPairs[key])
+	}
+
+	e.Score = 1.0 / (1.0 + math.Exp(-e.Logit))
+
+	// 按贡献绝对值从大到小排序
+	sort.SliceStable(e.Features, func(i, j int) bool {
+		return math.Abs(e.Features[i].Contribution) > math.Abs(e.Features[j].Contribution)
+	})
+	sort.SliceStable(e.Pairs, func(i, j int) bool {
+		return math.Abs(e.Pairs[i].Contribution) > math.Abs(e.Pairs[j].Contribution)
+	})
+	sort.SliceStable(e.FieldPairs, func(i, j int) bool {
+		return math.Abs(e.FieldPairs[i].Contribution) > math.Abs(e.FieldPairs[j].Contribution)
+	})
+	if topN > 0 {
+		if len(e.Features) > topN {
+			e.Features = e.Features[:topN]
+		}
+		if len(e.Pairs) > topN {
+			e.Pairs = e.Pairs[:topN]
+		}
+		if len(e.FieldPairs) > topN {
+			e.FieldPairs = e.FieldPairs[:topN]
+		}
+	}
+	return e
+}
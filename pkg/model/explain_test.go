@@ -0,0 +1,118 @@
+package model
+
+import (
+	"math"
+	"path/filepath"
+	"testing"
+)
+
+func TestExplainMatchesScore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "model.txt")
+	if err := newTestModel().OutputModel(path, "txt"); err != nil {
+		t.Fatal(err)
+	}
+	m := NewPredictModel(0)
+	if err := m.LoadModel(path, "txt"); err != nil {
+		t.Fatal(err)
+	}
+
+	x := []struct{ Field, Feature string; Value float64 }{
+		{"user", "u1", 1.0},
+		{"item", "i1", 0.5},
+		{"item", "unknown", 1.0},
+	}
+	e := m.Explain(x, m.MuBias.Wi, 0)
+	if score := m.GetScore(x, m.MuBias.Wi); math.Abs(score-e.Score) > 1e-12 {
+		t.Errorf("score: explain %v, GetScore %v", e.Score, score)
+	}
+
+	// logit = bias + 一阶项 + 二阶项
+	sum := e.Bias
+	for _, f := range e.Features {
+		sum += f.Contribution
+	}
+	for _, p := range e.Pairs {
+		sum += p.Contribution
+	}
+	if math.Abs(sum-e.Logit) > 1e-12 {
+		t.Errorf("decomposition sums to %v, logit %v", sum, e.Logit)
+	}
+
+	// u1针对item的向量(0.1,-0.2)与i1针对user的向量(0.3,0.4)内积为-0.05，乘以 1*0.5
+	if len(e.Pairs) != 1 || math.Abs(e.Pairs[0].Contribution-(-0.025)) > 1e-12 {
+		t.Errorf("unexpected pairs: %+v", e.Pairs)
+	}
+	if len(e.FieldPairs) != 1 || e.FieldPairs[0].FieldA != "item" || e.FieldPairs[0].FieldB != "user" {
+		t.Errorf("unexpected field pairs: %+v", e.FieldPairs)
+	}
+
+	if top := m.Explain(x, m.MuBias.Wi, 1); len(top.Features) != 1 || top.Features[0].Feature != "u1" {
+		t.Errorf("top 1 feature: got %+v", top.Features)
+	}
+}
+
+func TestImportanceAccumulator(t *testing.T) {
+	acc := NewImportanceAccumulator()
+	acc.Add(&Explanation{
+		Features: []FeatureContribution{
+			{Field: "user", Feature: "u1", Contribution: -0.4},
+			{Field: "item", Feature: "i1", Contribution: 0.2},
+		},
+		Pairs: []PairContribution{
+			{FeatureA: "u1", FieldA: "user", FeatureB: "i1", FieldB: "item", Contribution: -0.2},
+		},
+		FieldPairs: []FieldPairContribution{
+			{FieldA: "item", FieldB: "user", Contribution: -0.2, Pairs: 1},
+		},
+	})
+	acc.Add(&Explanation{
+		Features: []FeatureContribution{
+			{Field: "user", Feature: "u2", Contribution: 0.1},
+		},
+	})
+
+	report := acc.Report(1)
+	if report.Samples != 2 {
+		t.Fatalf("samples: got %d", report.Samples)
+	}
+	// u1: (0.4 + 0.1) / 2 个样本
+	if len(report.Features) != 1 || report.Features[0].Name != "u1" || math.Abs(report.Features[0].AvgAbs-0.25) > 1e-12 {
+		t.Errorf("top feature: got %+v", report.Features)
+	}
+	// user: 样本1 0.4+0.1，样本2 0.1
+	if len(report.Fields) != 2 || report.Fields[0].Name != "user" || math.Abs(report.Fields[0].AvgAbs-0.3) > 1e-12 {
+		t.Errorf("fields: got %+v", report.Fields)
+	}
+	if f := report.Fields[0]; f.Occurrences != 2 || math.Abs(f.AvgAbsPresent-0.3) > 1e-12 {
+		t.Errorf("user field: got %+v", f)
+	}
+	if len(report.FieldPairs) != 1 || report.FieldPairs[0].Name != "item|user" || report.FieldPairs[0].Occurrences != 1 {
+		t.Errorf("field pairs: got %+v", report.FieldPairs)
+	}
+}
+
+func TestFFMImportanceReport(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "model.txt")
+	if err := newTestModel().OutputModel(path, "txt"); err != nil {
+		t.Fatal(err)
+	}
+	im, err := NewFFMImportance(&ImportanceOption{ModelPath: path, ModelFormat: "txt"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := im.RunTask([]string{"1 user:u1:1 item:i1:1", "-1 user:u1:1", "bad line"}); err != nil {
+		t.Fatalf("RunTask: %v", err)
+	}
+
+	report := im.Report(0)
+	if report.Samples != 2 {
+		t.Fatalf("samples: got %d, want 2 (bad line skipped)", report.Samples)
+	}
+	// u1: 样本1 |0.25| + |-0.05|/2，样本2 |0.25|
+	if len(report.Features) != 2 || report.Features[0].Name != "u1" || math.Abs(report.Features[0].AvgAbs-0.2625) > 1e-12 {
+		t.Errorf("features: got %+v", report.Features)
+	}
+	if len(report.FieldPairs) != 1 || report.FieldPairs[0].Name != "item|user" || report.FieldPairs[0].Occurrences != 1 {
+		t.Errorf("field pairs: got %+v", report.FieldPairs)
+	}
+}
@@ -1,14 +1,16 @@
 package model
 
 import (
-	"bufio"
 	"fmt"
+	"io"
 	"math"
 	"os"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 
+	"github.com/xiongle/alphaFFM-go/pkg/config"
 	"github.com/xiongle/alphaFFM-go/pkg/simd"
 	"github.com/xiongle/alphaFFM-go/pkg/utils"
 )
@@ -26,6 +28,8 @@ type FFMModelUnit struct {
 	ViMap  map[string][]float64 // field -> 隐向量
 	VNiMap map[string][]float64 // field -> v的n参数
 	VZiMap map[string][]float64 // field -> v的z参数
+
+	Heads []TaskWeight // 多任务模型中其余任务的一阶权重，单任务模型为 nil
 	
 	mu sync.RWMutex // 保护 map 的并发访问
 }
@@ -149,7 +153,7 @@ func (u *FFMModelUnit) String(fieldNames []string, factorNum int) string {
 		}
 	}
 
-	return strings.Join(parts, " ")
+	return strings.Join(parts, " ") + formatTaskWeights(u.Heads)
 }
 
 // FFMModel FFM模型
@@ -159,7 +163,10 @@ type FFMModel struct {
 	FactorNum  int
 	InitMean   float64
 	InitStdev  float64
-	FieldNames []string // 所有field的名称列表（用于模型序列化）
+	FieldNames []string   // 所有field的名称列表（用于模型序列化）
+	Tasks      []string   // 多任务模型的任务名，按样本标签顺序；少于2个时为单任务模型
+	Meta       *ModelMeta // 模型元信息（超参数与来源）
+	samples    int64      // 累计训练样本数
 	mu         sync.RWMutex
 }
 
@@ -193,6 +200,9 @@ func (m *FFMModel) GetOrInitModelUnit(feature string) *FFMModelUnit {
 	}
 
 	unit = NewFFMModelUnit(m.FactorNum, m.InitMean, m.InitStdev)
+	if k := m.NumTasks(); k > 1 {
+		unit.Heads = make([]TaskWeight, k-1)
+	}
 	m.MuMap[feature] = unit
 	return unit
 }
@@ -202,13 +212,34 @@ func (m *FFMModel) GetOrInitModelUnitBias() *FFMModelUnit {
 	if m.MuBias == nil {
 		m.mu.Lock()
 		if m.MuBias == nil {
-			m.MuBias = NewFFMModelUnit(0, m.InitMean, m.InitStdev)
+			bias := NewFFMModelUnit(0, m.InitMean, m.InitStdev)
+			if k := m.NumTasks(); k > 1 {
+				bias.Heads = make([]TaskWeight, k-1)
+			}
+			m.MuBias = bias
 		}
 		m.mu.Unlock()
 	}
 	return m.MuBias
 }
 
+// AddSamples 累加训练样本数
+func (m *FFMModel) AddSamples(n int64) {
+	atomic.AddInt64(&m.samples, n)
+}
+
+// SampleCount 返回累计训练样本数（包含初始模型中记录的样本数）
+func (m *FFMModel) SampleCount() int64 {
+	return atomic.LoadInt64(&m.samples)
+}
+
+// Size 返回模型的特征数和域数
+func (m *FFMModel) Size() (features, fields int) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return len(m.MuMap), len(m.FieldNames)
+}
+
 // RegisterField 注册field（用于模型序列化）
 func (m *FFMModel) RegisterField(field string) {
 	m.mu.Lock()
@@ -290,12 +321,13 @@ func (m *FFMModel) LoadModel(modelPath, modelFormat string) error {
 	if modelFormat == "txt" {
 		return m.loadTxtModel(modelPath)
 	} else if modelFormat == "bin" {
-		return fmt.Errorf("binary format not yet implemented for FFM")
+		return m.loadBinModel(modelPath)
 	}
 	return fmt.Errorf("unsupported model format: %s", modelFormat)
 }
 
 // loadTxtModel 加载文本模型
+// 先完整读取并校验尾行，校验通过后才替换当前模型内容，损坏的文件不会留下半加载的模型
 func (m *FFMModel) loadTxtModel(modelPath string) error {
 	file, err := os.Open(modelPath)
 	if err != nil {
@@ -303,52 +335,65 @@ func (m *FFMModel) loadTxtModel(modelPath string) error {
 	}
 	defer file.Close()
 
-	scanner := bufio.NewScanner(file)
+	reader := newModelLineReader(file)
 
-	// 读取第一行：field列表
-	if !scanner.Scan() {
-		return fmt.Errorf("empty model file")
+	// 读取模型头：元信息和field列表
+	meta, fieldNames, err := readTxtModelHeader(reader)
+	if err != nil {
+		return err
 	}
-	firstLine := strings.Fields(scanner.Text())
-	if len(firstLine) < 2 || firstLine[0] != "FIELDS" {
-		return fmt.Errorf("invalid model format: missing FIELDS header")
+	// 维度在加载成功后才写入模型，失败时保留调用方指定的值
+	factorNum, err := resolveFactorNum(meta, m.FactorNum)
+	if err != nil {
+		return err
 	}
-	m.FieldNames = firstLine[1:]
-	numFields := len(m.FieldNames)
+	numFields := len(fieldNames)
+	// 多任务模型每行末尾依次是其余任务的 w n z
+	headLen := (numTasks(metaTasks(meta)) - 1) * 3
 
 	// 读取bias行
-	if !scanner.Scan() {
+	if !reader.Scan() {
+		if err := reader.Err(); err != nil {
+			return err
+		}
 		return fmt.Errorf("missing bias line")
 	}
-	parts := strings.Fields(scanner.Text())
-	if len(parts) != 4 {
+	parts := strings.Fields(reader.Text())
+	if len(parts) != 4+headLen {
 		return fmt.Errorf("invalid bias line format")
 	}
 
-	m.MuBias = NewFFMModelUnit(0, m.InitMean, m.InitStdev)
-	m.MuBias.Wi, err = strconv.ParseFloat(parts[1], 64)
+	muBias := NewFFMModelUnit(0, m.InitMean, m.InitStdev)
+	if muBias.Heads, err = parseTaskWeights(parts[4:]); err != nil {
+		return err
+	}
+	muBias.Wi, err = strconv.ParseFloat(parts[1], 64)
 	if err != nil {
 		return err
 	}
-	m.MuBias.WNi, err = strconv.ParseFloat(parts[2], 64)
+	muBias.WNi, err = strconv.ParseFloat(parts[2], 64)
 	if err != nil {
 		return err
 	}
-	m.MuBias.WZi, err = strconv.ParseFloat(parts[3], 64)
+	muBias.WZi, err = strconv.ParseFloat(parts[3], 64)
 	if err != nil {
 		return err
 	}
 
 	// 读取特征行
-	expectedLen := 1 + 1 + numFields*m.FactorNum + 2 + numFields*m.FactorNum*2
-	for scanner.Scan() {
-		parts := strings.Fields(scanner.Text())
+	muMap := make(map[string]*FFMModelUnit)
+	expectedLen := 1 + 1 + numFields*factorNum + 2 + numFields*factorNum*2 + headLen
+	for reader.Scan() {
+		parts := strings.Fields(reader.Text())
 		if len(parts) != expectedLen {
 			return fmt.Errorf("invalid feature line format: expected %d fields, got %d", expectedLen, len(parts))
 		}
 
 		feature := parts[0]
-		unit := NewFFMModelUnit(m.FactorNum, m.InitMean, m.InitStdev)
+		unit := NewFFMModelUnit(factorNum, m.InitMean, m.InitStdev)
+		if unit.Heads, err = parseTaskWeights(parts[expectedLen-headLen:]); err != nil {
+			return fmt.Errorf("feature %s: %v", feature, err)
+		}
 
 		unit.Wi, err = strconv.ParseFloat(parts[1], 64)
 		if err != nil {
@@ -357,9 +402,9 @@ func (m *FFMModel) loadTxtModel(modelPath string) error {
 
 		// 解析每个field的vi
 		idx := 2
-		for _, field := range m.FieldNames {
-			vi := make([]float64, m.FactorNum)
-			for f := 0; f < m.FactorNum; f++ {
+		for _, field := range fieldNames {
+			vi := make([]float64, factorNum)
+			for f := 0; f < factorNum; f++ {
 				vi[f], err = strconv.ParseFloat(parts[idx], 64)
 				if err != nil {
 					return err
@@ -382,9 +427,9 @@ func (m *FFMModel) loadTxtModel(modelPath string) error {
 		idx++
 
 		// 解析每个field的v_ni
-		for _, field := range m.FieldNames {
-			vni := make([]float64, m.FactorNum)
-			for f := 0; f < m.FactorNum; f++ {
+		for _, field := range fieldNames {
+			vni := make([]float64, factorNum)
+			for f := 0; f < factorNum; f++ {
 				vni[f], err = strconv.ParseFloat(parts[idx], 64)
 				if err != nil {
 					return err
@@ -395,9 +440,9 @@ func (m *FFMModel) loadTxtModel(modelPath string) error {
 		}
 
 		// 解析每个field的v_zi
-		for _, field := range m.FieldNames {
-			vzi := make([]float64, m.FactorNum)
-			for f := 0; f < m.FactorNum; f++ {
+		for _, field := range fieldNames {
+			vzi := make([]float64, factorNum)
+			for f := 0; f < factorNum; f++ {
 				vzi[f], err = strconv.ParseFloat(parts[idx], 64)
 				if err != nil {
 					return err
@@ -407,10 +452,59 @@ func (m *FFMModel) loadTxtModel(modelPath string) error {
 			unit.VZiMap[field] = vzi
 		}
 
+		muMap[feature] = unit
+	}
+	if err := reader.Err(); err != nil {
+		return err
+	}
+
+	m.FactorNum = factorNum
+	m.FieldNames = fieldNames
+	m.Tasks = metaTasks(meta)
+	m.MuBias = muBias
+	for feature, unit := range muMap {
 		m.MuMap[feature] = unit
 	}
+	m.Meta = meta
+	if meta != nil {
+		atomic.StoreInt64(&m.samples, meta.SampleCount)
+	}
+	return nil
+}
 
-	return scanner.Err()
+// readTxtModelHeader 读取文本模型头
+// 格式版本2的模型以 META 行开头，其后是 FIELDS 行；没有 META 行时返回的元信息为 nil
+func readTxtModelHeader(reader *modelLineReader) (*ModelMeta, []string, error) {
+	if !reader.Scan() {
+		if err := reader.Err(); err != nil {
+			return nil, nil, err
+		}
+		return nil, nil, fmt.Errorf("empty model file")
+	}
+
+	var meta *ModelMeta
+	line := reader.Text()
+	if isMetaLine(line) {
+		var err error
+		if meta, err = parseMetaLine(line); err != nil {
+			return nil, nil, err
+		}
+		if !reader.Scan() {
+			if err := reader.Err(); err != nil {
+				return nil, nil, err
+			}
+			return nil, nil, fmt.Errorf("missing FIELDS header")
+		}
+		line = reader.Text()
+	}
+
+	firstLine := strings.Fields(line)
+	if len(firstLine) < 2 || firstLine[0] != "FIELDS" {
+		return nil, nil, fmt.Errorf("invalid model format: missing FIELDS header")
+	}
+	// 格式版本2起才有尾行
+	reader.legacy = meta == nil || meta.FormatVersion < 2
+	return meta, firstLine[1:], nil
 }
 
 // OutputModel 输出模型
@@ -418,39 +512,51 @@ func (m *FFMModel) OutputModel(modelPath, modelFormat string) error {
 	if modelFormat == "txt" {
 		return m.outputTxtModel(modelPath)
 	} else if modelFormat == "bin" {
-		return fmt.Errorf("binary format not yet implemented for FFM")
+		return m.outputBinModel(modelPath)
 	}
 	return fmt.Errorf("unsupported model format: %s", modelFormat)
 }
 
 // outputTxtModel 输出文本模型
+// 通过临时文件原子写入，末尾附带记录行数和校验和的尾行
 func (m *FFMModel) outputTxtModel(modelPath string) error {
 	// 检查是否有有效数据
 	if m.MuBias == nil || len(m.FieldNames) == 0 {
 		return fmt.Errorf("no valid samples processed, cannot output model")
 	}
 
-	file, err := os.Create(modelPath)
-	if err != nil {
-		return err
-	}
-	defer file.Close()
+	return atomicWriteFile(modelPath, func(w io.Writer) error {
+		writer := newTrailerWriter(w)
 
-	writer := bufio.NewWriter(file)
-	defer writer.Flush()
+		// 输出元信息
+		metaLine, err := m.outputMeta().metaLine()
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintln(writer, metaLine); err != nil {
+			return err
+		}
 
-	// 输出field列表
-	fmt.Fprintf(writer, "FIELDS %s\n", strings.Join(m.FieldNames, " "))
+		// 输出field列表
+		if _, err := fmt.Fprintf(writer, "FIELDS %s\n", strings.Join(m.FieldNames, " ")); err != nil {
+			return err
+		}
 
-	// 输出bias
-	fmt.Fprintf(writer, "%s %.6g %.6g %.6g\n", BiasFeatureName, m.MuBias.Wi, m.MuBias.WNi, m.MuBias.WZi)
+		// 输出bias
+		if _, err := fmt.Fprintf(writer, "%s %.6g %.6g %.6g%s\n", BiasFeatureName, m.MuBias.Wi, m.MuBias.WNi, m.MuBias.WZi,
+			formatTaskWeights(m.MuBias.Heads)); err != nil {
+			return err
+		}
 
-	// 输出特征
-	for feature, unit := range m.MuMap {
-		fmt.Fprintf(writer, "%s %s\n", feature, unit.String(m.FieldNames, m.FactorNum))
-	}
+		// 输出特征
+		for feature, unit := range m.MuMap {
+			if _, err := fmt.Fprintf(writer, "%s %s\n", feature, unit.String(m.FieldNames, m.FactorNum)); err != nil {
+				return err
+			}
+		}
 
-	return nil
+		return writer.WriteTrailer()
+	})
 }
 
 // PredictModel FFM预测模型（简化版，只包含wi和vi）
@@ -459,15 +565,22 @@ type PredictModel struct {
 	MuMap      map[string]*PredictModelUnit
 	FactorNum  int
 	FieldNames []string
+	Tasks      []string   // 多任务模型的任务名，单任务模型为 nil
+	Meta       *ModelMeta // 模型元信息，旧格式模型为 nil
+
+	// FieldConfig Score/ScoreBatch/ScoreTasks 打分前用于分桶和归一化的域配置，nil 表示样本已经处理过
+	FieldConfig *config.FieldConfig
 }
 
 // PredictModelUnit FFM预测模型单元
 type PredictModelUnit struct {
 	Wi    float64
 	ViMap map[string][]float64 // field -> 隐向量
+	HeadW []float64            // 多任务模型中其余任务的一阶权重
 }
 
 // NewPredictModel 创建预测模型
+// factorNum 为 0 时加载模型时从模型头读取隐向量维度
 func NewPredictModel(factorNum int) *PredictModel {
 	return &PredictModel{
 		MuMap:      make(map[string]*PredictModelUnit),
@@ -487,6 +600,11 @@ func (u *PredictModelUnit) GetOrInitVi(field string, factorNum int) []float64 {
 
 // GetScore 计算预测得分（包含sigmoid）
 func (m *PredictModel) GetScore(x []struct{ Field, Feature string; Value float64 }, bias float64) float64 {
+	return 1.0 / (1.0 + math.Exp(-m.logit(x, bias)))
+}
+
+// logit 计算sigmoid之前的预测值
+func (m *PredictModel) logit(x []struct{ Field, Feature string; Value float64 }, bias float64) float64 {
 	result := bias
 
 	// 一阶项
@@ -521,12 +639,16 @@ func (m *PredictModel) GetScore(x []struct{ Field, Feature string; Value float64
 		}
 	}
 
-	// Sigmoid
-	return 1.0 / (1.0 + math.Exp(-result))
+	return result
 }
 
 // GetScoreSIMD 计算预测得分（包含sigmoid，使用SIMD优化）
 func (m *PredictModel) GetScoreSIMD(x []struct{ Field, Feature string; Value float64 }, bias float64, ops simd.VectorOps) float64 {
+	return 1.0 / (1.0 + math.Exp(-m.logitSIMD(x, bias, ops)))
+}
+
+// logitSIMD 使用SIMD计算sigmoid之前的预测值
+func (m *PredictModel) logitSIMD(x []struct{ Field, Feature string; Value float64 }, bias float64, ops simd.VectorOps) float64 {
 	result := bias
 
 	// 一阶项
@@ -557,8 +679,7 @@ func (m *PredictModel) GetScoreSIMD(x []struct{ Field, Feature string; Value flo
 		}
 	}
 
-	// Sigmoid
-	return 1.0 / (1.0 + math.Exp(-result))
+	return result
 }
 
 // LoadModel 加载模型
@@ -566,12 +687,13 @@ func (m *PredictModel) LoadModel(modelPath, modelFormat string) error {
 	if modelFormat == "txt" {
 		return m.loadTxtModel(modelPath)
 	} else if modelFormat == "bin" {
-		return fmt.Errorf("binary format not yet implemented for FFM")
+		return m.loadBinModel(modelPath)
 	}
 	return fmt.Errorf("unsupported model format: %s", modelFormat)
 }
 
 // loadTxtModel 加载文本模型
+// 格式错误的行、尾行缺失或不匹配都会导致加载失败，不会加载半个模型
 func (m *PredictModel) loadTxtModel(modelPath string) error {
 	file, err := os.Open(modelPath)
 	if err != nil {
@@ -579,40 +701,51 @@ func (m *PredictModel) loadTxtModel(modelPath string) error {
 	}
 	defer file.Close()
 
-	scanner := bufio.NewScanner(file)
+	reader := newModelLineReader(file)
 
-	// 读取field列表
-	if !scanner.Scan() {
-		return fmt.Errorf("empty model file")
+	// 读取模型头，确定隐向量维度
+	meta, fieldNames, err := readTxtModelHeader(reader)
+	if err != nil {
+		return err
 	}
-	firstLine := strings.Fields(scanner.Text())
-	if len(firstLine) < 2 || firstLine[0] != "FIELDS" {
-		return fmt.Errorf("invalid model format: missing FIELDS header")
+	// 维度在加载成功后才写入模型，失败时保留调用方指定的值
+	factorNum, err := resolveFactorNum(meta, m.FactorNum)
+	if err != nil {
+		return err
 	}
-	m.FieldNames = firstLine[1:]
-	numFields := len(m.FieldNames)
+	numFields := len(fieldNames)
+	headLen := (numTasks(metaTasks(meta)) - 1) * 3
 
 	// 读取bias
-	if !scanner.Scan() {
+	if !reader.Scan() {
+		if err := reader.Err(); err != nil {
+			return err
+		}
 		return fmt.Errorf("missing bias line")
 	}
-	parts := strings.Fields(scanner.Text())
-	if len(parts) != 4 {
+	parts := strings.Fields(reader.Text())
+	if len(parts) != 4+headLen {
 		return fmt.Errorf("invalid bias line")
 	}
 
-	m.MuBias = &PredictModelUnit{ViMap: make(map[string][]float64)}
-	m.MuBias.Wi, err = strconv.ParseFloat(parts[1], 64)
+	muBias := &PredictModelUnit{ViMap: make(map[string][]float64)}
+	muBias.Wi, err = strconv.ParseFloat(parts[1], 64)
+	if err != nil {
+		return err
+	}
+	heads, err := parseTaskWeights(parts[4:])
 	if err != nil {
 		return err
 	}
+	muBias.HeadW = headWeights(heads)
 
 	// 读取特征
-	expectedLen := 1 + 1 + numFields*m.FactorNum + 2 + numFields*m.FactorNum*2
-	for scanner.Scan() {
-		parts := strings.Fields(scanner.Text())
+	muMap := make(map[string]*PredictModelUnit)
+	expectedLen := 1 + 1 + numFields*factorNum + 2 + numFields*factorNum*2 + headLen
+	for reader.Scan() {
+		parts := strings.Fields(reader.Text())
 		if len(parts) != expectedLen {
-			continue // 跳过格式错误的行
+			return fmt.Errorf("invalid feature line format: expected %d fields, got %d", expectedLen, len(parts))
 		}
 
 		feature := parts[0]
@@ -620,18 +753,18 @@ func (m *PredictModel) loadTxtModel(modelPath string) error {
 
 		unit.Wi, err = strconv.ParseFloat(parts[1], 64)
 		if err != nil {
-			continue
+			return fmt.Errorf("invalid weight of feature %s: %v", feature, err)
 		}
 
 		// 解析每个field的vi
 		idx := 2
 		isNonZero := unit.Wi != 0.0
-		for _, field := range m.FieldNames {
-			vi := make([]float64, m.FactorNum)
-			for f := 0; f < m.FactorNum; f++ {
+		for _, field := range fieldNames {
+			vi := make([]float64, factorNum)
+			for f := 0; f < factorNum; f++ {
 				vi[f], err = strconv.ParseFloat(parts[idx], 64)
 				if err != nil {
-					vi[f] = 0.0
+					return fmt.Errorf("invalid vector of feature %s: %v", feature, err)
 				}
 				if vi[f] != 0.0 {
 					isNonZero = true
@@ -641,12 +774,33 @@ func (m *PredictModel) loadTxtModel(modelPath string) error {
 			unit.ViMap[field] = vi
 		}
 
+		heads, err := parseTaskWeights(parts[expectedLen-headLen:])
+		if err != nil {
+			return fmt.Errorf("feature %s: %v", feature, err)
+		}
+		unit.HeadW = headWeights(heads)
+		for _, w := range unit.HeadW {
+			if w != 0.0 {
+				isNonZero = true
+			}
+		}
+
 		// 只加载非零特征
 		if isNonZero {
-			m.MuMap[feature] = unit
+			muMap[feature] = unit
 		}
 	}
+	if err := reader.Err(); err != nil {
+		return err
+	}
 
-	return scanner.Err()
+	m.FactorNum = factorNum
+	m.FieldNames = fieldNames
+	m.Tasks = metaTasks(meta)
+	m.MuBias = muBias
+	for feature, unit := range muMap {
+		m.MuMap[feature] = unit
+	}
+	m.Meta = meta
+	return nil
 }
-
@@ -2,11 +2,15 @@ package model
 
 import (
 	"bufio"
+	"encoding/json"
 	"fmt"
 	"os"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/xiongle/alphaFFM-go/pkg/config"
+	"github.com/xiongle/alphaFFM-go/pkg/frame"
 	"github.com/xiongle/alphaFFM-go/pkg/sample"
 	"github.com/xiongle/alphaFFM-go/pkg/simd"
 )
@@ -18,15 +22,19 @@ type PredictorOption struct {
 	PredictPath     string
 	ModelNumberType string
 	ThreadsNum      int
-	FactorNum       int
+	FactorNum       int                // 隐向量维度，0 表示从模型头读取
 	SIMDType        simd.VectorOpsType // SIMD优化类型
 	FieldConfigPath string             // 域配置文件路径
+	InputFormat     string             // 输入格式: ffm、libffm、csv、tsv 或 jsonl
+	SchemaPath      string             // csv/tsv 的列定义文件
+	ExplainTopN     int                // 大于0时每个样本输出logit分解的JSON，保留贡献最大的topN项
+	ErrorPolicy     sample.ErrorPolicy // 坏样本的处理策略
 }
 
 // NewPredictorOption 创建默认预测选项
 func NewPredictorOption() *PredictorOption {
 	return &PredictorOption{
-		FactorNum:       8,
+		FactorNum:       0,
 		ThreadsNum:      1,
 		ModelFormat:     "txt",
 		ModelNumberType: "double",
@@ -43,6 +51,7 @@ type FFMPredictor struct {
 	simdOps     simd.VectorOps      // SIMD运算实例
 	useSIMD     bool                // 是否使用SIMD
 	fieldConfig *config.FieldConfig // 域配置
+	parserTask
 }
 
 // NewFFMPredictor 创建预测器
@@ -53,36 +62,10 @@ func NewFFMPredictor(opt *PredictorOption) (*FFMPredictor, error) {
 	}
 
 	// 加载域配置文件
-	if opt.FieldConfigPath != "" {
-		fieldConfig := config.NewFieldConfig()
-		
-		// 尝试JSON格式
-		if err := fieldConfig.LoadFromJSON(opt.FieldConfigPath); err != nil {
-			// 尝试文本格式
-			if err2 := fieldConfig.LoadFromText(opt.FieldConfigPath); err2 != nil {
-				fmt.Printf("Warning: failed to load field config from %s (JSON: %v, Text: %v), using auto mode\n", 
-					opt.FieldConfigPath, err, err2)
-			} else {
-				// 文本格式加载成功
-				fieldConfig.Mode = "config"
-				if err := fieldConfig.Validate(); err != nil {
-					fmt.Printf("Warning: invalid field config: %v, using auto mode\n", err)
-				} else {
-					p.fieldConfig = fieldConfig
-					fmt.Printf("Loaded field config from %s (text format, %d mappings)\n", 
-						opt.FieldConfigPath, len(fieldConfig.FeatureToField))
-				}
-			}
-		} else {
-			// JSON格式加载成功
-			if err := fieldConfig.Validate(); err != nil {
-				fmt.Printf("Warning: invalid field config: %v, using auto mode\n", err)
-			} else {
-				p.fieldConfig = fieldConfig
-				fmt.Printf("Loaded field config from %s (JSON format, mode: %s, %d mappings)\n", 
-					opt.FieldConfigPath, fieldConfig.Mode, len(fieldConfig.FeatureToField))
-			}
-		}
+	p.fieldConfig = loadFieldConfig(opt.FieldConfigPath)
+	p.parserTask = newSampleParser(taskPredict, opt.InputFormat, p.fieldConfig, opt.SchemaPath)
+	if err := p.setErrorPolicy(opt.ErrorPolicy); err != nil {
+		return nil, err
 	}
 
 	// 初始化SIMD
@@ -108,6 +91,24 @@ func NewFFMPredictor(opt *PredictorOption) (*FFMPredictor, error) {
 		return nil, fmt.Errorf("load model error: %v", err)
 	}
 	fmt.Println("model loading finished")
+	// logit分解只覆盖第一个任务，多任务模型不能静默地只解释其中一个任务
+	if opt.ExplainTopN > 0 && p.model.NumTasks() > 1 {
+		return nil, errMultiTask("-explain")
+	}
+	if meta := p.model.Meta; meta != nil {
+		fmt.Printf("model meta: factor_num=%d, samples=%d, trained_at=%s\n", meta.FactorNum, meta.SampleCount, meta.TrainedAt)
+		if len(meta.Tasks) > 1 {
+			fmt.Printf("model tasks: %s\n", strings.Join(meta.Tasks, ","))
+		}
+		if p.fieldConfig != nil && meta.FieldConfigHash != "" && meta.FieldConfigHash != p.fieldConfig.Hash() {
+			fmt.Printf("Warning: field config differs from the one used in training (model: %s, current: %s)\n",
+				meta.FieldConfigHash, p.fieldConfig.Hash())
+		}
+		if meta.InputFormat != "" && opt.InputFormat != "" && meta.InputFormat != opt.InputFormat {
+			fmt.Printf("Warning: input format differs from the one used in training (model: %s, current: %s)\n",
+				meta.InputFormat, opt.InputFormat)
+		}
+	}
 
 	// 打开输出文件
 	f, err := os.Create(opt.PredictPath)
@@ -121,30 +122,50 @@ func NewFFMPredictor(opt *PredictorOption) (*FFMPredictor, error) {
 
 // RunTask 处理一批数据
 func (p *FFMPredictor) RunTask(dataBuffer []string) error {
-	results := make([]string, len(dataBuffer))
-
-	for i, line := range dataBuffer {
-		var s *sample.FFMSample
-		var err error
-		
-		// 使用配置文件解析样本
-		if p.fieldConfig != nil {
-			s, err = sample.ParseSampleWithConfig(line, p.fieldConfig)
-		} else {
-			s, err = sample.ParseSample(line)
-		}
-		
-		if err != nil {
-			fmt.Printf("Warning: skip invalid sample: %v\n", err)
+	return p.RunBatch(&frame.Batch{Lines: dataBuffer})
+}
+
+// RunBatch 处理一批带输入位置的数据，坏行按错误策略处理，不输出结果
+func (p *FFMPredictor) RunBatch(batch *frame.Batch) error {
+	samples, err := p.parseBatch(batch)
+	if err != nil {
+		return err
+	}
+	results := make([]string, len(samples))
+
+	for i, s := range samples {
+		if s == nil {
 			continue
 		}
 
 		// 转换特征格式
-		xForPredict := make([]struct{ Field, Feature string; Value float64 }, len(s.X))
-		for j := 0; j < len(s.X); j++ {
-			xForPredict[j].Field = s.X[j].Field
-			xForPredict[j].Feature = s.X[j].Feature
-			xForPredict[j].Value = s.X[j].Value
+		start := time.Now()
+		xForPredict := toPredictInput(s)
+
+		if p.opt.ExplainTopN > 0 {
+			results[i], err = p.explain(s.Y, xForPredict)
+			if err != nil {
+				fmt.Printf("Warning: failed to explain sample: %v\n", err)
+			}
+			scoreLatency.Observe(time.Since(start).Seconds())
+			continue
+		}
+
+		// 多任务模型按任务顺序输出每个任务的概率
+		if p.model.NumTasks() > 1 {
+			var scores []float64
+			if p.useSIMD {
+				scores = p.model.GetTaskScoresSIMD(xForPredict, p.simdOps)
+			} else {
+				scores = p.model.GetTaskScores(xForPredict)
+			}
+			scoreLatency.Observe(time.Since(start).Seconds())
+			parts := make([]string, len(scores))
+			for k, score := range scores {
+				parts[k] = fmt.Sprintf("%.6g", score)
+			}
+			results[i] = sample.FormatLabels(s) + " " + strings.Join(parts, " ")
+			continue
 		}
 
 		var score float64
@@ -153,6 +174,7 @@ func (p *FFMPredictor) RunTask(dataBuffer []string) error {
 		} else {
 			score = p.model.GetScore(xForPredict, p.model.MuBias.Wi)
 		}
+		scoreLatency.Observe(time.Since(start).Seconds())
 		results[i] = fmt.Sprintf("%d %.6g", s.Y, score)
 	}
 
@@ -171,6 +193,33 @@ func (p *FFMPredictor) RunTask(dataBuffer []string) error {
 	return nil
 }
 
+// toPredictInput 将样本转换为预测模型的输入格式
+func toPredictInput(s *sample.FFMSample) []struct{ Field, Feature string; Value float64 } {
+	x := make([]struct{ Field, Feature string; Value float64 }, len(s.X))
+	for j := 0; j < len(s.X); j++ {
+		x[j].Field = s.X[j].Field
+		x[j].Feature = s.X[j].Feature
+		x[j].Value = s.X[j].Value
+	}
+	return x
+}
+
+// explain 生成单个样本的解释（JSON）
+func (p *FFMPredictor) explain(y int, x []struct{ Field, Feature string; Value float64 }) (string, error) {
+	out := struct {
+		Label int `json:"label"`
+		*Explanation
+	}{
+		Label:       y,
+		Explanation: p.model.Explain(x, p.model.MuBias.Wi, p.opt.ExplainTopN),
+	}
+	data, err := json.Marshal(out)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
 // Close 关闭预测器
 func (p *FFMPredictor) Close() error {
 	if p.outFile != nil {
@@ -4,8 +4,11 @@ import (
 	"fmt"
 	"math"
 	"sync"
+	"time"
 
 	"github.com/xiongle/alphaFFM-go/pkg/config"
+	"github.com/xiongle/alphaFFM-go/pkg/eval"
+	"github.com/xiongle/alphaFFM-go/pkg/frame"
 	"github.com/xiongle/alphaFFM-go/pkg/lock"
 	"github.com/xiongle/alphaFFM-go/pkg/sample"
 	"github.com/xiongle/alphaFFM-go/pkg/simd"
@@ -37,6 +40,16 @@ type TrainerOption struct {
 	ForceVSparse        bool
 	SIMDType            simd.VectorOpsType // SIMD优化类型
 	FieldConfigPath     string              // 域配置文件路径
+	InputFormat         string              // 输入格式: ffm、libffm、csv、tsv 或 jsonl
+	SchemaPath          string              // csv/tsv 的列定义文件
+	Inputs              []string            // 展开后的输入文件列表，检查点据此核对续训的输入
+	CheckpointDir       string              // 检查点目录
+	CheckpointLines     int64               // 每训练多少行写一次检查点
+	CheckpointInterval  time.Duration       // 每隔多长时间写一次检查点
+	Resume              bool                // 是否从检查点目录中的最新检查点恢复
+	ErrorPolicy         sample.ErrorPolicy  // 坏样本的处理策略
+	Progress            ProgressOption      // 训练进度报告
+	Tasks               []string            // 多任务训练的任务名，按样本标签顺序；少于2个时为单任务训练
 }
 
 // NewTrainerOption 创建默认训练选项
@@ -73,49 +86,36 @@ type FFMTrainer struct {
 	simdOps      simd.VectorOps    // SIMD运算实例
 	useSIMD      bool              // 是否使用SIMD
 	fieldConfig  *config.FieldConfig // 域配置
+	progress     *progressReporter   // 训练进度报告，未启用时为 nil
+	validation   *eval.Accumulator   // 渐进验证: 每个样本训练前预测的累计指标
+	parserTask
 }
 
 // NewFFMTrainer 创建训练器
 func NewFFMTrainer(opt *TrainerOption) *FFMTrainer {
 	t := &FFMTrainer{
-		model:    NewFFMModel(opt.FactorNum, opt.InitMean, opt.InitStdev),
-		lockPool: lock.NewLockPool(),
-		opt:      opt,
+		model:      NewFFMModel(opt.FactorNum, opt.InitMean, opt.InitStdev),
+		lockPool:   lock.NewLockPool(),
+		opt:        opt,
+		validation: eval.NewAccumulator(eval.DefaultAUCBins),
 	}
 	
 	// 加载域配置文件
-	if opt.FieldConfigPath != "" {
-		fieldConfig := config.NewFieldConfig()
-		
-		// 尝试JSON格式
-		if err := fieldConfig.LoadFromJSON(opt.FieldConfigPath); err != nil {
-			// 尝试文本格式
-			if err2 := fieldConfig.LoadFromText(opt.FieldConfigPath); err2 != nil {
-				fmt.Printf("Warning: failed to load field config from %s (JSON: %v, Text: %v), using auto mode\n", 
-					opt.FieldConfigPath, err, err2)
-			} else {
-				// 文本格式加载成功，设置为config模式
-				fieldConfig.Mode = "config"
-				if err := fieldConfig.Validate(); err != nil {
-					fmt.Printf("Warning: invalid field config: %v, using auto mode\n", err)
-				} else {
-					t.fieldConfig = fieldConfig
-					fmt.Printf("Loaded field config from %s (text format, %d mappings)\n", 
-						opt.FieldConfigPath, len(fieldConfig.FeatureToField))
-				}
-			}
+	t.fieldConfig = loadFieldConfig(opt.FieldConfigPath)
+	t.parserTask = newSampleParser(taskTrain, opt.InputFormat, t.fieldConfig, opt.SchemaPath)
+	t.model.Tasks = opt.Tasks
+	t.parserTask.tasks = numTasks(opt.Tasks)
+	if err := t.setErrorPolicy(opt.ErrorPolicy); err != nil {
+		fmt.Printf("Warning: %v, skipping invalid samples\n", err)
+	}
+	if opt.Progress.Enabled() {
+		if err := opt.Progress.Validate(); err != nil {
+			fmt.Printf("Warning: %v, progress reporting disabled\n", err)
 		} else {
-			// JSON格式加载成功
-			if err := fieldConfig.Validate(); err != nil {
-				fmt.Printf("Warning: invalid field config: %v, using auto mode\n", err)
-			} else {
-				t.fieldConfig = fieldConfig
-				fmt.Printf("Loaded field config from %s (JSON format, mode: %s, %d mappings)\n", 
-					opt.FieldConfigPath, fieldConfig.Mode, len(fieldConfig.FeatureToField))
-			}
+			t.progress = newProgressReporter(opt.Progress, t.model, t.metricTask(), t.validation)
 		}
 	}
-	
+
 	// 初始化SIMD
 	if opt.SIMDType != simd.VectorOpsScalar {
 		ops, err := simd.NewVectorOps(opt.SIMDType)
@@ -138,38 +138,120 @@ func NewFFMTrainer(opt *TrainerOption) *FFMTrainer {
 
 // RunTask 处理一批数据
 func (t *FFMTrainer) RunTask(dataBuffer []string) error {
-	for _, line := range dataBuffer {
-		var s *sample.FFMSample
-		var err error
-		
-		// 使用配置文件解析样本
-		if t.fieldConfig != nil {
-			s, err = sample.ParseSampleWithConfig(line, t.fieldConfig)
-		} else {
-			s, err = sample.ParseSample(line)
-		}
-		
-		if err != nil {
-			fmt.Printf("Warning: skip invalid sample: %v\n", err)
+	return t.RunBatch(&frame.Batch{Lines: dataBuffer})
+}
+
+// RunBatch 处理一批带输入位置的数据，坏行按错误策略处理
+func (t *FFMTrainer) RunBatch(batch *frame.Batch) error {
+	samples, err := t.parseBatch(batch)
+	if err != nil {
+		return err
+	}
+	t.trainSamples(samples)
+	return nil
+}
+
+// trainSamples 训练一批样本，跳过 nil
+func (t *FFMTrainer) trainSamples(samples []*sample.FFMSample) {
+	// 训练前的预测没有见过该样本，用于渐进验证（先测试后训练）
+	points := make([]eval.Point, 0, len(samples))
+	var trained int64
+	for _, s := range samples {
+		if s == nil {
 			continue
 		}
-		t.train(s.Y, s.X)
+		labels := taskLabels(s)
+		p := t.train(labels, s.X, s.Weight)
+		t.model.AddSamples(1)
+		trained++
+		// 多任务时按第一个任务评估，没有该任务标签的样本不参与
+		if labels[0] != sample.LabelMissing {
+			points = append(points, eval.Point{Prob: eval.Sigmoid(p), Positive: labels[0] > 0, Weight: s.Weight})
+		}
 	}
-	return nil
+	t.validation.Add(points)
+	if t.progress != nil {
+		t.progress.observe(trained, points)
+	}
+}
+
+// ValidationSummary 渐进验证结果: 本次运行中每个样本在训练前被预测的 logloss 和 AUC
+// 没有训练任何样本时返回空
+func (t *FFMTrainer) ValidationSummary() string {
+	n, logloss, auc := t.validation.Metrics()
+	if n == 0 {
+		return ""
+	}
+	name := "progressive validation"
+	if task := t.metricTask(); task != "" {
+		name += " [task " + task + "]"
+	}
+	return fmt.Sprintf("%s: %d samples, logloss %s, auc %s",
+		name, n, formatMetric(finite(logloss)), formatMetric(finite(auc)))
+}
+
+// metricTask 多任务训练时计算渐进验证和进度指标的任务（第一个任务），单任务时为空
+func (t *FFMTrainer) metricTask() string {
+	if numTasks(t.opt.Tasks) == 1 {
+		return ""
+	}
+	return t.opt.Tasks[0]
 }
 
-// LoadModel 加载模型
+// FinishProgress 训练结束时输出最后一次进度报告，未启用进度报告时不输出
+func (t *FFMTrainer) FinishProgress() {
+	if t.progress != nil {
+		t.progress.finish()
+	}
+}
+
+// LoadModel 加载模型，模型的任务须与训练选项一致
 func (t *FFMTrainer) LoadModel(modelPath, modelFormat string) error {
-	return t.model.LoadModel(modelPath, modelFormat)
+	if err := t.model.LoadModel(modelPath, modelFormat); err != nil {
+		return err
+	}
+	return checkTasks(t.model.Tasks, t.opt.Tasks)
 }
 
 // OutputModel 输出模型
 func (t *FFMTrainer) OutputModel(modelPath, modelFormat string) error {
+	t.updateMeta()
 	return t.model.OutputModel(modelPath, modelFormat)
 }
 
-// train 训练一个样本（FFM版本）
-func (t *FFMTrainer) train(y int, x []sample.FeatureValue) {
+// FieldMappingSummary 域映射规则的命中统计，没有任何特征经过映射时返回空
+func (t *FFMTrainer) FieldMappingSummary() string {
+	if t.fieldConfig == nil {
+		return ""
+	}
+	stats := t.fieldConfig.RuleStats()
+	for _, s := range stats {
+		if s.Hits > 0 {
+			return config.FormatRuleStats(stats)
+		}
+	}
+	return ""
+}
+
+// updateMeta 用本次训练的超参数和配置更新模型元信息
+func (t *FFMTrainer) updateMeta() {
+	t.model.Meta = t.newMeta()
+}
+
+// newMeta 根据本次训练的超参数和配置生成元信息
+func (t *FFMTrainer) newMeta() *ModelMeta {
+	meta := NewModelMetaFromOption(t.opt)
+	if t.fieldConfig != nil {
+		meta.FieldConfigHash = t.fieldConfig.Hash()
+	}
+	meta.TrainedAt = time.Now().Format(time.RFC3339)
+	return meta
+}
+
+// train 训练一个样本（FFM版本），labels 为各任务的标签，weight 为样本权重，按比例缩放梯度
+// 多任务时各任务有自己的 bias 和一阶权重，隐向量由所有任务的梯度共同更新，缺失标签的任务不更新
+// 返回更新前模型对该样本第一个任务的预测值（logit）
+func (t *FFMTrainer) train(labels []int, x []sample.FeatureValue, weight float64) float64 {
 	thetaBias := t.model.GetOrInitModelUnitBias()
 	xLen := len(x)
 	theta := make([]*FFMModelUnit, xLen)
@@ -205,11 +287,9 @@ func (t *FFMTrainer) train(y int, x []sample.FeatureValue) {
 
 		if (i < xLen && t.opt.K1) || (i == xLen && t.opt.K0) {
 			feaLocks[i].Lock()
-			if math.Abs(mu.WZi) <= t.opt.WL1 {
-				mu.Wi = 0.0
-			} else {
-				mu.Wi = -1.0 * (1.0 / (t.opt.WL2 + (t.opt.WBeta+math.Sqrt(mu.WNi))/t.opt.WAlpha)) *
-					(mu.WZi - float64(utils.Sgn(mu.WZi))*t.opt.WL1)
+			mu.Wi = t.ftrlWeight(mu.WZi, mu.WNi)
+			for k := range mu.Heads {
+				mu.Heads[k].W = t.ftrlWeight(mu.Heads[k].Z, mu.Heads[k].N)
 			}
 			feaLocks[i].Unlock()
 		}
@@ -258,8 +338,20 @@ func (t *FFMTrainer) train(y int, x []sample.FeatureValue) {
 		p = t.predictScalar(x, bias, theta)
 	}
 
-	// 计算梯度系数
-	mult := float64(y) * (1.0/(1.0+math.Exp(-p*float64(y))) - 1.0)
+	// 计算梯度系数，隐向量的梯度是各任务之和
+	mults := make([]float64, len(labels))
+	vMult := 0.0
+	for k, y := range labels {
+		if y == sample.LabelMissing {
+			continue
+		}
+		pk := p
+		if k > 0 {
+			pk = t.taskLogit(p, k, x, theta, thetaBias)
+		}
+		mults[k] = weight * float64(y) * (1.0/(1.0+math.Exp(-pk*float64(y))) - 1.0)
+		vMult += mults[k]
+	}
 
 	// 更新w_n, w_z
 	for i := 0; i <= xLen; i++ {
@@ -275,20 +367,51 @@ func (t *FFMTrainer) train(y int, x []sample.FeatureValue) {
 
 		if (i < xLen && t.opt.K1) || (i == xLen && t.opt.K0) {
 			feaLocks[i].Lock()
-			wGi := mult * xi
-			wSi := (1.0 / t.opt.WAlpha) * (math.Sqrt(mu.WNi+wGi*wGi) - math.Sqrt(mu.WNi))
-			mu.WZi += wGi - wSi*mu.Wi
-			mu.WNi += wGi * wGi
+			if labels[0] != sample.LabelMissing {
+				wGi := mults[0] * xi
+				wSi := (1.0 / t.opt.WAlpha) * (math.Sqrt(mu.WNi+wGi*wGi) - math.Sqrt(mu.WNi))
+				mu.WZi += wGi - wSi*mu.Wi
+				mu.WNi += wGi * wGi
+			}
+			for k := range mu.Heads {
+				if labels[k+1] == sample.LabelMissing {
+					continue
+				}
+				h := &mu.Heads[k]
+				wGi := mults[k+1] * xi
+				wSi := (1.0 / t.opt.WAlpha) * (math.Sqrt(h.N+wGi*wGi) - math.Sqrt(h.N))
+				h.Z += wGi - wSi*h.W
+				h.N += wGi * wGi
+			}
 			feaLocks[i].Unlock()
 		}
 	}
 
 	// 更新v_n, v_z（FFM版本）
 	if t.useSIMD && xLen > 0 {
-		t.updateVGradientsSIMD(theta, feaLocks, x, mult)
+		t.updateVGradientsSIMD(theta, feaLocks, x, vMult)
 	} else {
-		t.updateVGradientsScalar(theta, feaLocks, x, mult)
+		t.updateVGradientsScalar(theta, feaLocks, x, vMult)
+	}
+	return p
+}
+
+// ftrlWeight 由FTRL的z、n参数计算一阶权重
+func (t *FFMTrainer) ftrlWeight(z, n float64) float64 {
+	if math.Abs(z) <= t.opt.WL1 {
+		return 0.0
+	}
+	return -1.0 * (1.0 / (t.opt.WL2 + (t.opt.WBeta+math.Sqrt(n))/t.opt.WAlpha)) *
+		(z - float64(utils.Sgn(z))*t.opt.WL1)
+}
+
+// taskLogit 由第一个任务的预测值 p 计算第 k 个任务的预测值: 二阶交互项共享，替换 bias 和一阶项
+func (t *FFMTrainer) taskLogit(p float64, k int, x []sample.FeatureValue, theta []*FFMModelUnit, thetaBias *FFMModelUnit) float64 {
+	p += thetaBias.Heads[k-1].W - thetaBias.Wi
+	for i := range x {
+		p += (theta[i].Heads[k-1].W - theta[i].Wi) * x[i].Value
 	}
+	return p
 }
 
 // predictScalar 标量版本的FFM预测
@@ -0,0 +1,198 @@
+package model
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/xiongle/alphaFFM-go/pkg/config"
+	"github.com/xiongle/alphaFFM-go/pkg/frame"
+	"github.com/xiongle/alphaFFM-go/pkg/sample"
+)
+
+// SourceFFM 样本中显式指定了域（field:feature:value）
+const SourceFFM = "ffm"
+
+// SourceFailed 特征无法确定域
+const SourceFailed = "failed"
+
+// FeatureCount 特征（或域、错误原因）及其出现次数
+type FeatureCount struct {
+	Name  string
+	Count int64
+}
+
+// FieldCheckReport 域配置检查结果
+type FieldCheckReport struct {
+	Lines        int64             // 样本行数
+	InvalidLines int64             // 解析失败的行数
+	Tokens       int64             // 特征出现次数
+	Sources      []FeatureCount    // 每种来源（ffm、numeric、规则、feature_to_field、default_field、failed 等）确定的特征次数
+	Rules        []config.RuleStat // 每条规则的命中次数，包括从未命中的规则
+	Defaults     []FeatureCount    // 落入默认域的特征，按次数降序
+	Failures     []FeatureCount    // 无法确定域的特征，按次数降序
+	Errors       []FeatureCount    // 解析失败的原因，按次数降序
+	Fields       []FeatureCount    // 域 -> 不同特征个数，按域名排序
+	Features     int               // 不同特征个数
+	FactorNum    int
+}
+
+// Params 估算的模型参数个数: 每个特征的 w 和每个域的隐向量，各带 FTRL 的 n、z 两个参数
+func (r *FieldCheckReport) Params() int64 {
+	return int64(r.Features) * (3 + int64(len(r.Fields))*int64(r.FactorNum)*3)
+}
+
+// FieldChecker 用域配置试解析样本，统计每个特征的域来自哪条规则，可直接交给 PCFrame 运行
+type FieldChecker struct {
+	parserTask
+	cfg       *config.FieldConfig
+	factorNum int
+
+	mu       sync.Mutex
+	lines    int64
+	invalid  int64
+	tokens   int64
+	sources  map[string]int64
+	defaults map[string]int64
+	failures map[string]int64
+	errors   map[string]int64
+	fields   map[string]map[string]struct{}
+	features map[string]struct{}
+}
+
+// NewFieldChecker 创建域配置检查任务，配置须已通过校验，坏行按 policy 处理
+func NewFieldChecker(cfg *config.FieldConfig, factorNum int, policy sample.ErrorPolicy) (*FieldChecker, error) {
+	fc := &FieldChecker{
+		parserTask: newSampleParser(taskFieldCheck, sample.InputFormatFFM, cfg, ""),
+		cfg:        cfg,
+		factorNum:  factorNum,
+		sources:    make(map[string]int64),
+		defaults:   make(map[string]int64),
+		failures:   make(map[string]int64),
+		errors:     make(map[string]int64),
+		fields:     make(map[string]map[string]struct{}),
+		features:   make(map[string]struct{}),
+	}
+	if err := fc.setErrorPolicy(policy); err != nil {
+		return nil, err
+	}
+	return fc, nil
+}
+
+// RunTask 处理一批数据
+func (fc *FieldChecker) RunTask(dataBuffer []string) error {
+	return fc.RunBatch(&frame.Batch{Lines: dataBuffer})
+}
+
+// RunBatch 处理一批带输入位置的数据，坏行计入检查结果并按错误策略处理
+func (fc *FieldChecker) RunBatch(batch *frame.Batch) error {
+	samples, errs, policyErr := fc.parseBatchErrors(batch)
+	tokens := make([][][2]string, len(batch.Lines)) // 每行的特征名、来源
+	for i, line := range batch.Lines {
+		parts := strings.Fields(line)
+		for j := 1; j < len(parts); j++ {
+			kv := strings.Split(parts[j], ":")
+			switch len(kv) {
+			case 3:
+				tokens[i] = append(tokens[i], [2]string{kv[1], SourceFFM})
+			case 2:
+				_, source, err := config.ResolveFieldSource(fc.cfg, kv[0])
+				if err != nil {
+					source = SourceFailed
+				}
+				tokens[i] = append(tokens[i], [2]string{kv[0], source})
+			}
+		}
+	}
+
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	fc.lines += int64(len(batch.Lines))
+	fc.invalid += int64(len(errs))
+	for _, e := range errs {
+		fc.errors[errorReason(e.Err)]++
+	}
+	for i, s := range samples {
+		for _, t := range tokens[i] {
+			fc.tokens++
+			fc.sources[t[1]]++
+			switch t[1] {
+			case config.SourceDefault:
+				fc.defaults[t[0]]++
+			case SourceFailed:
+				fc.failures[t[0]]++
+			}
+		}
+		if s == nil {
+			continue
+		}
+		for _, x := range s.X {
+			features, ok := fc.fields[x.Field]
+			if !ok {
+				features = make(map[string]struct{})
+				fc.fields[x.Field] = features
+			}
+			features[x.Feature] = struct{}{}
+			fc.features[x.Feature] = struct{}{}
+		}
+	}
+	return policyErr
+}
+
+// quotedPattern 错误信息中引号括起的特征名或取值
+var quotedPattern = regexp.MustCompile(`'[^']*'|"[^"]*"`)
+
+// errorReason 错误原因，去掉具体的特征名和取值以便按原因汇总
+func errorReason(err error) string {
+	msg := err.Error()
+	if strings.HasPrefix(msg, "invalid feature format:") {
+		return "invalid feature format"
+	}
+	return quotedPattern.ReplaceAllString(msg, "'...'")
+}
+
+// Report 生成检查结果，top 为每个列表最多保留的条数（0 表示不限）
+func (fc *FieldChecker) Report(top int) *FieldCheckReport {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+
+	r := &FieldCheckReport{
+		Lines:        fc.lines,
+		InvalidLines: fc.invalid,
+		Tokens:       fc.tokens,
+		Sources:      sortCounts(fc.sources, 0),
+		Defaults:     sortCounts(fc.defaults, top),
+		Failures:     sortCounts(fc.failures, top),
+		Errors:       sortCounts(fc.errors, top),
+		Features:     len(fc.features),
+		FactorNum:    fc.factorNum,
+	}
+	// 解析和来源统计都会经过规则匹配，命中次数按来源统计，而不是用配置中的计数器
+	for _, stat := range fc.cfg.RuleStats() {
+		r.Rules = append(r.Rules, config.RuleStat{Rule: stat.Rule, Hits: fc.sources[stat.Rule]})
+	}
+	for field, features := range fc.fields {
+		r.Fields = append(r.Fields, FeatureCount{Name: field, Count: int64(len(features))})
+	}
+	sort.Slice(r.Fields, func(i, j int) bool { return r.Fields[i].Name < r.Fields[j].Name })
+	return r
+}
+
+// sortCounts 按次数降序（次数相同按名称）排列，保留前 top 条
+func sortCounts(m map[string]int64, top int) []FeatureCount {
+	counts := make([]FeatureCount, 0, len(m))
+	for name, count := range m {
+		counts = append(counts, FeatureCount{Name: name, Count: count})
+	}
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].Count != counts[j].Count {
+			return counts[i].Count > counts[j].Count
+		}
+		return counts[i].Name < counts[j].Name
+	})
+	if top > 0 && len(counts) > top {
+		counts = counts[:top]
+	}
+	return counts
+}
@@ -0,0 +1,92 @@
+package model
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/xiongle/alphaFFM-go/pkg/config"
+	"github.com/xiongle/alphaFFM-go/pkg/sample"
+)
+
+func TestFieldChecker(t *testing.T) {
+	cfg := config.NewFieldConfig()
+	cfg.FeatureToField = map[string]string{"age": "user"}
+	cfg.DefaultField = "misc"
+	cfg.Rules = []*config.FieldRule{
+		{Type: config.RulePrefix, Pattern: "tag", Field: "tags"},
+		{Type: config.RuleSuffix, Pattern: "_x", Field: "never"},
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	fc, err := NewFieldChecker(cfg, 4, sample.ErrorPolicy{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := fc.RunTask([]string{
+		"1 age:1 tag1:1 tag2:1 zz:1 51539607553:1",
+		"0 u:i:1 -1:1 zz:1",
+		"0 age:x",
+	}); err != nil {
+		t.Fatal(err)
+	}
+	r := fc.Report(0)
+
+	if r.Lines != 3 || r.InvalidLines != 1 || r.Tokens != 9 {
+		t.Errorf("counts: got %d lines, %d invalid, %d tokens", r.Lines, r.InvalidLines, r.Tokens)
+	}
+	sources := make(map[string]int64)
+	for _, s := range r.Sources {
+		sources[s.Name] = s.Count
+	}
+	want := map[string]int64{
+		config.SourceMapping: 2, config.SourceDefault: 2, "#0 prefix(tag) -> tags": 2,
+		config.SourceNumeric: 1, config.SourceSpecial: 1, SourceFFM: 1,
+	}
+	for name, count := range want {
+		if sources[name] != count {
+			t.Errorf("source %s: got %d, want %d", name, sources[name], count)
+		}
+	}
+	if len(r.Rules) != 4 || r.Rules[0].Hits != 2 || r.Rules[1].Hits != 0 {
+		t.Errorf("rules: got %+v", r.Rules)
+	}
+	if len(r.Defaults) != 1 || r.Defaults[0] != (FeatureCount{Name: "zz", Count: 2}) {
+		t.Errorf("defaults: got %+v", r.Defaults)
+	}
+	if len(r.Errors) != 1 {
+		t.Errorf("errors: got %+v", r.Errors)
+	}
+	if got := fc.InputSummary(); !strings.HasPrefix(got, "rejected 1 of 3 lines") {
+		t.Errorf("InputSummary = %q", got)
+	}
+	// age tag1 tag2 zz 51539607553 i -1
+	if r.Features != 7 || len(r.Fields) != 6 {
+		t.Errorf("features/fields: got %d/%+v", r.Features, r.Fields)
+	}
+	if got, want := r.Params(), int64(7*(3+6*4*3)); got != want {
+		t.Errorf("params: got %d, want %d", got, want)
+	}
+
+	// 坏行按错误策略处理
+	strict, err := NewFieldChecker(cfg, 4, sample.ErrorPolicy{OnError: sample.OnErrorFail})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := strict.RunTask([]string{"1 age:1", "0 age:x"}); err == nil {
+		t.Error("expected error with on_error=fail")
+	}
+	if r := strict.Report(0); r.InvalidLines != 1 {
+		t.Errorf("on_error=fail: got %d invalid lines, want 1", r.InvalidLines)
+	}
+
+	cfg.Mode = "explicit"
+	if fc, err = NewFieldChecker(cfg, 4, sample.ErrorPolicy{}); err != nil {
+		t.Fatal(err)
+	}
+	fc.RunTask([]string{"1 zz:1"})
+	if r := fc.Report(0); len(r.Fields) != 1 || r.Fields[0].Name != "" {
+		t.Errorf("explicit mode: got %+v", r.Fields)
+	}
+}
@@ -0,0 +1,164 @@
+package model
+
+import (
+	"fmt"
+
+	"github.com/xiongle/alphaFFM-go/pkg/config"
+	"github.com/xiongle/alphaFFM-go/pkg/frame"
+	"github.com/xiongle/alphaFFM-go/pkg/sample"
+)
+
+// LoadFieldConfig 加载并校验域配置文件（先尝试JSON格式，再尝试文本格式）
+// 返回的 format 为 "JSON" 或 "text"
+func LoadFieldConfig(path string) (fieldConfig *config.FieldConfig, format string, err error) {
+	fieldConfig = config.NewFieldConfig()
+
+	// 尝试JSON格式
+	format = "JSON"
+	if err := fieldConfig.LoadFromJSON(path); err != nil {
+		// 尝试文本格式
+		if err2 := fieldConfig.LoadFromText(path); err2 != nil {
+			return nil, "", fmt.Errorf("failed to load field config from %s (JSON: %v, Text: %v)", path, err, err2)
+		}
+		// 文本格式加载成功，设置为config模式
+		fieldConfig.Mode = "config"
+		format = "text"
+	}
+
+	if err := fieldConfig.Validate(); err != nil {
+		return nil, "", fmt.Errorf("invalid field config: %v", err)
+	}
+	return fieldConfig, format, nil
+}
+
+// loadFieldConfig 加载域配置文件
+// 路径为空或加载、校验失败时返回 nil（打印警告），样本须使用FFM格式或大数字特征
+func loadFieldConfig(path string) *config.FieldConfig {
+	if path == "" {
+		return nil
+	}
+	fieldConfig, format, err := LoadFieldConfig(path)
+	if err != nil {
+		fmt.Printf("Warning: %v, using auto mode\n", err)
+		return nil
+	}
+	if format == "text" {
+		fmt.Printf("Loaded field config from %s (text format, %d mappings)\n",
+			path, len(fieldConfig.FeatureToField))
+	} else {
+		fmt.Printf("Loaded field config from %s (JSON format, mode: %s, %d mappings)\n",
+			path, fieldConfig.Mode, len(fieldConfig.FeatureToField))
+	}
+	return fieldConfig
+}
+
+// newSampleParser 创建样本解析器，输入格式或列定义无效时打印警告并使用默认格式
+// task 为运行指标中的任务标签
+func newSampleParser(task, format string, fieldConfig *config.FieldConfig, schemaPath string) parserTask {
+	var schema *config.CSVSchema
+	if schemaPath != "" {
+		var err error
+		if schema, err = config.LoadCSVSchema(schemaPath); err != nil {
+			fmt.Printf("Warning: %v\n", err)
+		}
+	}
+	parser, err := sample.NewParser(format, fieldConfig, schema)
+	if err != nil {
+		fmt.Printf("Warning: %v, falling back to ffm\n", err)
+		parser, _ = sample.NewParser(sample.InputFormatFFM, fieldConfig, nil)
+	}
+	errs, _ := sample.NewErrorHandler(sample.ErrorPolicy{})
+	return parserTask{task: task, parser: parser, errs: errs}
+}
+
+// parserTask 持有样本解析器和坏样本处理器，嵌入到各任务中实现 frame.HeaderTask
+type parserTask struct {
+	task   string
+	parser sample.Parser
+	errs   *sample.ErrorHandler
+	tasks  int // 大于0时检查每个样本的标签个数与任务数一致（训练）
+}
+
+// setErrorPolicy 设置坏样本的处理策略
+func (p *parserTask) setErrorPolicy(policy sample.ErrorPolicy) error {
+	errs, err := sample.NewErrorHandler(policy)
+	if err != nil {
+		return err
+	}
+	p.errs = errs
+	return nil
+}
+
+// parseBatch 解析一批样本，返回的切片与输入行一一对应，坏行为 nil
+// 按错误策略需要终止时返回错误
+func (p *parserTask) parseBatch(batch *frame.Batch) ([]*sample.FFMSample, error) {
+	samples, _, err := p.parseBatchErrors(batch)
+	return samples, err
+}
+
+// parseBatchErrors 与 parseBatch 相同，同时返回这批样本的解析错误（按行序）
+func (p *parserTask) parseBatchErrors(batch *frame.Batch) ([]*sample.FFMSample, []*sample.ParseError, error) {
+	samples := make([]*sample.FFMSample, len(batch.Lines))
+	var errs []*sample.ParseError
+	var rejected []string
+	for i, line := range batch.Lines {
+		s, err := p.parser.Parse(line)
+		if err == nil && p.tasks > 0 {
+			err = sample.CheckTaskLabels(s, p.tasks)
+		}
+		if err != nil {
+			// 没有位置信息的批次（直接调用 RunTask）行号未知
+			var lineNum int64
+			if batch.FirstLine > 0 {
+				lineNum = batch.Line(i)
+			}
+			errs = append(errs, sample.AtLine(err, batch.Source, lineNum))
+			rejected = append(rejected, line)
+			continue
+		}
+		samples[i] = s
+	}
+	linesTotal.Add(p.task, int64(len(batch.Lines)))
+	samplesTotal.Add(p.task, int64(len(batch.Lines)-len(errs)))
+	for _, e := range errs {
+		parseErrorsTotal.Add(e.Reason, 1)
+	}
+	return samples, errs, p.errs.Record(len(batch.Lines), errs, rejected)
+}
+
+// FinishInput 输入处理完成后调用: 关闭坏行文件，并按最终的坏行比例检查错误策略
+func (p *parserTask) FinishInput() error {
+	return p.errs.Finish()
+}
+
+// InputSummary 坏行汇总
+func (p *parserTask) InputSummary() string {
+	return p.errs.Summary()
+}
+
+// NeedHeader 输入首行是否为表头
+func (p *parserTask) NeedHeader() bool {
+	h, ok := p.parser.(sample.HeaderParser)
+	return ok && h.NeedHeader()
+}
+
+// SetHeader 把表头交给解析器
+func (p *parserTask) SetHeader(line string) error {
+	if h, ok := p.parser.(sample.HeaderParser); ok {
+		return h.SetHeader(line)
+	}
+	return nil
+}
+
+// CheckInput 检查输入格式和列定义是否有效，供命令行在创建任务前提前报错
+func CheckInput(format, schemaPath string) error {
+	var schema *config.CSVSchema
+	if schemaPath != "" {
+		var err error
+		if schema, err = config.LoadCSVSchema(schemaPath); err != nil {
+			return err
+		}
+	}
+	_, err := sample.NewParser(format, nil, schema)
+	return err
+}
@@ -0,0 +1,205 @@
+package model
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+
+	"github.com/xiongle/alphaFFM-go/pkg/frame"
+	"github.com/xiongle/alphaFFM-go/pkg/sample"
+)
+
+// ImportanceItem 单个特征、field或field对的重要性
+type ImportanceItem struct {
+	Name          string  `json:"name"`
+	AvgAbs        float64 `json:"avg_abs"`         // 在所有样本上的平均绝对贡献（未出现记为0）
+	AvgAbsPresent float64 `json:"avg_abs_present"` // 在出现该项的样本上的平均绝对贡献
+	Occurrences   int64   `json:"occurrences"`     // 出现该项的样本数
+}
+
+// ImportanceReport 全局重要性报告
+type ImportanceReport struct {
+	Samples    int64            `json:"samples"`
+	Features   []ImportanceItem `json:"features"`
+	Fields     []ImportanceItem `json:"fields"`
+	FieldPairs []ImportanceItem `json:"field_pairs"`
+}
+
+// importanceEntry 累计值
+type importanceEntry struct {
+	sumAbs      float64
+	occurrences int64
+}
+
+// ImportanceAccumulator 累计样本解释，计算全局的特征、field和field对重要性
+//
+// 每个样本的贡献归属方式:
+//   - 特征: |wi*xi| 加上它参与的每个二阶项绝对值的一半
+//   - field: 该field中所有特征的 |wi*xi| 之和，加上一端落在该field的二阶项绝对值的一半
+//     （两端都在该field时计全部）
+//   - field对: 样本内该field对所有二阶项之和的绝对值
+type ImportanceAccumulator struct {
+	mu         sync.Mutex
+	samples    int64
+	features   map[string]*importanceEntry
+	fields     map[string]*importanceEntry
+	fieldPairs map[string]*importanceEntry
+}
+
+// NewImportanceAccumulator 创建重要性累计器
+func NewImportanceAccumulator() *ImportanceAccumulator {
+	return &ImportanceAccumulator{
+		features:   make(map[string]*importanceEntry),
+		fields:     make(map[string]*importanceEntry),
+		fieldPairs: make(map[string]*importanceEntry),
+	}
+}
+
+// addTo 将样本内的累计值并入全局累计
+func addTo(dst map[string]*importanceEntry, sampleValues map[string]float64) {
+	for name, v := range sampleValues {
+		entry, ok := dst[name]
+		if !ok {
+			entry = &importanceEntry{}
+			dst[name] = entry
+		}
+		entry.sumAbs += v
+		entry.occurrences++
+	}
+}
+
+// Add 加入一个样本的完整解释（Explain 的 topN 须为 0）
+func (a *ImportanceAccumulator) Add(e *Explanation) {
+	features := make(map[string]float64, len(e.Features))
+	fields := make(map[string]float64)
+	fieldPairs := make(map[string]float64, len(e.FieldPairs))
+
+	for _, f := range e.Features {
+		c := math.Abs(f.Contribution)
+		features[f.Feature] += c
+		fields[f.Field] += c
+	}
+	for _, p := range e.Pairs {
+		half := math.Abs(p.Contribution) / 2
+		features[p.FeatureA] += half
+		features[p.FeatureB] += half
+		fields[p.FieldA] += half
+		fields[p.FieldB] += half
+	}
+	for _, fp := range e.FieldPairs {
+		fieldPairs[fp.FieldA+"|"+fp.FieldB] += math.Abs(fp.Contribution)
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.samples++
+	addTo(a.features, features)
+	addTo(a.fields, fields)
+	addTo(a.fieldPairs, fieldPairs)
+}
+
+// rank 按平均绝对贡献排序，topN <= 0 时返回全部
+func (a *ImportanceAccumulator) rank(entries map[string]*importanceEntry, topN int) []ImportanceItem {
+	items := make([]ImportanceItem, 0, len(entries))
+	for name, entry := range entries {
+		item := ImportanceItem{Name: name, Occurrences: entry.occurrences}
+		if a.samples > 0 {
+			item.AvgAbs = entry.sumAbs / float64(a.samples)
+		}
+		if entry.occurrences > 0 {
+			item.AvgAbsPresent = entry.sumAbs / float64(entry.occurrences)
+		}
+		items = append(items, item)
+	}
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].AvgAbs != items[j].AvgAbs {
+			return items[i].AvgAbs > items[j].AvgAbs
+		}
+		return items[i].Name < items[j].Name
+	})
+	if topN > 0 && len(items) > topN {
+		items = items[:topN]
+	}
+	return items
+}
+
+// Report 生成重要性报告
+// 特征列表只保留topN项（topN <= 0 表示全部），field 和 field对 总是全部输出，便于判断哪些field可以删除
+func (a *ImportanceAccumulator) Report(topN int) *ImportanceReport {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return &ImportanceReport{
+		Samples:    a.samples,
+		Features:   a.rank(a.features, topN),
+		Fields:     a.rank(a.fields, 0),
+		FieldPairs: a.rank(a.fieldPairs, 0),
+	}
+}
+
+// ImportanceOption 重要性统计选项
+type ImportanceOption struct {
+	ModelPath       string
+	ModelFormat     string
+	FactorNum       int                // 隐向量维度，0 表示从模型头读取
+	FieldConfigPath string             // 域配置文件路径
+	InputFormat     string             // 输入格式: ffm、libffm、csv、tsv 或 jsonl
+	SchemaPath      string             // csv/tsv 的列定义文件
+	ErrorPolicy     sample.ErrorPolicy // 坏样本的处理策略
+}
+
+// FFMImportance 重要性统计任务，可直接交给 PCFrame 运行
+type FFMImportance struct {
+	model *PredictModel
+	parserTask
+	acc *ImportanceAccumulator
+}
+
+// NewFFMImportance 创建重要性统计任务
+func NewFFMImportance(opt *ImportanceOption) (*FFMImportance, error) {
+	im := &FFMImportance{
+		model:      NewPredictModel(opt.FactorNum),
+		parserTask: newSampleParser(taskImportance, opt.InputFormat, loadFieldConfig(opt.FieldConfigPath), opt.SchemaPath),
+		acc:        NewImportanceAccumulator(),
+	}
+	if err := im.setErrorPolicy(opt.ErrorPolicy); err != nil {
+		return nil, err
+	}
+
+	fmt.Println("load model...")
+	if err := im.model.LoadModel(opt.ModelPath, opt.ModelFormat); err != nil {
+		return nil, fmt.Errorf("load model error: %v", err)
+	}
+	fmt.Println("model loading finished")
+	// 重要性基于第一个任务的logit分解，多任务模型不支持
+	if im.model.NumTasks() > 1 {
+		return nil, errMultiTask("importance")
+	}
+	return im, nil
+}
+
+// RunTask 处理一批数据
+func (im *FFMImportance) RunTask(dataBuffer []string) error {
+	return im.RunBatch(&frame.Batch{Lines: dataBuffer})
+}
+
+// RunBatch 处理一批带输入位置的数据，坏行按错误策略处理
+func (im *FFMImportance) RunBatch(batch *frame.Batch) error {
+	samples, err := im.parseBatch(batch)
+	if err != nil {
+		return err
+	}
+	for _, s := range samples {
+		if s == nil {
+			continue
+		}
+
+		im.acc.Add(im.model.Explain(toPredictInput(s), im.model.MuBias.Wi, 0))
+	}
+	return nil
+}
+
+// Report 生成重要性报告
+func (im *FFMImportance) Report(topN int) *ImportanceReport {
+	return im.acc.Report(topN)
+}
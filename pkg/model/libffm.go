@@ -0,0 +1,290 @@
+package model
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+
+	"github.com/xiongle/alphaFFM-go/pkg/sample"
+)
+
+// libffm 二进制模型格式（ffm_save_model，小端序）:
+//
+//	n              int32 特征数
+//	m              int32 field数
+//	k              int32 隐向量维度
+//	normalization  1字节 bool，是否做样本级归一化
+//	W              float32[n][m][2*kAligned]，每个(特征, field)按 kALIGN 分段交错存放权重和AdaGrad累积梯度:
+//	               w[0..kALIGN) g[0..kALIGN) w[kALIGN..2kALIGN) g[kALIGN..2kALIGN) ...
+//
+// 开启SSE编译时 kALIGN 为4、kAligned 为 k 向上取整到4的倍数，否则 kALIGN 为1、kAligned 等于 k。
+// 加载时根据文件大小判断；k 是4的倍数时两种布局大小相同，取累积梯度都不小于1（初始值为1）的布局
+const (
+	libFFMHeaderSize = 13
+	libFFMAlign      = 4
+)
+
+// LibFFMModel libffm模型，参数按 (特征ID, fieldID) 稠密存储
+// libffm 没有偏置和一阶项，logit = Σ <w[j1][f2], w[j2][f1]> * x1 * x2 * r，
+// normalization 为 true 时 r 为样本特征值平方和的倒数，否则为1
+type LibFFMModel struct {
+	N             int
+	M             int
+	K             int
+	Normalization bool
+	W             []float32 // 长度 N*M*K，W[(j*M+f)*K : (j*M+f+1)*K] 为特征j针对field f的隐向量
+}
+
+// NewLibFFMModel 创建全零的libffm模型
+func NewLibFFMModel(n, m, k int) *LibFFMModel {
+	return &LibFFMModel{N: n, M: m, K: k, W: make([]float32, n*m*k)}
+}
+
+// Vector 特征j针对field f的隐向量
+func (l *LibFFMModel) Vector(j, f int) []float32 {
+	offset := (j*l.M + f) * l.K
+	return l.W[offset : offset+l.K]
+}
+
+// alignK 按 kALIGN 对齐的隐向量长度
+func alignK(k, kAlign int) int {
+	return (k + kAlign - 1) / kAlign * kAlign
+}
+
+// libFFMLane 对齐后第d维的权重和累积梯度在 (特征, field) 块中的下标
+func libFFMLane(d, kAlign int) (w, g int) {
+	w = d/kAlign*2*kAlign + d%kAlign
+	return w, w + kAlign
+}
+
+// LoadLibFFMModel 加载libffm二进制模型
+func LoadLibFFMModel(path string) (*LibFFMModel, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	reader := bufio.NewReaderSize(file, 1<<20)
+	var header [libFFMHeaderSize]byte
+	if _, err := io.ReadFull(reader, header[:]); err != nil {
+		return nil, fmt.Errorf("invalid libffm model: truncated header")
+	}
+	n := int(int32(binary.LittleEndian.Uint32(header[0:4])))
+	m := int(int32(binary.LittleEndian.Uint32(header[4:8])))
+	k := int(int32(binary.LittleEndian.Uint32(header[8:12])))
+	if n < 0 || m <= 0 || k <= 0 {
+		return nil, fmt.Errorf("invalid libffm model: n=%d, m=%d, k=%d", n, m, k)
+	}
+	body, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	// 根据文件大小判断是否按SSE对齐，大小相同时再看累积梯度
+	var candidates []int
+	for _, kAlign := range []int{libFFMAlign, 1} {
+		if int64(n)*int64(m)*int64(alignK(k, kAlign))*2*4 == int64(len(body)) {
+			candidates = append(candidates, kAlign)
+		}
+	}
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("invalid libffm model: size %d does not match n=%d, m=%d, k=%d",
+			libFFMHeaderSize+len(body), n, m, k)
+	}
+	kAlign := candidates[0]
+	for _, c := range candidates {
+		if libFFMGradientsValid(body, k, c) {
+			kAlign = c
+			break
+		}
+	}
+
+	l := NewLibFFMModel(n, m, k)
+	l.Normalization = header[12] != 0
+	blockSize := alignK(k, kAlign) * 2 * 4
+	for j := 0; j < n; j++ {
+		for f := 0; f < m; f++ {
+			block := body[(j*m+f)*blockSize:]
+			v := l.Vector(j, f)
+			for d := 0; d < k; d++ {
+				w, _ := libFFMLane(d, kAlign)
+				v[d] = math.Float32frombits(binary.LittleEndian.Uint32(block[w*4:]))
+			}
+		}
+	}
+	return l, nil
+}
+
+// libFFMGradientsValid 按 kAlign 布局读取时累积梯度是否都不小于1
+// AdaGrad 累积梯度初始为1且只增不减，错误的布局会把权重当作梯度读出
+func libFFMGradientsValid(body []byte, k, kAlign int) bool {
+	kAligned := alignK(k, kAlign)
+	for block := 0; block < len(body); block += kAligned * 2 * 4 {
+		for d := 0; d < kAligned; d++ {
+			_, g := libFFMLane(d, kAlign)
+			if math.Float32frombits(binary.LittleEndian.Uint32(body[block+g*4:])) < 1 {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Save 输出libffm二进制模型（SSE对齐，kALIGN 为4）
+// 与 libffm 的 init_model 一样，对齐填充的权重为0，AdaGrad累积梯度写为初始值1，
+// libffm可以直接加载预测；继续训练时相当于重新开始学习率衰减
+func (l *LibFFMModel) Save(path string) error {
+	return atomicWriteFile(path, func(w io.Writer) error {
+		var header [libFFMHeaderSize]byte
+		binary.LittleEndian.PutUint32(header[0:4], uint32(l.N))
+		binary.LittleEndian.PutUint32(header[4:8], uint32(l.M))
+		binary.LittleEndian.PutUint32(header[8:12], uint32(l.K))
+		if l.Normalization {
+			header[12] = 1
+		}
+		if _, err := w.Write(header[:]); err != nil {
+			return err
+		}
+
+		kAligned := alignK(l.K, libFFMAlign)
+		block := make([]byte, kAligned*2*4)
+		for d := 0; d < kAligned; d++ {
+			_, g := libFFMLane(d, libFFMAlign)
+			binary.LittleEndian.PutUint32(block[g*4:], math.Float32bits(1))
+		}
+		for j := 0; j < l.N; j++ {
+			for f := 0; f < l.M; f++ {
+				for d, v := range l.Vector(j, f) {
+					lane, _ := libFFMLane(d, libFFMAlign)
+					binary.LittleEndian.PutUint32(block[lane*4:], math.Float32bits(v))
+				}
+				if _, err := w.Write(block); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// ToFFMModel 转换为FFM模型
+// 特征和field名称为十进制ID（与 libffm 输入格式的解析结果一致），全零的隐向量不输出；
+// 偏置、一阶项和FTRL的n/z为0，继续训练时导入的隐向量相当于初始值
+func (l *LibFFMModel) ToFFMModel() *FFMModel {
+	m := NewFFMModel(l.K, 0.0, 0.0)
+	m.GetOrInitModelUnitBias()
+	m.Meta = &ModelMeta{FormatVersion: ModelFormatVersion, FactorNum: l.K, InputFormat: sample.InputFormatLibFFM}
+
+	fieldNames := make([]string, l.M)
+	for f := 0; f < l.M; f++ {
+		fieldNames[f] = strconv.Itoa(f)
+		m.RegisterField(fieldNames[f])
+	}
+
+	for j := 0; j < l.N; j++ {
+		var unit *FFMModelUnit
+		for f := 0; f < l.M; f++ {
+			v := l.Vector(j, f)
+			if isZeroVector(v) {
+				continue
+			}
+			if unit == nil {
+				unit = m.GetOrInitModelUnit(strconv.Itoa(j))
+			}
+			vi := make([]float64, l.K)
+			for d := range v {
+				vi[d] = float64(v[d])
+			}
+			unit.ViMap[fieldNames[f]] = vi
+			unit.VNiMap[fieldNames[f]] = make([]float64, l.K)
+			unit.VZiMap[fieldNames[f]] = make([]float64, l.K)
+		}
+	}
+	return m
+}
+
+// isZeroVector 判断是否全零
+func isZeroVector(v []float32) bool {
+	for _, x := range v {
+		if x != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// LibFFMExport 导出结果
+type LibFFMExport struct {
+	Model         *LibFFMModel
+	DroppedBias   float64 // 被丢弃的偏置
+	DroppedLinear int     // 被丢弃的非零一阶项个数
+}
+
+// ExportLibFFM 将FFM模型转换为libffm模型
+// 特征名和field名都必须是非负整数（即使用 libffm 输入格式训练），n 和 m 取最大ID加1；
+// libffm 没有偏置和一阶项，它们会被丢弃并在结果中报告
+func ExportLibFFM(m *FFMModel) (*LibFFMExport, error) {
+	if m.NumTasks() > 1 {
+		return nil, errMultiTask("libffm export")
+	}
+	fieldIDs := make(map[string]int, len(m.FieldNames))
+	maxField := -1
+	for _, field := range m.FieldNames {
+		id, err := parseLibFFMID(field)
+		if err != nil {
+			return nil, fmt.Errorf("field %q is not a libffm field id", field)
+		}
+		fieldIDs[field] = id
+		if id > maxField {
+			maxField = id
+		}
+	}
+
+	featureIDs := make(map[string]int, len(m.MuMap))
+	maxFeature := -1
+	for feature := range m.MuMap {
+		id, err := parseLibFFMID(feature)
+		if err != nil {
+			return nil, fmt.Errorf("feature %q is not a libffm feature id", feature)
+		}
+		featureIDs[feature] = id
+		if id > maxFeature {
+			maxFeature = id
+		}
+	}
+	if maxField < 0 {
+		return nil, fmt.Errorf("model has no fields")
+	}
+
+	export := &LibFFMExport{Model: NewLibFFMModel(maxFeature+1, maxField+1, m.FactorNum)}
+	if m.MuBias != nil {
+		export.DroppedBias = m.MuBias.Wi
+	}
+	for feature, unit := range m.MuMap {
+		if unit.Wi != 0 {
+			export.DroppedLinear++
+		}
+		j := featureIDs[feature]
+		for field, vi := range unit.ViMap {
+			v := export.Model.Vector(j, fieldIDs[field])
+			for d := range v {
+				v[d] = float32(vi[d])
+			}
+		}
+	}
+	return export, nil
+}
+
+// parseLibFFMID 解析libffm的非负整数ID
+func parseLibFFMID(s string) (int, error) {
+	id, err := strconv.ParseUint(s, 10, 31)
+	if err != nil {
+		return 0, err
+	}
+	return int(id), nil
+}
@@ -0,0 +1,183 @@
+package model
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/xiongle/alphaFFM-go/pkg/sample"
+)
+
+func TestExportLibFFM(t *testing.T) {
+	// 用 libffm 输入格式的样本构造模型，特征和field名称都是十进制ID
+	s, err := sample.LibFFMParser{}.Parse("1 0:3:1 02:007:0.5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.X[1].Field != "2" || s.X[1].Feature != "7" {
+		t.Fatalf("ids not normalized: %+v", s.X[1])
+	}
+	if _, err := (sample.LibFFMParser{}).Parse("1 user:3:1"); err == nil {
+		t.Error("expected error for non-integer field")
+	}
+
+	m := NewFFMModel(3, 0, 0)
+	m.GetOrInitModelUnitBias().Wi = 0.5
+	m.RegisterField("0")
+	m.RegisterField("2")
+	u3 := m.GetOrInitModelUnit("3")
+	u3.Wi = 0.1
+	u3.ViMap["2"] = []float64{0.1, 0.2, 0.3}
+	u7 := m.GetOrInitModelUnit("7")
+	u7.ViMap["0"] = []float64{-0.5, 0.25, 1}
+
+	export, err := ExportLibFFM(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if export.DroppedBias != 0.5 || export.DroppedLinear != 1 {
+		t.Errorf("dropped: bias %v, linear %d", export.DroppedBias, export.DroppedLinear)
+	}
+	if l := export.Model; l.N != 8 || l.M != 3 || l.K != 3 {
+		t.Fatalf("shape: n=%d m=%d k=%d", l.N, l.M, l.K)
+	}
+
+	imported := export.Model.ToFFMModel()
+	if len(imported.MuMap) != 2 || len(imported.FieldNames) != 3 {
+		t.Fatalf("imported %d features, %d fields", len(imported.MuMap), len(imported.FieldNames))
+	}
+	for feature, unit := range m.MuMap {
+		for field, vi := range unit.ViMap {
+			got := imported.MuMap[feature].ViMap[field]
+			for d := range vi {
+				if math.Abs(got[d]-vi[d]) > 1e-7 {
+					t.Errorf("%s/%s[%d]: got %v, want %v", feature, field, d, got[d], vi[d])
+				}
+			}
+		}
+	}
+}
+
+// libFFMFixture 按给出的float32序列手工写出libffm模型文件
+func libFFMFixture(t *testing.T, n, m, k int, normalization bool, body []float32) []byte {
+	t.Helper()
+	data := make([]byte, libFFMHeaderSize+len(body)*4)
+	binary.LittleEndian.PutUint32(data[0:], uint32(n))
+	binary.LittleEndian.PutUint32(data[4:], uint32(m))
+	binary.LittleEndian.PutUint32(data[8:], uint32(k))
+	if normalization {
+		data[12] = 1
+	}
+	for i, v := range body {
+		binary.LittleEndian.PutUint32(data[libFFMHeaderSize+i*4:], math.Float32bits(v))
+	}
+	return data
+}
+
+// sseBlock SSE编译的libffm中 k=6 的一个(特征, field)块:
+// 4个权重、4个梯度、2个权重和2个填充0、4个梯度
+func sseBlock(base float32, g float32) []float32 {
+	return []float32{
+		base + 1, base + 2, base + 3, base + 4, g, g, g, g,
+		base + 5, base + 6, 0, 0, g, g, g, g,
+	}
+}
+
+func TestLoadLibFFMModelSSE(t *testing.T) {
+	var body []float32
+	for j := 0; j < 2; j++ {
+		for f := 0; f < 2; f++ {
+			body = append(body, sseBlock(float32(10*j+f), 1.5)...)
+		}
+	}
+	path := filepath.Join(t.TempDir(), "model.ffm")
+	if err := os.WriteFile(path, libFFMFixture(t, 2, 2, 6, true, body), 0644); err != nil {
+		t.Fatal(err)
+	}
+	l, err := LoadLibFFMModel(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l.N != 2 || l.M != 2 || l.K != 6 || !l.Normalization {
+		t.Fatalf("header: n=%d m=%d k=%d normalization=%v", l.N, l.M, l.K, l.Normalization)
+	}
+	for _, c := range []struct {
+		j, f int
+		want []float32
+	}{
+		{0, 0, []float32{1, 2, 3, 4, 5, 6}},
+		{0, 1, []float32{2, 3, 4, 5, 6, 7}},
+		{1, 0, []float32{11, 12, 13, 14, 15, 16}},
+		{1, 1, []float32{12, 13, 14, 15, 16, 17}},
+	} {
+		if got := l.Vector(c.j, c.f); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("Vector(%d, %d) = %v, want %v", c.j, c.f, got, c.want)
+		}
+	}
+
+	// 导出的文件与libffm的布局逐字节一致：填充为0，累积梯度为1
+	for j := 0; j < 2; j++ {
+		for f := 0; f < 2; f++ {
+			copy(body[(j*2+f)*16:], sseBlock(float32(10*j+f), 1))
+		}
+	}
+	saved := filepath.Join(t.TempDir(), "saved.ffm")
+	if err := l.Save(saved); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(saved)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := libFFMFixture(t, 2, 2, 6, true, body); !bytes.Equal(data, want) {
+		t.Errorf("saved libffm model differs from the libffm layout")
+	}
+}
+
+func TestLoadLibFFMModelNoSSE(t *testing.T) {
+	// 不开启SSE时 kALIGN 为1，权重和累积梯度逐维交错；k=4 时文件大小与SSE布局相同，按梯度区分
+	body := []float32{
+		0.1, 1, 0.2, 1.5, 0.3, 2, 0.4, 2.5,
+		-0.5, 1, 0.25, 1, 0.125, 1, -1, 1,
+	}
+	path := filepath.Join(t.TempDir(), "model.ffm")
+	if err := os.WriteFile(path, libFFMFixture(t, 1, 2, 4, false, body), 0644); err != nil {
+		t.Fatal(err)
+	}
+	l, err := LoadLibFFMModel(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := l.Vector(0, 0), []float32{0.1, 0.2, 0.3, 0.4}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Vector(0, 0) = %v, want %v", got, want)
+	}
+	if got, want := l.Vector(0, 1), []float32{-0.5, 0.25, 0.125, -1}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Vector(0, 1) = %v, want %v", got, want)
+	}
+
+	// k=3 只有不对齐的布局与文件大小一致
+	path3 := filepath.Join(t.TempDir(), "model3.ffm")
+	if err := os.WriteFile(path3, libFFMFixture(t, 1, 1, 3, false, []float32{0.5, 1, 0.75, 1, -0.25, 1}), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if l, err := LoadLibFFMModel(path3); err != nil || !reflect.DeepEqual(l.Vector(0, 0), []float32{0.5, 0.75, -0.25}) {
+		t.Errorf("k=3 model: %v, %v", l, err)
+	}
+	// 大小对不上的文件被拒绝
+	if err := os.WriteFile(path3, libFFMFixture(t, 1, 1, 3, false, []float32{0.5, 1, 0.75}), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadLibFFMModel(path3); err == nil {
+		t.Error("expected error for truncated libffm model")
+	}
+}
+
+func TestExportLibFFMRejectsNames(t *testing.T) {
+	if _, err := ExportLibFFM(newTestModel()); err == nil {
+		t.Error("expected error for non-integer feature names")
+	}
+}
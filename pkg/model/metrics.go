@@ -0,0 +1,59 @@
+package model
+
+import (
+	"github.com/xiongle/alphaFFM-go/pkg/metrics"
+)
+
+// 训练和预测共用的运行指标，注册在 metrics.Default 中，用 -metrics_addr 对外提供
+var (
+	linesTotal = metrics.Default.NewCounterVec("alphaffm_lines_total",
+		"Input lines processed, including invalid ones.", "task")
+	samplesTotal = metrics.Default.NewCounterVec("alphaffm_samples_total",
+		"Valid samples trained or scored.", "task")
+	parseErrorsTotal = metrics.Default.NewCounterVec("alphaffm_parse_errors_total",
+		"Invalid input lines by reason.", "reason")
+	scoreLatency = metrics.Default.NewHistogram("alphaffm_score_latency_seconds",
+		"Time to score one sample in ffm_predict.", metrics.ExponentialBuckets(1e-6, 4, 10))
+)
+
+// 指标中的任务标签
+const (
+	taskTrain      = "train"
+	taskPredict    = "predict"
+	taskImportance = "importance"
+	taskFitBuckets = "fit_buckets"
+	taskFieldCheck = "fieldcheck"
+)
+
+// RegisterMetrics 注册训练模型的大小指标
+func (t *FFMTrainer) RegisterMetrics(r *metrics.Registry) {
+	r.NewGaugeFunc("alphaffm_model_features", "Features in the model.", func() float64 {
+		features, _ := t.model.Size()
+		return float64(features)
+	})
+	r.NewGaugeFunc("alphaffm_model_fields", "Fields in the model.", func() float64 {
+		_, fields := t.model.Size()
+		return float64(fields)
+	})
+	r.NewGaugeFunc("alphaffm_model_samples", "Samples the model has been trained on, including the initial model.", func() float64 {
+		return float64(t.model.SampleCount())
+	})
+	r.NewGaugeFunc("alphaffm_pv_logloss", "Progressive validation logloss of this run (predictions made before training on each sample).", func() float64 {
+		_, logloss, _ := t.validation.Metrics()
+		return logloss
+	})
+	r.NewGaugeFunc("alphaffm_pv_auc", "Progressive validation AUC of this run.", func() float64 {
+		_, _, auc := t.validation.Metrics()
+		return auc
+	})
+}
+
+// RegisterMetrics 注册预测模型的大小指标
+func (p *FFMPredictor) RegisterMetrics(r *metrics.Registry) {
+	r.NewGaugeFunc("alphaffm_model_features", "Features in the model.", func() float64 {
+		return float64(len(p.model.MuMap))
+	})
+	r.NewGaugeFunc("alphaffm_model_fields", "Fields in the model.", func() float64 {
+		return float64(len(p.model.FieldNames))
+	})
+}
@@ -0,0 +1,405 @@
+package model
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math"
+	"os"
+	"sync/atomic"
+)
+
+// 二进制模型格式（小端序）:
+//
+//	magic        8字节 "AFFMBIN\x00"
+//	meta         uint32 长度 + JSON（与文本模型 META 行内容相同）
+//	numberType   uint8，8 表示 float64，4 表示 float32
+//	fields       uint32 个数 + 每个 (uint32 长度 + 名称)
+//	bias         Wi WNi WZi heads
+//	features     uint64 个数 + 每个 (uint32 长度 + 名称, Wi, vi[F*k], WNi, WZi, vni[F*k], vzi[F*k], heads)
+//	             heads 为多任务模型其余任务的 (w n z)，单任务模型没有
+//	trailer      8字节 "AFFMEND\x00" + uint64 特征数 + uint32 之前所有字节的CRC32
+const (
+	binMagic   = "AFFMBIN\x00"
+	binTrailer = "AFFMEND\x00"
+)
+
+// binWriter 二进制模型写入器，记录第一个写错误
+type binWriter struct {
+	w       io.Writer
+	float32 bool
+	buf     [8]byte
+	err     error
+}
+
+func (b *binWriter) write(p []byte) {
+	if b.err == nil {
+		_, b.err = b.w.Write(p)
+	}
+}
+
+func (b *binWriter) uint32(v uint32) {
+	binary.LittleEndian.PutUint32(b.buf[:4], v)
+	b.write(b.buf[:4])
+}
+
+func (b *binWriter) uint64(v uint64) {
+	binary.LittleEndian.PutUint64(b.buf[:8], v)
+	b.write(b.buf[:8])
+}
+
+func (b *binWriter) string(s string) {
+	b.uint32(uint32(len(s)))
+	b.write([]byte(s))
+}
+
+func (b *binWriter) float(v float64) {
+	if b.float32 {
+		b.uint32(math.Float32bits(float32(v)))
+	} else {
+		b.uint64(math.Float64bits(v))
+	}
+}
+
+// floats 按field顺序写出向量块，缺失的field写零向量
+func (b *binWriter) floats(blocks map[string][]float64, fieldNames []string, factorNum int) {
+	for _, field := range fieldNames {
+		v := blocks[field]
+		for f := 0; f < factorNum; f++ {
+			if v != nil {
+				b.float(v[f])
+			} else {
+				b.float(0)
+			}
+		}
+	}
+}
+
+// heads 写出其余任务的参数
+func (b *binWriter) heads(heads []TaskWeight) {
+	for _, h := range heads {
+		b.float(h.W)
+		b.float(h.N)
+		b.float(h.Z)
+	}
+}
+
+// outputBinModel 输出二进制模型
+func (m *FFMModel) outputBinModel(modelPath string) error {
+	return m.writeBinModel(modelPath, m.outputMeta())
+}
+
+// writeBinModel 按 meta 输出二进制模型，meta.NumberType 决定浮点宽度
+// 与文本模型一样通过临时文件原子写入，末尾附带特征数和校验和
+func (m *FFMModel) writeBinModel(modelPath string, meta *ModelMeta) error {
+	if m.MuBias == nil || len(m.FieldNames) == 0 {
+		return fmt.Errorf("no valid samples processed, cannot output model")
+	}
+
+	metaLine, err := meta.metaLine()
+	if err != nil {
+		return err
+	}
+
+	return atomicWriteFile(modelPath, func(w io.Writer) error {
+		crc := crc32.NewIEEE()
+		b := &binWriter{w: io.MultiWriter(w, crc), float32: meta.NumberType == "float"}
+
+		b.write([]byte(binMagic))
+		b.string(metaLine[len(metaPrefix)+1:])
+		if b.float32 {
+			b.write([]byte{4})
+		} else {
+			b.write([]byte{8})
+		}
+		b.uint32(uint32(len(m.FieldNames)))
+		for _, field := range m.FieldNames {
+			b.string(field)
+		}
+		b.float(m.MuBias.Wi)
+		b.float(m.MuBias.WNi)
+		b.float(m.MuBias.WZi)
+		b.heads(m.MuBias.Heads)
+
+		b.uint64(uint64(len(m.MuMap)))
+		for feature, unit := range m.MuMap {
+			unit.mu.RLock()
+			b.string(feature)
+			b.float(unit.Wi)
+			b.floats(unit.ViMap, m.FieldNames, m.FactorNum)
+			b.float(unit.WNi)
+			b.float(unit.WZi)
+			b.floats(unit.VNiMap, m.FieldNames, m.FactorNum)
+			b.floats(unit.VZiMap, m.FieldNames, m.FactorNum)
+			b.heads(unit.Heads)
+			unit.mu.RUnlock()
+		}
+		if b.err != nil {
+			return b.err
+		}
+
+		// 尾部不参与校验和
+		tail := &binWriter{w: w}
+		tail.write([]byte(binTrailer))
+		tail.uint64(uint64(len(m.MuMap)))
+		tail.uint32(crc.Sum32())
+		return tail.err
+	})
+}
+
+// binReader 二进制模型读取器，记录第一个读错误
+type binReader struct {
+	r       io.Reader
+	float32 bool
+	buf     [8]byte
+	err     error
+}
+
+func (b *binReader) read(p []byte) {
+	if b.err == nil {
+		if _, b.err = io.ReadFull(b.r, p); b.err == io.EOF || b.err == io.ErrUnexpectedEOF {
+			b.err = fmt.Errorf("binary model truncated")
+		}
+	}
+}
+
+func (b *binReader) uint32() uint32 {
+	b.read(b.buf[:4])
+	return binary.LittleEndian.Uint32(b.buf[:4])
+}
+
+func (b *binReader) uint64() uint64 {
+	b.read(b.buf[:8])
+	return binary.LittleEndian.Uint64(b.buf[:8])
+}
+
+func (b *binReader) string() string {
+	n := b.uint32()
+	if b.err != nil {
+		return ""
+	}
+	if n > maxModelLineSize {
+		b.err = fmt.Errorf("invalid string length %d in binary model", n)
+		return ""
+	}
+	p := make([]byte, n)
+	b.read(p)
+	return string(p)
+}
+
+func (b *binReader) float() float64 {
+	if b.float32 {
+		return float64(math.Float32frombits(b.uint32()))
+	}
+	return math.Float64frombits(b.uint64())
+}
+
+// floats 读取按field顺序排列的向量块
+func (b *binReader) floats(fieldNames []string, factorNum int) map[string][]float64 {
+	blocks := make(map[string][]float64, len(fieldNames))
+	for _, field := range fieldNames {
+		v := make([]float64, factorNum)
+		for f := 0; f < factorNum; f++ {
+			v[f] = b.float()
+		}
+		blocks[field] = v
+	}
+	return blocks
+}
+
+// heads 读取其余任务的参数，单任务模型返回 nil
+func (b *binReader) heads(tasks int) []TaskWeight {
+	if tasks < 2 {
+		return nil
+	}
+	heads := make([]TaskWeight, tasks-1)
+	for i := range heads {
+		heads[i].W = b.float()
+		heads[i].N = b.float()
+		heads[i].Z = b.float()
+	}
+	return heads
+}
+
+// decodeBinModel 解码二进制模型
+// onHeader 在读完头部后调用，返回要使用的隐向量维度；onUnit 对每个特征调用一次。
+// 尾部校验失败时返回错误，调用方应在成功返回后才使用解码结果
+func decodeBinModel(modelPath string,
+	onHeader func(meta *ModelMeta, fieldNames []string) (int, error),
+	onUnit func(feature string, unit *FFMModelUnit)) (*FFMModelUnit, error) {
+
+	file, err := os.Open(modelPath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	raw := bufio.NewReaderSize(file, 1<<20)
+	crc := crc32.NewIEEE()
+	b := &binReader{r: io.TeeReader(raw, crc)}
+
+	magic := make([]byte, len(binMagic))
+	b.read(magic)
+	if b.err != nil || string(magic) != binMagic {
+		return nil, fmt.Errorf("invalid binary model: bad magic")
+	}
+
+	meta, err := parseMetaLine(metaPrefix + " " + b.string())
+	if b.err != nil {
+		return nil, b.err
+	}
+	if err != nil {
+		return nil, err
+	}
+	tasks := numTasks(meta.Tasks)
+	width := make([]byte, 1)
+	b.read(width)
+	switch width[0] {
+	case 4:
+		b.float32 = true
+	case 8:
+	default:
+		return nil, fmt.Errorf("invalid binary model: unknown number width %d", width[0])
+	}
+
+	numFields := b.uint32()
+	if b.err == nil && numFields > 1<<20 {
+		return nil, fmt.Errorf("invalid binary model: %d fields", numFields)
+	}
+	fieldNames := make([]string, 0, numFields)
+	for i := uint32(0); i < numFields && b.err == nil; i++ {
+		fieldNames = append(fieldNames, b.string())
+	}
+	if b.err != nil {
+		return nil, b.err
+	}
+
+	factorNum, err := onHeader(meta, fieldNames)
+	if err != nil {
+		return nil, err
+	}
+
+	bias := NewFFMModelUnit(0, 0, 0)
+	bias.Wi = b.float()
+	bias.WNi = b.float()
+	bias.WZi = b.float()
+	bias.Heads = b.heads(tasks)
+
+	numFeatures := b.uint64()
+	for i := uint64(0); i < numFeatures && b.err == nil; i++ {
+		feature := b.string()
+		unit := NewFFMModelUnit(factorNum, 0, 0)
+		unit.Wi = b.float()
+		unit.ViMap = b.floats(fieldNames, factorNum)
+		unit.WNi = b.float()
+		unit.WZi = b.float()
+		unit.VNiMap = b.floats(fieldNames, factorNum)
+		unit.VZiMap = b.floats(fieldNames, factorNum)
+		unit.Heads = b.heads(tasks)
+		if b.err == nil {
+			onUnit(feature, unit)
+		}
+	}
+	if b.err != nil {
+		return nil, b.err
+	}
+
+	// 校验尾部
+	sum := crc.Sum32()
+	tail := &binReader{r: raw}
+	end := make([]byte, len(binTrailer))
+	tail.read(end)
+	count := tail.uint64()
+	expected := tail.uint32()
+	if tail.err != nil || string(end) != binTrailer {
+		return nil, fmt.Errorf("binary model has no trailer (truncated)")
+	}
+	if count != numFeatures {
+		return nil, fmt.Errorf("binary model trailer mismatch: trailer records %d features, read %d", count, numFeatures)
+	}
+	if expected != sum {
+		return nil, fmt.Errorf("binary model trailer mismatch: checksum %08x, computed %08x", expected, sum)
+	}
+	if _, err := raw.ReadByte(); err != io.EOF {
+		return nil, fmt.Errorf("unexpected data after binary model trailer")
+	}
+	return bias, nil
+}
+
+// loadBinModel 加载二进制模型
+func (m *FFMModel) loadBinModel(modelPath string) error {
+	var meta *ModelMeta
+	var fieldNames []string
+	var factorNum int
+	muMap := make(map[string]*FFMModelUnit)
+
+	bias, err := decodeBinModel(modelPath,
+		func(fileMeta *ModelMeta, names []string) (int, error) {
+			meta, fieldNames = fileMeta, names
+			var err error
+			factorNum, err = resolveFactorNum(meta, m.FactorNum)
+			return factorNum, err
+		},
+		func(feature string, unit *FFMModelUnit) {
+			muMap[feature] = unit
+		})
+	if err != nil {
+		return err
+	}
+
+	// 维度在加载成功后才写入模型，失败时保留调用方指定的值
+	m.FactorNum = factorNum
+	m.FieldNames = fieldNames
+	m.Tasks = metaTasks(meta)
+	m.MuBias = bias
+	for feature, unit := range muMap {
+		m.MuMap[feature] = unit
+	}
+	m.Meta = meta
+	atomic.StoreInt64(&m.samples, meta.SampleCount)
+	return nil
+}
+
+// loadBinModel 加载二进制模型（只保留非零特征的wi和vi）
+func (m *PredictModel) loadBinModel(modelPath string) error {
+	var meta *ModelMeta
+	var fieldNames []string
+	var factorNum int
+	muMap := make(map[string]*PredictModelUnit)
+
+	bias, err := decodeBinModel(modelPath,
+		func(fileMeta *ModelMeta, names []string) (int, error) {
+			meta, fieldNames = fileMeta, names
+			var err error
+			factorNum, err = resolveFactorNum(meta, m.FactorNum)
+			return factorNum, err
+		},
+		func(feature string, unit *FFMModelUnit) {
+			pu := &PredictModelUnit{Wi: unit.Wi, ViMap: unit.ViMap, HeadW: headWeights(unit.Heads)}
+			isNonZero := unit.IsNonZero()
+			for _, w := range pu.HeadW {
+				if w != 0.0 {
+					isNonZero = true
+				}
+			}
+			if isNonZero {
+				muMap[feature] = pu
+			}
+		})
+	if err != nil {
+		return err
+	}
+
+	// 维度在加载成功后才写入模型，失败时保留调用方指定的值
+	m.FactorNum = factorNum
+	m.FieldNames = fieldNames
+	m.Tasks = metaTasks(meta)
+	m.MuBias = &PredictModelUnit{Wi: bias.Wi, ViMap: make(map[string][]float64), HeadW: headWeights(bias.Heads)}
+	for feature, unit := range muMap {
+		m.MuMap[feature] = unit
+	}
+	m.Meta = meta
+	return nil
+}
@@ -0,0 +1,193 @@
+package model
+
+import (
+	"bufio"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// trailerPrefix 模型文件尾行前缀
+// 尾行格式: #TRAILER lines=<尾行之前的行数> crc32=<尾行之前所有字节的CRC32>
+const trailerPrefix = "#TRAILER"
+
+// maxModelLineSize 模型文件单行最大长度（field数 × 隐向量维度较大时一行会很长）
+const maxModelLineSize = 256 * 1024 * 1024
+
+// atomicWriteFile 原子地写文件
+// 先写入同目录下的临时文件，检查所有写错误、fsync 后再重命名为目标文件，
+// 任何一步失败都会删除临时文件，目标文件要么是旧内容要么是完整的新内容
+func atomicWriteFile(path string, write func(w io.Writer) error) (err error) {
+	dir, base := filepath.Split(path)
+	if dir == "" {
+		dir = "."
+	}
+	file, err := os.CreateTemp(dir, base+".tmp*")
+	if err != nil {
+		return err
+	}
+	tmpPath := file.Name()
+	defer func() {
+		if err != nil {
+			file.Close()
+			os.Remove(tmpPath)
+		}
+	}()
+
+	writer := bufio.NewWriterSize(file, 1<<20)
+	if err = write(writer); err != nil {
+		return err
+	}
+	if err = writer.Flush(); err != nil {
+		return fmt.Errorf("failed to flush %s: %v", tmpPath, err)
+	}
+	if err = file.Sync(); err != nil {
+		return fmt.Errorf("failed to sync %s: %v", tmpPath, err)
+	}
+	if err = file.Close(); err != nil {
+		return fmt.Errorf("failed to close %s: %v", tmpPath, err)
+	}
+	if err = os.Chmod(tmpPath, 0644); err != nil {
+		return err
+	}
+	if err = os.Rename(tmpPath, path); err != nil {
+		return err
+	}
+
+	// 同步目录，保证重命名落盘；部分文件系统不支持，忽略错误
+	if d, derr := os.Open(dir); derr == nil {
+		d.Sync()
+		d.Close()
+	}
+	return nil
+}
+
+// trailerWriter 统计写入的行数和校验和，用于生成尾行
+type trailerWriter struct {
+	w     io.Writer
+	crc   hash.Hash32
+	lines int64
+}
+
+// newTrailerWriter 创建尾行统计写入器
+func newTrailerWriter(w io.Writer) *trailerWriter {
+	return &trailerWriter{w: w, crc: crc32.NewIEEE()}
+}
+
+// Write 写入数据并更新统计
+func (t *trailerWriter) Write(p []byte) (int, error) {
+	n, err := t.w.Write(p)
+	t.crc.Write(p[:n])
+	for _, b := range p[:n] {
+		if b == '\n' {
+			t.lines++
+		}
+	}
+	return n, err
+}
+
+// WriteTrailer 写入尾行（必须是最后一次写入）
+func (t *trailerWriter) WriteTrailer() error {
+	_, err := fmt.Fprintf(t.w, "%s lines=%d crc32=%08x\n", trailerPrefix, t.lines, t.crc.Sum32())
+	return err
+}
+
+// modelLineReader 逐行读取文本模型并校验尾行
+// 读到尾行时校验行数和校验和，尾行缺失、不匹配或尾行后还有数据都会通过 Err 返回错误
+// 格式版本1的旧模型（没有 META 头）没有尾行，读到文件末尾时打印警告后正常结束
+type modelLineReader struct {
+	scanner *bufio.Scanner
+	crc     hash.Hash32
+	lines   int64
+	line    string
+	err     error
+	legacy  bool // 格式版本1的模型，不要求尾行
+}
+
+// newModelLineReader 创建模型行读取器
+func newModelLineReader(r io.Reader) *modelLineReader {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxModelLineSize)
+	return &modelLineReader{scanner: scanner, crc: crc32.NewIEEE()}
+}
+
+// Scan 读取下一行数据，遇到尾行或出错时返回 false
+func (r *modelLineReader) Scan() bool {
+	if r.err != nil {
+		return false
+	}
+	if !r.scanner.Scan() {
+		if err := r.scanner.Err(); err != nil {
+			r.err = err
+		} else if r.legacy {
+			fmt.Printf("Warning: model file has no META header and no trailer, loading it as a version 1 model without integrity check\n")
+			r.err = io.EOF
+		} else {
+			r.err = fmt.Errorf("model file has no trailer after %d lines (truncated)", r.lines)
+		}
+		return false
+	}
+
+	line := r.scanner.Text()
+	if strings.HasPrefix(line, trailerPrefix) {
+		r.err = r.checkTrailer(line)
+		if r.err == nil && r.scanner.Scan() {
+			r.err = fmt.Errorf("unexpected data after model trailer")
+		}
+		if r.err == nil {
+			r.err = io.EOF
+		}
+		return false
+	}
+
+	r.crc.Write([]byte(line))
+	r.crc.Write([]byte{'\n'})
+	r.lines++
+	r.line = line
+	return true
+}
+
+// checkTrailer 校验尾行
+func (r *modelLineReader) checkTrailer(line string) error {
+	var lines int64 = -1
+	var sum string
+	for _, kv := range strings.Fields(line)[1:] {
+		if v := strings.TrimPrefix(kv, "lines="); v != kv {
+			n, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid model trailer: %s", line)
+			}
+			lines = n
+		} else if v := strings.TrimPrefix(kv, "crc32="); v != kv {
+			sum = v
+		}
+	}
+	if lines < 0 || sum == "" {
+		return fmt.Errorf("invalid model trailer: %s", line)
+	}
+	if lines != r.lines {
+		return fmt.Errorf("model trailer mismatch: trailer records %d lines, read %d", lines, r.lines)
+	}
+	if actual := fmt.Sprintf("%08x", r.crc.Sum32()); actual != sum {
+		return fmt.Errorf("model trailer mismatch: checksum %s, computed %s", sum, actual)
+	}
+	return nil
+}
+
+// Text 返回当前行
+func (r *modelLineReader) Text() string {
+	return r.line
+}
+
+// Err 返回读取错误，正常读到匹配的尾行时返回 nil
+func (r *modelLineReader) Err() error {
+	if r.err == io.EOF {
+		return nil
+	}
+	return r.err
+}
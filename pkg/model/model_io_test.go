@@ -0,0 +1,241 @@
+package model
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newTestModel 构造一个包含两个field、两个特征的小模型
+func newTestModel() *FFMModel {
+	m := NewFFMModel(2, 0.0, 0.1)
+	m.GetOrInitModelUnitBias().Wi = 0.5
+	m.RegisterField("user")
+	m.RegisterField("item")
+
+	u := m.GetOrInitModelUnit("u1")
+	u.Wi = 0.25
+	u.ViMap["item"] = []float64{0.1, -0.2}
+	u.VNiMap["item"] = []float64{1, 2}
+	u.VZiMap["item"] = []float64{-0.5, 0.5}
+
+	i := m.GetOrInitModelUnit("i1")
+	i.Wi = -0.125
+	i.ViMap["user"] = []float64{0.3, 0.4}
+	i.VNiMap["user"] = []float64{3, 4}
+	i.VZiMap["user"] = []float64{0.5, -0.5}
+	return m
+}
+
+func TestTxtModelRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "model.txt")
+	if err := newTestModel().OutputModel(path, "txt"); err != nil {
+		t.Fatalf("OutputModel failed: %v", err)
+	}
+
+	loaded := NewFFMModel(2, 0.0, 0.1)
+	if err := loaded.LoadModel(path, "txt"); err != nil {
+		t.Fatalf("LoadModel failed: %v", err)
+	}
+	if loaded.MuBias.Wi != 0.5 {
+		t.Errorf("bias: got %v, want 0.5", loaded.MuBias.Wi)
+	}
+	if got := loaded.MuMap["u1"].ViMap["item"][1]; got != -0.2 {
+		t.Errorf("u1 vi[item][1]: got %v, want -0.2", got)
+	}
+	if got := loaded.MuMap["i1"].VZiMap["user"][0]; got != 0.5 {
+		t.Errorf("i1 vzi[user][0]: got %v, want 0.5", got)
+	}
+
+	predict := NewPredictModel(2)
+	if err := predict.LoadModel(path, "txt"); err != nil {
+		t.Fatalf("PredictModel.LoadModel failed: %v", err)
+	}
+	if len(predict.MuMap) != 2 {
+		t.Errorf("predict model features: got %d, want 2", len(predict.MuMap))
+	}
+
+	// 临时文件不应残留
+	entries, _ := os.ReadDir(filepath.Dir(path))
+	if len(entries) != 1 {
+		t.Errorf("unexpected files left in model dir: %d", len(entries))
+	}
+}
+
+func TestTxtModelRejectsCorruption(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "model.txt")
+	if err := newTestModel().OutputModel(path, "txt"); err != nil {
+		t.Fatalf("OutputModel failed: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.SplitAfter(string(data), "\n")
+
+	cases := map[string]string{
+		// 截断：丢掉最后一个特征行和尾行
+		"truncated": strings.Join(lines[:len(lines)-3], ""),
+		// 篡改：修改 bias 的值
+		"modified": strings.Replace(string(data), "bias 0.5", "bias 0.6", 1),
+		// 尾行之后还有数据
+		"trailing": string(data) + "x 1\n",
+	}
+	for name, content := range cases {
+		bad := filepath.Join(dir, name+".txt")
+		if err := os.WriteFile(bad, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := NewFFMModel(2, 0.0, 0.1).LoadModel(bad, "txt"); err == nil {
+			t.Errorf("%s: FFMModel.LoadModel should fail", name)
+		}
+		if err := NewPredictModel(2).LoadModel(bad, "txt"); err == nil {
+			t.Errorf("%s: PredictModel.LoadModel should fail", name)
+		}
+	}
+}
+
+func TestTxtModelMetaHeader(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "model.txt")
+	m := newTestModel()
+	m.AddSamples(42)
+	if err := m.OutputModel(path, "txt"); err != nil {
+		t.Fatalf("OutputModel failed: %v", err)
+	}
+
+	// 未指定维度时从模型头读取
+	predict := NewPredictModel(0)
+	if err := predict.LoadModel(path, "txt"); err != nil {
+		t.Fatalf("LoadModel failed: %v", err)
+	}
+	if predict.FactorNum != 2 {
+		t.Errorf("factor num: got %d, want 2", predict.FactorNum)
+	}
+	if predict.Meta == nil || predict.Meta.SampleCount != 42 || predict.Meta.FormatVersion != ModelFormatVersion {
+		t.Errorf("unexpected meta: %+v", predict.Meta)
+	}
+
+	// 维度不匹配时提前报错，模型保留调用方指定的维度
+	mismatch := NewPredictModel(4)
+	if err := mismatch.LoadModel(path, "txt"); err == nil || !strings.Contains(err.Error(), "mismatch") {
+		t.Errorf("expected factor num mismatch error, got %v", err)
+	}
+	if mismatch.FactorNum != 4 {
+		t.Errorf("factor num after mismatch: got %d, want 4", mismatch.FactorNum)
+	}
+	mismatchTrain := NewFFMModel(4, 0.0, 0.1)
+	if err := mismatchTrain.LoadModel(path, "txt"); err == nil {
+		t.Errorf("expected factor num mismatch error for FFMModel")
+	}
+	if mismatchTrain.FactorNum != 4 {
+		t.Errorf("FFMModel factor num after mismatch: got %d, want 4", mismatchTrain.FactorNum)
+	}
+
+	// 样本数在重新加载后保留
+	loaded := NewFFMModel(2, 0.0, 0.1)
+	if err := loaded.LoadModel(path, "txt"); err != nil {
+		t.Fatalf("LoadModel failed: %v", err)
+	}
+	if loaded.SampleCount() != 42 {
+		t.Errorf("sample count: got %d, want 42", loaded.SampleCount())
+	}
+}
+
+func TestBinModelRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "model.bin")
+	m := newTestModel()
+	m.AddSamples(7)
+	if err := m.OutputModel(path, "bin"); err != nil {
+		t.Fatalf("OutputModel failed: %v", err)
+	}
+
+	loaded := NewFFMModel(0, 0.0, 0.1)
+	if err := loaded.LoadModel(path, "bin"); err != nil {
+		t.Fatalf("LoadModel failed: %v", err)
+	}
+	if loaded.FactorNum != 2 || loaded.SampleCount() != 7 {
+		t.Errorf("unexpected header: factor num %d, samples %d", loaded.FactorNum, loaded.SampleCount())
+	}
+	if got := loaded.MuMap["u1"].ViMap["item"][1]; got != -0.2 {
+		t.Errorf("u1 vi[item][1]: got %v, want -0.2", got)
+	}
+	if got := loaded.MuMap["i1"].VNiMap["user"][1]; got != 4 {
+		t.Errorf("i1 vni[user][1]: got %v, want 4", got)
+	}
+
+	predict := NewPredictModel(0)
+	if err := predict.LoadModel(path, "bin"); err != nil {
+		t.Fatalf("PredictModel.LoadModel failed: %v", err)
+	}
+	if predict.MuBias.Wi != 0.5 || len(predict.MuMap) != 2 {
+		t.Errorf("unexpected predict model: bias %v, %d features", predict.MuBias.Wi, len(predict.MuMap))
+	}
+
+	// 截断的二进制模型应被拒绝
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bad := filepath.Join(dir, "bad.bin")
+	if err := os.WriteFile(bad, data[:len(data)-5], 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := NewPredictModel(0).LoadModel(bad, "bin"); err == nil {
+		t.Errorf("truncated binary model should fail to load")
+	}
+	// 加载失败时保留调用方指定的维度
+	truncated := NewPredictModel(0)
+	truncated.LoadModel(bad, "bin")
+	truncatedTrain := NewFFMModel(0, 0.0, 0.1)
+	truncatedTrain.LoadModel(bad, "bin")
+	if truncated.FactorNum != 0 || truncatedTrain.FactorNum != 0 {
+		t.Errorf("factor num after failed load: %d, %d, want 0", truncated.FactorNum, truncatedTrain.FactorNum)
+	}
+}
+
+func TestTxtModelLegacyFormat(t *testing.T) {
+	// 格式版本1的模型: 没有 META 头和尾行，隐向量维度由调用方指定
+	legacy := "FIELDS user item\n" +
+		"bias 0.5 1 2\n" +
+		"u1 0.25 0 0 0.1 -0.2 3 4 0 0 1 2 0 0 -0.5 0.5\n" +
+		"i1 -0.125 0.3 0.4 0 0 3 4 3 4 0 0 0.5 -0.5 0 0\n"
+	path := filepath.Join(t.TempDir(), "legacy.txt")
+	if err := os.WriteFile(path, []byte(legacy), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded := NewFFMModel(2, 0.0, 0.1)
+	if err := loaded.LoadModel(path, "txt"); err != nil {
+		t.Fatalf("LoadModel failed: %v", err)
+	}
+	if loaded.Meta != nil || loaded.MuBias.WZi != 2 || len(loaded.MuMap) != 2 {
+		t.Errorf("unexpected legacy model: meta %+v, bias %+v, %d features", loaded.Meta, loaded.MuBias, len(loaded.MuMap))
+	}
+	if got := loaded.MuMap["u1"].ViMap["item"][1]; got != -0.2 {
+		t.Errorf("u1 vi[item][1]: got %v, want -0.2", got)
+	}
+
+	predict := NewPredictModel(2)
+	if err := predict.LoadModel(path, "txt"); err != nil {
+		t.Fatalf("PredictModel.LoadModel failed: %v", err)
+	}
+	if len(predict.MuMap) != 2 {
+		t.Errorf("predict model features: got %d, want 2", len(predict.MuMap))
+	}
+	// 旧模型没有模型头，必须指定维度
+	if err := NewPredictModel(0).LoadModel(path, "txt"); err == nil {
+		t.Error("expected error for legacy model without factor num")
+	}
+
+	// 重新输出后为当前格式
+	upgraded := filepath.Join(t.TempDir(), "model.txt")
+	if err := loaded.OutputModel(upgraded, "txt"); err != nil {
+		t.Fatalf("OutputModel failed: %v", err)
+	}
+	if err := NewPredictModel(0).LoadModel(upgraded, "txt"); err != nil {
+		t.Errorf("reload upgraded model: %v", err)
+	}
+}
@@ -0,0 +1,297 @@
+package model
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// FieldDrift 单个field向量块的漂移
+type FieldDrift struct {
+	Field   string
+	L2      float64 // 所有特征在该field上的隐向量差的L2范数
+	OldNorm float64 // 旧模型该field所有隐向量的L2范数
+}
+
+// Relative 相对漂移（相对旧模型范数）
+func (d FieldDrift) Relative() float64 {
+	if d.OldNorm == 0 {
+		return math.Inf(1)
+	}
+	return d.L2 / d.OldNorm
+}
+
+// FeatureChange 单个特征的变化量
+type FeatureChange struct {
+	Feature string
+	WiDelta float64 // wi的变化
+	L2      float64 // wi和全部隐向量变化的L2范数
+}
+
+// ModelDiff 两个模型的差异
+type ModelDiff struct {
+	AddedFeatures   []string
+	RemovedFeatures []string
+	AddedFields     []string
+	RemovedFields   []string
+	CommonFeatures  int
+	BiasDelta       float64
+	WiL2            float64 // 所有特征wi差的L2范数（缺失特征视为0）
+	FieldDrifts     []FieldDrift
+	TopChanged      []FeatureChange // 共同特征中变化最大的topN个
+}
+
+// blockDiff 计算两个向量差的平方和，缺失的向量视为零向量
+func blockDiff(a, b []float64, factorNum int) float64 {
+	sum := 0.0
+	for f := 0; f < factorNum; f++ {
+		var x, y float64
+		if a != nil {
+			x = a[f]
+		}
+		if b != nil {
+			y = b[f]
+		}
+		sum += (x - y) * (x - y)
+	}
+	return sum
+}
+
+// DiffModels 比较两个模型
+// 对新旧模型的特征并集逐field计算隐向量漂移，缺失的特征或向量块视为零
+func DiffModels(oldModel, newModel *FFMModel, topN int) (*ModelDiff, error) {
+	if oldModel.FactorNum != newModel.FactorNum {
+		return nil, fmt.Errorf("factor num mismatch: %d vs %d", oldModel.FactorNum, newModel.FactorNum)
+	}
+	// 漂移只覆盖第一个任务的一阶权重，多任务模型其余任务的变化会被漏掉
+	if oldModel.NumTasks() > 1 || newModel.NumTasks() > 1 {
+		return nil, errMultiTask("diff")
+	}
+	k := oldModel.FactorNum
+	diff := &ModelDiff{}
+
+	oldFields := make(map[string]bool)
+	for _, f := range oldModel.FieldNames {
+		oldFields[f] = true
+	}
+	newFields := make(map[string]bool)
+	for _, f := range newModel.FieldNames {
+		newFields[f] = true
+		if !oldFields[f] {
+			diff.AddedFields = append(diff.AddedFields, f)
+		}
+	}
+	for _, f := range oldModel.FieldNames {
+		if !newFields[f] {
+			diff.RemovedFields = append(diff.RemovedFields, f)
+		}
+	}
+
+	if oldModel.MuBias != nil && newModel.MuBias != nil {
+		diff.BiasDelta = newModel.MuBias.Wi - oldModel.MuBias.Wi
+	}
+
+	// 按field累计漂移
+	fieldOrder := append(append([]string{}, oldModel.FieldNames...), diff.AddedFields...)
+	drift := make(map[string]*FieldDrift, len(fieldOrder))
+	for _, f := range fieldOrder {
+		drift[f] = &FieldDrift{Field: f}
+	}
+
+	wiSqr := 0.0
+	var changes []FeatureChange
+	for feature, ou := range oldModel.MuMap {
+		nu, ok := newModel.MuMap[feature]
+		if !ok {
+			diff.RemovedFeatures = append(diff.RemovedFeatures, feature)
+			wiSqr += ou.Wi * ou.Wi
+			for field, vi := range ou.ViMap {
+				if d, ok := drift[field]; ok {
+					s := blockDiff(vi, nil, k)
+					d.L2 += s
+					d.OldNorm += s
+				}
+			}
+			continue
+		}
+
+		diff.CommonFeatures++
+		wiDelta := nu.Wi - ou.Wi
+		featureSqr := wiDelta * wiDelta
+		wiSqr += wiDelta * wiDelta
+		for _, field := range fieldOrder {
+			ov, nv := ou.ViMap[field], nu.ViMap[field]
+			if ov == nil && nv == nil {
+				continue
+			}
+			s := blockDiff(ov, nv, k)
+			featureSqr += s
+			drift[field].L2 += s
+			drift[field].OldNorm += blockDiff(ov, nil, k)
+		}
+		changes = append(changes, FeatureChange{Feature: feature, WiDelta: wiDelta, L2: math.Sqrt(featureSqr)})
+	}
+	for feature, nu := range newModel.MuMap {
+		if _, ok := oldModel.MuMap[feature]; ok {
+			continue
+		}
+		diff.AddedFeatures = append(diff.AddedFeatures, feature)
+		wiSqr += nu.Wi * nu.Wi
+		for field, vi := range nu.ViMap {
+			if d, ok := drift[field]; ok {
+				d.L2 += blockDiff(vi, nil, k)
+			}
+		}
+	}
+
+	diff.WiL2 = math.Sqrt(wiSqr)
+	for _, f := range fieldOrder {
+		d := drift[f]
+		d.L2 = math.Sqrt(d.L2)
+		d.OldNorm = math.Sqrt(d.OldNorm)
+		diff.FieldDrifts = append(diff.FieldDrifts, *d)
+	}
+	sort.Strings(diff.AddedFeatures)
+	sort.Strings(diff.RemovedFeatures)
+
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].L2 != changes[j].L2 {
+			return changes[i].L2 > changes[j].L2
+		}
+		return changes[i].Feature < changes[j].Feature
+	})
+	if topN >= 0 && len(changes) > topN {
+		changes = changes[:topN]
+	}
+	diff.TopChanged = changes
+	return diff, nil
+}
+
+// 合并操作
+const (
+	MergeAvg = "avg" // 在包含该参数的模型间求平均
+	MergeSum = "sum" // 求和
+)
+
+// accumulate 将 src 累加到 dst（dst 为 nil 时创建）
+func accumulate(dst, src []float64) []float64 {
+	if dst == nil {
+		dst = make([]float64, len(src))
+	}
+	for i := range src {
+		dst[i] += src[i]
+	}
+	return dst
+}
+
+// allZero 向量是否全为0（或为空）
+func allZero(vs ...[]float64) bool {
+	for _, v := range vs {
+		for _, x := range v {
+			if x != 0 {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// scale 向量数乘
+func scale(v []float64, s float64) {
+	for i := range v {
+		v[i] *= s
+	}
+}
+
+// MergeModels 合并多个模型（包括FTRL的n/z状态）
+// avg: 每个参数在包含它的模型之间求平均，某个分片没有见过的特征或field不会把参数拉向0；
+// 从文件加载的模型对 FIELDS 中的每个field都有向量块（没训练过的为零向量），vi、v_n、v_z 全为0的块视为该分片没有；
+// sum: 每个参数求和。
+// 合并后的field顺序为各模型field的并集（按首次出现顺序），样本数为各模型之和
+func MergeModels(models []*FFMModel, op string) (*FFMModel, error) {
+	if len(models) == 0 {
+		return nil, fmt.Errorf("no models to merge")
+	}
+	if op != MergeAvg && op != MergeSum {
+		return nil, fmt.Errorf("unsupported merge op: %s (must be avg or sum)", op)
+	}
+	first := models[0]
+	for i, m := range models {
+		if m.FactorNum != first.FactorNum {
+			return nil, fmt.Errorf("factor num mismatch: model 0 has %d, model %d has %d", first.FactorNum, i, m.FactorNum)
+		}
+		if m.MuBias == nil {
+			return nil, fmt.Errorf("model %d has no bias", i)
+		}
+		if m.NumTasks() > 1 {
+			return nil, fmt.Errorf("model %d: %v", i, errMultiTask("merge"))
+		}
+	}
+
+	merged := NewFFMModel(first.FactorNum, first.InitMean, first.InitStdev)
+	bias := merged.GetOrInitModelUnitBias()
+	featureCount := make(map[string]int)
+	blockCount := make(map[string]map[string]int)
+	var samples int64
+
+	for _, m := range models {
+		for _, field := range m.FieldNames {
+			merged.RegisterField(field)
+		}
+		bias.Wi += m.MuBias.Wi
+		bias.WNi += m.MuBias.WNi
+		bias.WZi += m.MuBias.WZi
+		samples += m.SampleCount()
+
+		for feature, src := range m.MuMap {
+			dst := merged.GetOrInitModelUnit(feature)
+			featureCount[feature]++
+			dst.Wi += src.Wi
+			dst.WNi += src.WNi
+			dst.WZi += src.WZi
+
+			counts := blockCount[feature]
+			if counts == nil {
+				counts = make(map[string]int)
+				blockCount[feature] = counts
+			}
+			for field, vi := range src.ViMap {
+				if allZero(vi, src.VNiMap[field], src.VZiMap[field]) {
+					continue
+				}
+				counts[field]++
+				dst.ViMap[field] = accumulate(dst.ViMap[field], vi)
+				dst.VNiMap[field] = accumulate(dst.VNiMap[field], src.VNiMap[field])
+				dst.VZiMap[field] = accumulate(dst.VZiMap[field], src.VZiMap[field])
+			}
+		}
+	}
+
+	if op == MergeAvg {
+		n := float64(len(models))
+		bias.Wi /= n
+		bias.WNi /= n
+		bias.WZi /= n
+		for feature, unit := range merged.MuMap {
+			c := float64(featureCount[feature])
+			unit.Wi /= c
+			unit.WNi /= c
+			unit.WZi /= c
+			for field, cnt := range blockCount[feature] {
+				s := 1.0 / float64(cnt)
+				scale(unit.ViMap[field], s)
+				scale(unit.VNiMap[field], s)
+				scale(unit.VZiMap[field], s)
+			}
+		}
+	}
+
+	merged.AddSamples(samples)
+	if first.Meta != nil {
+		meta := *first.Meta
+		meta.TrainedAt = time.Now().Format(time.RFC3339)
+		merged.Meta = &meta
+	}
+	return merged, nil
+}
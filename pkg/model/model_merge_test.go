@@ -0,0 +1,107 @@
+package model
+
+import (
+	"fmt"
+	"math"
+	"path/filepath"
+	"testing"
+)
+
+func TestMergeModelsAvg(t *testing.T) {
+	a := newTestModel()
+	a.AddSamples(10)
+	b := newTestModel()
+	b.AddSamples(20)
+	b.MuBias.Wi = 1.5
+	b.MuMap["u1"].Wi = 0.75
+	b.MuMap["u1"].VNiMap["item"] = []float64{3, 4}
+	// 只在b中出现的特征不应被平均到一半
+	extra := b.GetOrInitModelUnit("u2")
+	extra.Wi = 1.0
+
+	merged, err := MergeModels([]*FFMModel{a, b}, MergeAvg)
+	if err != nil {
+		t.Fatalf("MergeModels failed: %v", err)
+	}
+	if merged.MuBias.Wi != 1.0 {
+		t.Errorf("bias: got %v, want 1.0", merged.MuBias.Wi)
+	}
+	if got := merged.MuMap["u1"].Wi; got != 0.5 {
+		t.Errorf("u1 wi: got %v, want 0.5", got)
+	}
+	if got := merged.MuMap["u1"].VNiMap["item"]; got[0] != 2 || got[1] != 3 {
+		t.Errorf("u1 vni[item]: got %v, want [2 3]", got)
+	}
+	if got := merged.MuMap["u2"].Wi; got != 1.0 {
+		t.Errorf("u2 wi: got %v, want 1.0", got)
+	}
+	if merged.SampleCount() != 30 {
+		t.Errorf("sample count: got %d, want 30", merged.SampleCount())
+	}
+	if _, err := MergeModels([]*FFMModel{a, NewFFMModel(4, 0, 0)}, MergeAvg); err == nil {
+		t.Errorf("merging models with different factor num should fail")
+	}
+}
+
+func TestMergeLoadedModelsAvg(t *testing.T) {
+	// b 中的 u1 没有 item 块；写出再加载后该块为零向量，不应把 a 的参数平均到一半
+	b := newTestModel()
+	delete(b.MuMap["u1"].ViMap, "item")
+	delete(b.MuMap["u1"].VNiMap, "item")
+	delete(b.MuMap["u1"].VZiMap, "item")
+
+	dir := t.TempDir()
+	var models []*FFMModel
+	for i, m := range []*FFMModel{newTestModel(), b} {
+		path := filepath.Join(dir, fmt.Sprintf("shard%d.txt", i))
+		if err := m.OutputModel(path, "txt"); err != nil {
+			t.Fatalf("OutputModel failed: %v", err)
+		}
+		loaded := NewFFMModel(0, 0.0, 0.1)
+		if err := loaded.LoadModel(path, "txt"); err != nil {
+			t.Fatalf("LoadModel failed: %v", err)
+		}
+		models = append(models, loaded)
+	}
+	if _, ok := models[1].MuMap["u1"].ViMap["item"]; !ok {
+		t.Fatal("loaded model should contain a zero item block for u1")
+	}
+
+	merged, err := MergeModels(models, MergeAvg)
+	if err != nil {
+		t.Fatalf("MergeModels failed: %v", err)
+	}
+	if got := merged.MuMap["u1"].ViMap["item"]; got[0] != 0.1 || got[1] != -0.2 {
+		t.Errorf("u1 vi[item]: got %v, want [0.1 -0.2]", got)
+	}
+	if got := merged.MuMap["u1"].VNiMap["item"]; got[0] != 1 || got[1] != 2 {
+		t.Errorf("u1 vni[item]: got %v, want [1 2]", got)
+	}
+}
+
+func TestDiffModels(t *testing.T) {
+	a := newTestModel()
+	b := newTestModel()
+	b.MuMap["u1"].ViMap["item"] = []float64{0.4, 0.2} // 差为 (0.3, 0.4)，L2=0.5
+	delete(b.MuMap, "i1")
+	b.GetOrInitModelUnit("i2").Wi = 1.0
+
+	diff, err := DiffModels(a, b, 10)
+	if err != nil {
+		t.Fatalf("DiffModels failed: %v", err)
+	}
+	if len(diff.AddedFeatures) != 1 || diff.AddedFeatures[0] != "i2" {
+		t.Errorf("added features: got %v", diff.AddedFeatures)
+	}
+	if len(diff.RemovedFeatures) != 1 || diff.RemovedFeatures[0] != "i1" {
+		t.Errorf("removed features: got %v", diff.RemovedFeatures)
+	}
+	for _, d := range diff.FieldDrifts {
+		if d.Field == "item" && math.Abs(d.L2-0.5) > 1e-9 {
+			t.Errorf("item drift: got %v, want 0.5", d.L2)
+		}
+	}
+	if len(diff.TopChanged) != 1 || diff.TopChanged[0].Feature != "u1" {
+		t.Errorf("top changed: got %+v", diff.TopChanged)
+	}
+}
@@ -0,0 +1,128 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ModelFormatVersion 当前模型文件格式版本
+// 1: 只有 FIELDS 头；2: 增加 META 元信息头和尾行
+const ModelFormatVersion = 2
+
+// metaPrefix 文本模型元信息行前缀，格式: META <json>
+const metaPrefix = "META"
+
+// ModelMeta 模型元信息（超参数与来源）
+// 写在文本模型的第一行，预测时据此确定隐向量维度并提前发现不匹配
+type ModelMeta struct {
+	FormatVersion   int      `json:"format_version"`
+	FactorNum       int      `json:"factor_num"`
+	NumberType      string   `json:"number_type,omitempty"`
+	K0              bool     `json:"k0"`
+	K1              bool     `json:"k1"`
+	InitStdev       float64  `json:"init_stdev"`
+	WAlpha          float64  `json:"w_alpha"`
+	WBeta           float64  `json:"w_beta"`
+	WL1             float64  `json:"w_l1"`
+	WL2             float64  `json:"w_l2"`
+	VAlpha          float64  `json:"v_alpha"`
+	VBeta           float64  `json:"v_beta"`
+	VL1             float64  `json:"v_l1"`
+	VL2             float64  `json:"v_l2"`
+	ForceVSparse    bool     `json:"force_v_sparse"`
+	FieldConfigHash string   `json:"field_config_hash,omitempty"`
+	InputFormat     string   `json:"input_format,omitempty"`
+	Tasks           []string `json:"tasks,omitempty"` // 多任务模型的任务名，按样本标签顺序
+	SampleCount     int64    `json:"sample_count"`
+	TrainedAt       string   `json:"trained_at,omitempty"`
+}
+
+// NewModelMetaFromOption 根据训练选项创建模型元信息
+func NewModelMetaFromOption(opt *TrainerOption) *ModelMeta {
+	return &ModelMeta{
+		FormatVersion: ModelFormatVersion,
+		FactorNum:     opt.FactorNum,
+		NumberType:    opt.ModelNumberType,
+		K0:            opt.K0,
+		K1:            opt.K1,
+		InitStdev:     opt.InitStdev,
+		WAlpha:        opt.WAlpha,
+		WBeta:         opt.WBeta,
+		WL1:           opt.WL1,
+		WL2:           opt.WL2,
+		VAlpha:        opt.VAlpha,
+		VBeta:         opt.VBeta,
+		VL1:           opt.VL1,
+		VL2:           opt.VL2,
+		ForceVSparse:  opt.ForceVSparse,
+		InputFormat:   opt.InputFormat,
+		Tasks:         opt.Tasks,
+	}
+}
+
+// metaLine 生成元信息行（不含换行符）
+func (meta *ModelMeta) metaLine() (string, error) {
+	data, err := json.Marshal(meta)
+	if err != nil {
+		return "", err
+	}
+	return metaPrefix + " " + string(data), nil
+}
+
+// isMetaLine 判断是否是元信息行
+func isMetaLine(line string) bool {
+	return strings.HasPrefix(line, metaPrefix+" ")
+}
+
+// parseMetaLine 解析元信息行
+func parseMetaLine(line string) (*ModelMeta, error) {
+	meta := &ModelMeta{}
+	if err := json.Unmarshal([]byte(strings.TrimPrefix(line, metaPrefix+" ")), meta); err != nil {
+		return nil, fmt.Errorf("invalid model meta header: %v", err)
+	}
+	if meta.FormatVersion > ModelFormatVersion {
+		return nil, fmt.Errorf("model format version %d is newer than supported version %d", meta.FormatVersion, ModelFormatVersion)
+	}
+	if meta.FactorNum <= 0 {
+		return nil, fmt.Errorf("invalid factor num in model meta header: %d", meta.FactorNum)
+	}
+	return meta, nil
+}
+
+// resolveFactorNum 根据模型元信息确定隐向量维度
+// factorNum 为调用方指定的维度，0 表示由模型头决定
+func resolveFactorNum(meta *ModelMeta, factorNum int) (int, error) {
+	if meta == nil {
+		if factorNum <= 0 {
+			return 0, fmt.Errorf("model has no meta header, factor num (-dim) must be specified")
+		}
+		return factorNum, nil
+	}
+	if factorNum > 0 && factorNum != meta.FactorNum {
+		return 0, fmt.Errorf("factor num mismatch: model was trained with %d, but %d was specified", meta.FactorNum, factorNum)
+	}
+	return meta.FactorNum, nil
+}
+
+// outputMeta 生成输出用的元信息
+func (m *FFMModel) outputMeta() *ModelMeta {
+	return m.completeMeta(m.Meta)
+}
+
+// completeMeta 复制 base 并补全格式版本、维度、样本数等由模型决定的字段，base 可以为 nil
+func (m *FFMModel) completeMeta(base *ModelMeta) *ModelMeta {
+	meta := &ModelMeta{}
+	if base != nil {
+		*meta = *base
+	}
+	meta.FormatVersion = ModelFormatVersion
+	meta.FactorNum = m.FactorNum
+	meta.SampleCount = m.SampleCount()
+	meta.Tasks = m.Tasks
+	if meta.TrainedAt == "" {
+		meta.TrainedAt = time.Now().Format(time.RFC3339)
+	}
+	return meta
+}
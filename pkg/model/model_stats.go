@@ -0,0 +1,203 @@
+package model
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// histogramBounds 直方图分桶上界（按数量级划分），最后一个桶为 [1, +inf)
+var histogramBounds = []float64{0, 1e-4, 1e-3, 1e-2, 1e-1, 1}
+
+// Histogram 按数量级分桶的绝对值直方图
+// Counts[0] 统计精确为0的值，Counts[i] 统计 [bounds[i-1], bounds[i]) 内的值
+type Histogram struct {
+	Counts []int64
+}
+
+// NewHistogram 创建直方图
+func NewHistogram() *Histogram {
+	return &Histogram{Counts: make([]int64, len(histogramBounds)+1)}
+}
+
+// Add 加入一个值（取绝对值）
+func (h *Histogram) Add(v float64) {
+	v = math.Abs(v)
+	if v == 0 {
+		h.Counts[0]++
+		return
+	}
+	for i := 1; i < len(histogramBounds); i++ {
+		if v < histogramBounds[i] {
+			h.Counts[i]++
+			return
+		}
+	}
+	h.Counts[len(histogramBounds)]++
+}
+
+// Labels 返回每个桶的标签
+func (h *Histogram) Labels() []string {
+	labels := []string{"0"}
+	for i := 1; i < len(histogramBounds); i++ {
+		labels = append(labels, fmt.Sprintf("[%g,%g)", histogramBounds[i-1], histogramBounds[i]))
+	}
+	labels[1] = fmt.Sprintf("(0,%g)", histogramBounds[1])
+	return append(labels, fmt.Sprintf("[%g,+inf)", histogramBounds[len(histogramBounds)-1]))
+}
+
+// String 格式化输出
+func (h *Histogram) String() string {
+	labels := h.Labels()
+	parts := make([]string, 0, len(labels))
+	for i, label := range labels {
+		parts = append(parts, fmt.Sprintf("%s:%d", label, h.Counts[i]))
+	}
+	return strings.Join(parts, " ")
+}
+
+// FieldStats 单个field的向量块统计
+// 向量块指每个特征针对该field的隐向量 v_{i,f}
+type FieldStats struct {
+	Field         string
+	Blocks        int64      // 存在该field向量块的特征数
+	NonZeroBlocks int64      // 向量块非零的特征数
+	ValueHist     *Histogram // 向量块元素绝对值直方图
+	NormHist      *Histogram // 向量块L2范数直方图
+}
+
+// ModelStats 模型统计信息
+type ModelStats struct {
+	NumFeatures      int
+	NumFields        int
+	FactorNum        int
+	NonZeroWi        int64
+	NonZeroFeatures  int64      // wi或任意向量块非零的特征数
+	WiHist           *Histogram // wi绝对值直方图
+	Fields           []*FieldStats
+	TrainMemoryBytes int64 // 训练时（FFMModel，含n/z）的内存估算
+	PredictMemBytes  int64 // 预测时（PredictModel，只含非零特征的wi和vi）的内存估算
+}
+
+// 内存估算使用的经验常数（64位平台）
+const (
+	mapEntryOverhead = 48 // map中每个条目的平均开销（含哈希桶摊销）
+	sliceHeaderSize  = 24
+	stringHeaderSize = 16
+	unitOverhead     = 3*8 + 3*8 + 24 // FFMModelUnit 的 wi/n/z、三个map指针和锁
+)
+
+// Stats 统计模型信息
+func (m *FFMModel) Stats() *ModelStats {
+	stats := &ModelStats{
+		NumFeatures: len(m.MuMap),
+		NumFields:   len(m.FieldNames),
+		FactorNum:   m.FactorNum,
+		WiHist:      NewHistogram(),
+	}
+	fieldIndex := make(map[string]*FieldStats, len(m.FieldNames))
+	for _, field := range m.FieldNames {
+		fs := &FieldStats{Field: field, ValueHist: NewHistogram(), NormHist: NewHistogram()}
+		fieldIndex[field] = fs
+		stats.Fields = append(stats.Fields, fs)
+	}
+
+	blockBytes := int64(m.FactorNum*8 + sliceHeaderSize)
+	for feature, unit := range m.MuMap {
+		unit.mu.RLock()
+		stats.WiHist.Add(unit.Wi)
+		nonZero := unit.Wi != 0
+		if unit.Wi != 0 {
+			stats.NonZeroWi++
+		}
+		nameBytes := int64(len(feature) + stringHeaderSize)
+		stats.TrainMemoryBytes += nameBytes + mapEntryOverhead + unitOverhead
+		predictBytes := nameBytes + mapEntryOverhead + 8 + 8
+
+		for field, vi := range unit.ViMap {
+			fs, ok := fieldIndex[field]
+			if !ok {
+				continue
+			}
+			fs.Blocks++
+			norm := 0.0
+			for _, v := range vi {
+				fs.ValueHist.Add(v)
+				norm += v * v
+			}
+			if norm > 0 {
+				fs.NonZeroBlocks++
+				nonZero = true
+			}
+			fs.NormHist.Add(math.Sqrt(norm))
+			stats.TrainMemoryBytes += 3 * (blockBytes + mapEntryOverhead)
+			predictBytes += blockBytes + mapEntryOverhead
+		}
+		unit.mu.RUnlock()
+
+		if nonZero {
+			stats.NonZeroFeatures++
+			stats.PredictMemBytes += predictBytes
+		}
+	}
+	return stats
+}
+
+// FeatureDump 单个特征的全部参数
+type FeatureDump struct {
+	Feature string
+	Wi      float64
+	WNi     float64
+	WZi     float64
+	Fields  []FieldDump
+	Tasks   []TaskDump // 多任务模型中其余任务的一阶参数，单任务模型为 nil
+}
+
+// TaskDump 多任务模型中单个特征在某个任务上的一阶参数
+type TaskDump struct {
+	Task string
+	TaskWeight
+}
+
+// FieldDump 单个特征针对某个field的参数
+type FieldDump struct {
+	Field string
+	Vi    []float64
+	VNi   []float64
+	VZi   []float64
+}
+
+// DumpFeature 导出单个特征的参数，特征不存在时返回 nil
+func (m *FFMModel) DumpFeature(feature string) *FeatureDump {
+	var unit *FFMModelUnit
+	if feature == BiasFeatureName {
+		unit = m.MuBias
+	} else {
+		unit = m.MuMap[feature]
+	}
+	if unit == nil {
+		return nil
+	}
+
+	unit.mu.RLock()
+	defer unit.mu.RUnlock()
+	dump := &FeatureDump{Feature: feature, Wi: unit.Wi, WNi: unit.WNi, WZi: unit.WZi}
+	for i, h := range unit.Heads {
+		dump.Tasks = append(dump.Tasks, TaskDump{Task: m.Tasks[i+1], TaskWeight: h})
+	}
+	fields := make([]string, 0, len(unit.ViMap))
+	for field := range unit.ViMap {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	for _, field := range fields {
+		dump.Fields = append(dump.Fields, FieldDump{
+			Field: field,
+			Vi:    unit.ViMap[field],
+			VNi:   unit.VNiMap[field],
+			VZi:   unit.VZiMap[field],
+		})
+	}
+	return dump
+}
@@ -0,0 +1,175 @@
+package model
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/xiongle/alphaFFM-go/pkg/sample"
+	"github.com/xiongle/alphaFFM-go/pkg/simd"
+)
+
+// TaskWeight 多任务模型中一个任务的一阶权重及其FTRL参数
+// 第一个任务使用 FFMModelUnit 的 Wi/WNi/WZi，其余任务各有一个 TaskWeight，隐向量由所有任务共享
+type TaskWeight struct {
+	W float64 // 一阶权重
+	N float64 // w的n参数
+	Z float64 // w的z参数
+}
+
+// numTasks 任务数，少于2个任务名时为单任务
+func numTasks(tasks []string) int {
+	if len(tasks) < 2 {
+		return 1
+	}
+	return len(tasks)
+}
+
+// ParseTasks 解析逗号分隔的任务名，空串表示单任务；任务名不能为空或重复
+func ParseTasks(s string) ([]string, error) {
+	if s == "" {
+		return nil, nil
+	}
+	tasks := strings.Split(s, ",")
+	seen := make(map[string]bool, len(tasks))
+	for _, task := range tasks {
+		if task == "" || strings.ContainsAny(task, " \t") {
+			return nil, fmt.Errorf("invalid task name %q", task)
+		}
+		if seen[task] {
+			return nil, fmt.Errorf("duplicate task name %q", task)
+		}
+		seen[task] = true
+	}
+	return tasks, nil
+}
+
+// CheckTaskInput 检查多任务训练的输入格式：多任务标签只支持 ffm 和 libffm 输入
+func CheckTaskInput(tasks []string, inputFormat string) error {
+	if numTasks(tasks) == 1 {
+		return nil
+	}
+	if inputFormat != "" && inputFormat != sample.InputFormatFFM && inputFormat != sample.InputFormatLibFFM {
+		return fmt.Errorf("multi-task training only supports ffm and libffm input, got %s", inputFormat)
+	}
+	return nil
+}
+
+// metaTasks 模型头中记录的任务名，旧模型没有元信息时为 nil
+func metaTasks(meta *ModelMeta) []string {
+	if meta == nil {
+		return nil
+	}
+	return meta.Tasks
+}
+
+// NumTasks 模型的任务数，单任务模型为 1
+func (m *FFMModel) NumTasks() int {
+	return numTasks(m.Tasks)
+}
+
+// NumTasks 模型的任务数，单任务模型为 1
+func (m *PredictModel) NumTasks() int {
+	return numTasks(m.Tasks)
+}
+
+// checkTasks 检查模型的任务与训练选项是否一致
+func checkTasks(modelTasks, optTasks []string) error {
+	if numTasks(modelTasks) == 1 && numTasks(optTasks) == 1 {
+		return nil
+	}
+	if strings.Join(modelTasks, ",") != strings.Join(optTasks, ",") {
+		return fmt.Errorf("task mismatch: model was trained with tasks [%s], but [%s] was specified",
+			strings.Join(modelTasks, ","), strings.Join(optTasks, ","))
+	}
+	return nil
+}
+
+// errMultiTask 多任务模型不支持的操作
+func errMultiTask(op string) error {
+	return fmt.Errorf("multi-task models do not support %s", op)
+}
+
+// formatTaskWeights 文本模型行末尾的其余任务参数: 每个任务依次为 w n z
+func formatTaskWeights(heads []TaskWeight) string {
+	var b strings.Builder
+	for _, h := range heads {
+		fmt.Fprintf(&b, " %.6g %.6g %.6g", h.W, h.N, h.Z)
+	}
+	return b.String()
+}
+
+// parseTaskWeights 解析文本模型行末尾的其余任务参数
+func parseTaskWeights(parts []string) ([]TaskWeight, error) {
+	if len(parts) == 0 {
+		return nil, nil
+	}
+	heads := make([]TaskWeight, len(parts)/3)
+	for i := range heads {
+		for j, dst := range []*float64{&heads[i].W, &heads[i].N, &heads[i].Z} {
+			v, err := strconv.ParseFloat(parts[i*3+j], 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid weight of task %d: %v", i+1, err)
+			}
+			*dst = v
+		}
+	}
+	return heads, nil
+}
+
+// headWeights 只保留其余任务的一阶权重，供预测模型使用
+func headWeights(heads []TaskWeight) []float64 {
+	if len(heads) == 0 {
+		return nil
+	}
+	w := make([]float64, len(heads))
+	for i, h := range heads {
+		w[i] = h.W
+	}
+	return w
+}
+
+// taskLabels 样本在各任务上的标签，单任务样本为 [Y]
+func taskLabels(s *sample.FFMSample) []int {
+	if s.Labels != nil {
+		return s.Labels
+	}
+	return []int{s.Y}
+}
+
+// GetTaskScores 计算每个任务的预测概率，第一个任务与 GetScore 相同
+// 各任务共享二阶交互项，只有 bias 和一阶项不同
+func (m *PredictModel) GetTaskScores(x []struct{ Field, Feature string; Value float64 }) []float64 {
+	return m.taskScores(x, m.logit(x, m.MuBias.Wi))
+}
+
+// GetTaskScoresSIMD 与 GetTaskScores 相同，二阶交互项使用SIMD计算
+func (m *PredictModel) GetTaskScoresSIMD(x []struct{ Field, Feature string; Value float64 }, ops simd.VectorOps) []float64 {
+	return m.taskScores(x, m.logitSIMD(x, m.MuBias.Wi, ops))
+}
+
+// taskScores 由第一个任务的logit换上其余任务的 bias 和一阶项，得到每个任务的概率
+func (m *PredictModel) taskScores(x []struct{ Field, Feature string; Value float64 }, logit0 float64) []float64 {
+	k := m.NumTasks()
+	logits := make([]float64, k)
+	logits[0] = logit0
+	for t := 1; t < k; t++ {
+		logits[t] = logits[0] + m.MuBias.HeadW[t-1] - m.MuBias.Wi
+	}
+	for i := range x {
+		unit, ok := m.MuMap[x[i].Feature]
+		if !ok {
+			continue
+		}
+		for t := 1; t < k; t++ {
+			logits[t] += (unit.HeadW[t-1] - unit.Wi) * x[i].Value
+		}
+	}
+
+	scores := make([]float64, k)
+	for t := range logits {
+		scores[t] = 1.0 / (1.0 + math.Exp(-logits[t]))
+	}
+	return scores
+}
@@ -0,0 +1,201 @@
+package model
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/xiongle/alphaFFM-go/pkg/sample"
+	"github.com/xiongle/alphaFFM-go/pkg/simd"
+)
+
+// multiTaskLines 在 apiSamples 的点击标签后加上转化标签：转化只在点击后有标签，i1 总是转化，i2 从不转化
+func multiTaskLines(n int) []string {
+	lines := make([]string, 0, n)
+	for _, s := range apiSamples(n) {
+		user, item := s.X[0].Feature, s.X[1].Feature
+		labels := "0,-"
+		if s.Y > 0 {
+			labels = "1,0"
+			if item == "i1" {
+				labels = "1,1"
+			}
+		}
+		lines = append(lines, fmt.Sprintf("%s user:%s:1 item:%s:1", labels, user, item))
+	}
+	return lines
+}
+
+func TestMultiTaskTrainAndPredict(t *testing.T) {
+	opt := NewTrainerOption()
+	opt.FactorNum = 4
+	opt.WL1, opt.VL1 = 0, 0
+	opt.Tasks = []string{"click", "conv"}
+	var progress bytes.Buffer
+	opt.Progress = ProgressOption{EveryLines: 1 << 20, Format: ProgressJSON, Output: &progress}
+	trainer := NewFFMTrainer(opt)
+	if err := trainer.RunTask(multiTaskLines(4000)); err != nil {
+		t.Fatalf("RunTask: %v", err)
+	}
+	// 标签个数与任务数不一致的样本被跳过
+	if err := trainer.RunTask([]string{"1 user:u1:1 item:i1:1"}); err != nil {
+		t.Fatalf("RunTask: %v", err)
+	}
+	if got := trainer.model.SampleCount(); got != 4000 {
+		t.Errorf("trained %d samples, want 4000", got)
+	}
+	// 进度和渐进验证注明按第一个任务计算，样本数包含没有第一个任务标签的样本
+	trainer.FinishProgress()
+	var final ProgressStats
+	if err := json.Unmarshal(bytes.TrimSpace(progress.Bytes()), &final); err != nil {
+		t.Fatalf("progress report %q: %v", progress.String(), err)
+	}
+	if final.Task != "click" || final.Samples != 4000 {
+		t.Errorf("progress task %q, samples %d, want click, 4000", final.Task, final.Samples)
+	}
+	if summary := trainer.ValidationSummary(); !strings.HasPrefix(summary, "progressive validation [task click]: ") {
+		t.Errorf("ValidationSummary = %q", summary)
+	}
+
+	path := filepath.Join(t.TempDir(), "model.txt")
+	if err := trainer.OutputModel(path, "txt"); err != nil {
+		t.Fatalf("OutputModel: %v", err)
+	}
+	binPath := filepath.Join(t.TempDir(), "model.bin")
+	if err := trainer.OutputModel(binPath, "bin"); err != nil {
+		t.Fatalf("OutputModel bin: %v", err)
+	}
+	m, err := OpenPredictModel(path, "txt")
+	if err != nil {
+		t.Fatalf("OpenPredictModel: %v", err)
+	}
+	if !reflect.DeepEqual(m.Tasks, opt.Tasks) {
+		t.Fatalf("model tasks %v, want %v", m.Tasks, opt.Tasks)
+	}
+
+	pair := func(user, item string) sample.FFMSample {
+		return *sample.NewFFMSample(true, []sample.FeatureValue{
+			{Field: "user", Feature: user, Value: 1},
+			{Field: "item", Feature: item, Value: 1},
+		})
+	}
+	clicked, other := pair("u2", "i2"), pair("u1", "i2")
+	conv, err := m.ScoreTasks(pair("u1", "i1"))
+	if err != nil {
+		t.Fatalf("ScoreTasks: %v", err)
+	}
+	noConv, _ := m.ScoreTasks(clicked)
+	noClick, _ := m.ScoreTasks(other)
+	if noClick[0] >= noConv[0] || noClick[0] >= conv[0] {
+		t.Errorf("click scores %v %v %v do not reflect the interactions", conv[0], noConv[0], noClick[0])
+	}
+	if conv[1] <= noConv[1] {
+		t.Errorf("conversion scores %v <= %v", conv[1], noConv[1])
+	}
+	// 第一个任务与单任务打分一致
+	if score, _ := m.Score(clicked); math.Abs(score-noConv[0]) > 1e-12 {
+		t.Errorf("Score %v, first task %v", score, noConv[0])
+	}
+	// SIMD 与标量计算一致
+	ops, err := simd.NewBLASOps()
+	if err != nil {
+		t.Fatalf("NewBLASOps: %v", err)
+	}
+	x := toPredictInput(&clicked)
+	withSIMD := m.GetTaskScoresSIMD(x, ops)
+	for k, score := range m.GetTaskScores(x) {
+		if math.Abs(score-withSIMD[k]) > 1e-12 {
+			t.Errorf("task %d: SIMD %v, scalar %v", k, withSIMD[k], score)
+		}
+	}
+
+	// logit分解只覆盖第一个任务，多任务模型拒绝 -explain 和重要性统计
+	popt := NewPredictorOption()
+	popt.ModelPath = path
+	popt.PredictPath = filepath.Join(t.TempDir(), "predict.txt")
+	popt.ExplainTopN = 5
+	if _, err := NewFFMPredictor(popt); err == nil || !strings.Contains(err.Error(), "multi-task") {
+		t.Errorf("expected multi-task error for -explain, got %v", err)
+	}
+	if _, err := NewFFMImportance(&ImportanceOption{ModelPath: path, ModelFormat: "txt"}); err == nil {
+		t.Error("expected multi-task error for importance")
+	}
+
+	// 内存中转换的模型与文件加载的模型一致（文本只保留6位有效数字，二进制无损）
+	mem, _ := trainer.PredictModel().ScoreTasks(clicked)
+	bin, err := OpenPredictModel(binPath, "bin")
+	if err != nil {
+		t.Fatalf("OpenPredictModel bin: %v", err)
+	}
+	fromBin, _ := bin.ScoreTasks(clicked)
+	for k := range mem {
+		if math.Abs(mem[k]-noConv[k]) > 1e-4 {
+			t.Errorf("task %d: in-memory %v, file %v", k, mem[k], noConv[k])
+		}
+		if fromBin[k] != mem[k] {
+			t.Errorf("task %d: bin %v, in-memory %v", k, fromBin[k], mem[k])
+		}
+	}
+
+	// 初始模型的任务须与训练选项一致
+	resumed := NewFFMTrainer(opt)
+	if err := resumed.LoadModel(path, "txt"); err != nil {
+		t.Errorf("LoadModel: %v", err)
+	}
+	single := NewFFMTrainer(NewTrainerOption())
+	if err := single.LoadModel(path, "txt"); err == nil {
+		t.Error("expected task mismatch error")
+	}
+	if _, err := MergeModels([]*FFMModel{resumed.model}, MergeAvg); err == nil {
+		t.Error("expected error merging multi-task models")
+	}
+	if _, err := DiffModels(resumed.model, resumed.model, 10); err == nil {
+		t.Error("expected error diffing multi-task models")
+	}
+	// 导出特征时列出其余任务的一阶参数
+	dump := resumed.model.DumpFeature("i1")
+	want := resumed.model.MuMap["i1"].Heads[0]
+	if dump == nil || len(dump.Tasks) != 1 || dump.Tasks[0].Task != "conv" || dump.Tasks[0].TaskWeight != want {
+		t.Errorf("DumpFeature(i1) tasks: %+v, want conv %+v", dump, want)
+	}
+	if bias := resumed.model.DumpFeature(BiasFeatureName); bias == nil || len(bias.Tasks) != 1 {
+		t.Errorf("DumpFeature(bias) tasks: %+v", bias)
+	}
+
+	// 检查点保留所有任务的参数
+	ckptDir := t.TempDir()
+	if err := trainer.SaveCheckpoint(ckptDir, 4001); err != nil {
+		t.Fatalf("SaveCheckpoint: %v", err)
+	}
+	fromCkpt := NewFFMTrainer(opt)
+	if _, err := fromCkpt.ResumeFromCheckpoint(ckptDir); err != nil {
+		t.Fatalf("ResumeFromCheckpoint: %v", err)
+	}
+	restored, _ := fromCkpt.PredictModel().ScoreTasks(clicked)
+	if !reflect.DeepEqual(restored, mem) {
+		t.Errorf("checkpoint scores %v, want %v", restored, mem)
+	}
+}
+
+func TestParseTasks(t *testing.T) {
+	tasks, err := ParseTasks("click,conv,like")
+	if err != nil || len(tasks) != 3 {
+		t.Fatalf("ParseTasks = %v, %v", tasks, err)
+	}
+	for _, bad := range []string{"click,,conv", "click,click"} {
+		if _, err := ParseTasks(bad); err == nil {
+			t.Errorf("ParseTasks(%q): expected error", bad)
+		}
+	}
+	if err := CheckTaskInput(tasks, sample.InputFormatCSV); err == nil {
+		t.Error("expected error for csv input")
+	}
+	if err := CheckTaskInput(tasks, sample.InputFormatLibFFM); err != nil {
+		t.Errorf("CheckTaskInput(libffm): %v", err)
+	}
+}
@@ -0,0 +1,221 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/xiongle/alphaFFM-go/pkg/eval"
+)
+
+// 训练进度的输出格式
+const (
+	ProgressText = "text" // 一行可读文本
+	ProgressJSON = "json" // 每次一行JSON，便于日志系统采集
+)
+
+// DefaultProgressWindow 默认按最近多少个样本计算滚动的 logloss 和 AUC
+const DefaultProgressWindow = 100000
+
+// ProgressOption 训练进度报告选项
+type ProgressOption struct {
+	EveryLines int64         // 每训练多少个样本报告一次
+	Every      time.Duration // 每隔多长时间报告一次
+	Format     string        // text 或 json
+	Window     int           // 滚动指标的窗口大小，0 表示默认值
+	Output     io.Writer     // 输出位置，nil 表示标准输出
+}
+
+// Validate 验证选项并补全默认值
+func (o *ProgressOption) Validate() error {
+	switch o.Format {
+	case "":
+		o.Format = ProgressText
+	case ProgressText, ProgressJSON:
+	default:
+		return fmt.Errorf("unsupported progress format: %s (must be text or json)", o.Format)
+	}
+	if o.Window < 0 {
+		return fmt.Errorf("progress window cannot be negative: %d", o.Window)
+	}
+	if o.Window == 0 {
+		o.Window = DefaultProgressWindow
+	}
+	return nil
+}
+
+// Enabled 是否需要报告进度
+func (o *ProgressOption) Enabled() bool {
+	return o.EveryLines > 0 || o.Every > 0
+}
+
+// ProgressStats 一次进度报告
+type ProgressStats struct {
+	Time       string   `json:"time"`
+	Samples    int64    `json:"samples"`         // 本次运行训练的样本数
+	Elapsed    float64  `json:"elapsed_sec"`     // 本次运行的耗时
+	Rate       float64  `json:"samples_per_sec"` // 上次报告以来的吞吐
+	AvgRate    float64  `json:"avg_samples_per_sec"`
+	LogLoss    *float64 `json:"logloss"`        // 最近 Window 个样本训练前预测的 logloss，没有样本时为空
+	AUC        *float64 `json:"auc"`            // 同上的 AUC，窗口内只有一类样本时为空
+	Window     int      `json:"window"`         // 计算滚动指标的样本数
+	PVLogLoss  *float64 `json:"pv_logloss"`     // 渐进验证: 本次运行全部样本训练前预测的 logloss
+	PVAUC      *float64 `json:"pv_auc"`         // 渐进验证的 AUC（按概率分桶近似）
+	Task       string   `json:"task,omitempty"` // 多任务模型中计算指标的任务（第一个任务）
+	Features   int      `json:"features"`
+	Fields     int      `json:"fields"`
+	HeapBytes  uint64   `json:"heap_bytes"`
+	Goroutines int      `json:"goroutines"`
+	Final      bool     `json:"final,omitempty"` // 训练结束时的最后一次报告
+}
+
+// progressReporter 训练进度报告，在每批样本训练后检查是否到达报告间隔
+type progressReporter struct {
+	opt        ProgressOption
+	model      *FFMModel
+	task       string // 多任务时计算指标的任务名
+	window     *eval.Window
+	validation *eval.Accumulator
+
+	mu          sync.Mutex
+	samples     int64
+	start       time.Time
+	last        time.Time
+	lastSamples int64
+}
+
+func newProgressReporter(opt ProgressOption, m *FFMModel, task string, validation *eval.Accumulator) *progressReporter {
+	now := time.Now()
+	return &progressReporter{
+		opt:        opt,
+		model:      m,
+		task:       task,
+		window:     eval.NewWindow(opt.Window),
+		validation: validation,
+		start:      now,
+		last:       now,
+	}
+}
+
+// observe 记录一批训练的 n 个样本及其训练前的预测，到达间隔时输出报告
+// 多任务时没有第一个任务标签的样本只计数，不产生预测
+func (r *progressReporter) observe(n int64, points []eval.Point) {
+	for _, p := range points {
+		r.window.Add(p)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.samples += n
+	due := r.opt.EveryLines > 0 && r.samples/r.opt.EveryLines > (r.samples-n)/r.opt.EveryLines
+	if r.opt.Every > 0 && time.Since(r.last) >= r.opt.Every {
+		due = true
+	}
+	if due {
+		r.report(false)
+	}
+}
+
+// finish 输出最后一次报告
+func (r *progressReporter) finish() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.report(true)
+}
+
+// report 生成并输出报告，调用方须持有锁
+func (r *progressReporter) report(final bool) {
+	now := time.Now()
+	stats := r.stats(now, final)
+	r.last, r.lastSamples = now, r.samples
+
+	out := r.opt.Output
+	if out == nil {
+		out = os.Stdout
+	}
+	var err error
+	if r.opt.Format == ProgressJSON {
+		var data []byte
+		if data, err = json.Marshal(stats); err == nil {
+			_, err = fmt.Fprintf(out, "%s\n", data)
+		}
+	} else {
+		_, err = fmt.Fprintln(out, stats.String())
+	}
+	if err != nil {
+		fmt.Printf("Warning: write progress error: %v\n", err)
+	}
+}
+
+// stats 收集当前的进度，调用方须持有锁
+func (r *progressReporter) stats(now time.Time, final bool) *ProgressStats {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	features, fields := r.model.Size()
+	logloss, auc := r.window.Metrics()
+	_, pvLogloss, pvAUC := r.validation.Metrics()
+
+	elapsed := now.Sub(r.start).Seconds()
+	return &ProgressStats{
+		Time:       now.Format(time.RFC3339),
+		Samples:    r.samples,
+		Elapsed:    elapsed,
+		Rate:       rate(r.samples-r.lastSamples, now.Sub(r.last).Seconds()),
+		AvgRate:    rate(r.samples, elapsed),
+		LogLoss:    finite(logloss),
+		AUC:        finite(auc),
+		Window:     r.window.Len(),
+		PVLogLoss:  finite(pvLogloss),
+		PVAUC:      finite(pvAUC),
+		Task:       r.task,
+		Features:   features,
+		Fields:     fields,
+		HeapBytes:  mem.HeapAlloc,
+		Goroutines: runtime.NumGoroutine(),
+		Final:      final,
+	}
+}
+
+// String 可读的单行进度
+func (s *ProgressStats) String() string {
+	prefix := "progress"
+	if s.Final {
+		prefix = "progress (final)"
+	}
+	if s.Task != "" {
+		prefix += " [task " + s.Task + "]"
+	}
+	return fmt.Sprintf("%s: %d samples in %s, %.0f samples/s (avg %.0f), logloss %s, auc %s (last %d), pv logloss %s, pv auc %s, features %d, fields %d, heap %.1fMB",
+		prefix, s.Samples, time.Duration(s.Elapsed*float64(time.Second)).Round(time.Second),
+		s.Rate, s.AvgRate, formatMetric(s.LogLoss), formatMetric(s.AUC), s.Window,
+		formatMetric(s.PVLogLoss), formatMetric(s.PVAUC),
+		s.Features, s.Fields, float64(s.HeapBytes)/(1<<20))
+}
+
+// rate 每秒的数量
+func rate(n int64, seconds float64) float64 {
+	if seconds <= 0 {
+		return 0
+	}
+	return float64(n) / seconds
+}
+
+// finite NaN 和 Inf 无法编码为JSON，返回空
+func finite(v float64) *float64 {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return nil
+	}
+	return &v
+}
+
+func formatMetric(v *float64) string {
+	if v == nil {
+		return "-"
+	}
+	return fmt.Sprintf("%.4f", *v)
+}
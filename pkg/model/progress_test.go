@@ -0,0 +1,72 @@
+package model
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestTrainerProgress(t *testing.T) {
+	var out bytes.Buffer
+	opt := NewTrainerOption()
+	opt.Progress = ProgressOption{EveryLines: 4, Format: ProgressJSON, Window: 3, Output: &out}
+	trainer := NewFFMTrainer(opt)
+
+	lines := []string{
+		"1 u:a:1 i:x:1",
+		"-1 u:b:1 i:y:1",
+		"1 u:a:1 i:y:1",
+		"-1 u:b:1 i:x:1",
+		"1 u:c:1 i:x:1",
+	}
+	if err := trainer.RunTask(lines); err != nil {
+		t.Fatalf("RunTask: %v", err)
+	}
+	trainer.FinishProgress()
+
+	reports := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(reports) != 2 {
+		t.Fatalf("got %d reports, want 2:\n%s", len(reports), out.String())
+	}
+	var first, final ProgressStats
+	if err := json.Unmarshal([]byte(reports[0]), &first); err != nil {
+		t.Fatalf("decode report: %v", err)
+	}
+	if err := json.Unmarshal([]byte(reports[1]), &final); err != nil {
+		t.Fatalf("decode report: %v", err)
+	}
+	if first.Samples != 5 || first.Final {
+		t.Errorf("first report: samples %d final %v, want 5 false", first.Samples, first.Final)
+	}
+	if !final.Final || final.Window != 3 {
+		t.Errorf("final report: final %v window %d, want true 3", final.Final, final.Window)
+	}
+	if final.Features != 5 || final.Fields != 2 {
+		t.Errorf("model size: %d features %d fields, want 5 and 2", final.Features, final.Fields)
+	}
+	if final.LogLoss == nil || final.AUC == nil || final.PVLogLoss == nil || final.PVAUC == nil {
+		t.Errorf("rolling metrics missing: %s", reports[1])
+	}
+	// 窗口只保留最近3个样本，渐进验证覆盖全部5个样本
+	if *final.PVLogLoss == *final.LogLoss {
+		t.Errorf("progressive validation logloss equals the window logloss: %s", reports[1])
+	}
+	if summary := trainer.ValidationSummary(); !strings.HasPrefix(summary, "progressive validation: 5 samples, logloss ") {
+		t.Errorf("ValidationSummary = %q", summary)
+	}
+}
+
+func TestProgressOptionValidate(t *testing.T) {
+	opt := ProgressOption{EveryLines: 10}
+	if err := opt.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+	if opt.Format != ProgressText || opt.Window != DefaultProgressWindow {
+		t.Errorf("defaults: format %q window %d", opt.Format, opt.Window)
+	}
+	bad := ProgressOption{Format: "xml"}
+	if err := bad.Validate(); err == nil {
+		t.Error("expected error for unsupported format")
+	}
+}
@@ -0,0 +1,147 @@
+package model
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/xiongle/alphaFFM-go/pkg/sample"
+)
+
+// ToPredictModel 在内存中把训练模型转换为预测模型，与 OutputModel 后再 LoadModel 得到的模型等价
+// （只保留非零特征），但不经过文本格式，没有精度损失。与训练同时调用时请使用 FFMTrainer.PredictModel
+func (m *FFMModel) ToPredictModel() *PredictModel {
+	return m.toPredictModel(m.outputMeta(), nil, nil)
+}
+
+// toPredictModel 复制模型参数，meta 为预测模型的元信息
+// lockFor 非空时复制每个特征前持有它返回的锁，复制 bias 时持有 biasLock
+func (m *FFMModel) toPredictModel(meta *ModelMeta, lockFor func(feature string) sync.Locker, biasLock sync.Locker) *PredictModel {
+	m.mu.RLock()
+	fieldNames := append([]string(nil), m.FieldNames...)
+	units := make(map[string]*FFMModelUnit, len(m.MuMap))
+	for feature, unit := range m.MuMap {
+		units[feature] = unit
+	}
+	bias := m.MuBias
+	m.mu.RUnlock()
+
+	pm := NewPredictModel(m.FactorNum)
+	pm.FieldNames = fieldNames
+	pm.Tasks = m.Tasks
+	pm.Meta = meta
+	if bias == nil {
+		return pm
+	}
+	pm.MuBias = &PredictModelUnit{ViMap: make(map[string][]float64)}
+	if biasLock != nil {
+		biasLock.Lock()
+		pm.MuBias.Wi, pm.MuBias.HeadW = bias.Wi, headWeights(bias.Heads)
+		biasLock.Unlock()
+	} else {
+		pm.MuBias.Wi, pm.MuBias.HeadW = bias.Wi, headWeights(bias.Heads)
+	}
+
+	// 模型中没有的 field 用同一个零向量补齐，与从文件加载的模型一致，打分时不再分配
+	zero := make([]float64, m.FactorNum)
+	for feature, unit := range units {
+		var l sync.Locker
+		if lockFor != nil {
+			l = lockFor(feature)
+			l.Lock()
+		}
+		pu := copyPredictUnit(unit, fieldNames, zero)
+		if l != nil {
+			l.Unlock()
+		}
+		if pu != nil {
+			pm.MuMap[feature] = pu
+		}
+	}
+	return pm
+}
+
+// copyPredictUnit 复制一个特征的 wi 和各 field 的隐向量，全为零时返回 nil
+func copyPredictUnit(unit *FFMModelUnit, fieldNames []string, zero []float64) *PredictModelUnit {
+	unit.mu.RLock()
+	defer unit.mu.RUnlock()
+
+	pu := &PredictModelUnit{Wi: unit.Wi, ViMap: make(map[string][]float64, len(fieldNames)), HeadW: headWeights(unit.Heads)}
+	nonZero := unit.Wi != 0.0
+	for _, w := range pu.HeadW {
+		nonZero = nonZero || w != 0.0
+	}
+	for _, field := range fieldNames {
+		vi, ok := unit.ViMap[field]
+		if !ok {
+			pu.ViMap[field] = zero
+			continue
+		}
+		pu.ViMap[field] = append([]float64(nil), vi...)
+		for _, v := range vi {
+			if v != 0.0 {
+				nonZero = true
+			}
+		}
+	}
+	if !nonZero {
+		return nil
+	}
+	return pu
+}
+
+// PredictModel 复制当前的训练结果为预测模型，可以在训练的同时调用
+// 每个特征在其特征锁下复制，因此单个特征的参数是一致的；不同特征可能来自相邻的几次更新
+// 元信息只写入返回的预测模型，不修改训练模型，可以与 OutputModel 同时调用
+// 返回的模型带有训练器的域配置，Score 等接口按其分桶和归一化
+func (t *FFMTrainer) PredictModel() *PredictModel {
+	meta := t.model.completeMeta(t.newMeta())
+	pm := t.model.toPredictModel(meta, func(feature string) sync.Locker {
+		return t.lockPool.GetFeatureLock(feature)
+	}, t.lockPool.GetBiasLock())
+	pm.FieldConfig = t.fieldConfig
+	return pm
+}
+
+// ServingModel 可以原子替换的预测模型，供边训练边服务的进程使用
+// 打分总是使用某一个完整的模型快照，替换时正在进行的打分不受影响
+type ServingModel struct {
+	current atomic.Value // *PredictModel
+}
+
+// NewServingModel 创建服务模型，m 为初始模型，可以为 nil
+func NewServingModel(m *PredictModel) *ServingModel {
+	s := &ServingModel{}
+	if m != nil {
+		s.Store(m)
+	}
+	return s
+}
+
+// Store 替换当前模型，传入的模型之后不能再修改
+func (s *ServingModel) Store(m *PredictModel) {
+	s.current.Store(m)
+}
+
+// Load 返回当前模型，还没有模型时返回 nil
+func (s *ServingModel) Load() *PredictModel {
+	m, _ := s.current.Load().(*PredictModel)
+	return m
+}
+
+// Score 用当前模型计算单个样本的预测概率
+func (s *ServingModel) Score(x sample.FFMSample) (float64, error) {
+	m := s.Load()
+	if m == nil {
+		return 0, ErrModelNotLoaded
+	}
+	return m.Score(x)
+}
+
+// ScoreBatch 用同一个模型快照计算一批样本的预测概率
+func (s *ServingModel) ScoreBatch(samples []sample.FFMSample) ([]float64, error) {
+	m := s.Load()
+	if m == nil {
+		return nil, ErrModelNotLoaded
+	}
+	return m.ScoreBatch(samples)
+}
@@ -0,0 +1,107 @@
+package model
+
+import (
+	"context"
+	"math"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/xiongle/alphaFFM-go/pkg/sample"
+)
+
+func TestToPredictModelMatchesFile(t *testing.T) {
+	opt := NewTrainerOption()
+	opt.FactorNum = 4
+	trainer := NewFFMTrainer(opt)
+	samples := apiSamples(2000)
+	if _, err := trainer.Train(context.Background(), sample.NewSliceIterator(samples)); err != nil {
+		t.Fatalf("Train: %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "model.txt")
+	if err := trainer.OutputModel(path, "txt"); err != nil {
+		t.Fatalf("OutputModel: %v", err)
+	}
+	fromFile, err := OpenPredictModel(path, "txt")
+	if err != nil {
+		t.Fatalf("OpenPredictModel: %v", err)
+	}
+	inMemory := trainer.PredictModel()
+
+	if len(inMemory.MuMap) != len(fromFile.MuMap) {
+		t.Errorf("in-memory model has %d features, file has %d", len(inMemory.MuMap), len(fromFile.MuMap))
+	}
+	if inMemory.Meta == nil || inMemory.Meta.SampleCount != 2000 {
+		t.Errorf("in-memory meta = %+v, want sample count 2000", inMemory.Meta)
+	}
+	batch := make([]sample.FFMSample, 4)
+	for i := range batch {
+		batch[i] = *samples[i]
+	}
+	want, _ := fromFile.ScoreBatch(batch)
+	got, err := inMemory.ScoreBatch(batch)
+	if err != nil {
+		t.Fatalf("ScoreBatch: %v", err)
+	}
+	for i := range want {
+		// 文件中的参数保留6位有效数字
+		if math.Abs(got[i]-want[i]) > 1e-4 {
+			t.Errorf("sample %d: in-memory score %v, file score %v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestServeWhileTraining(t *testing.T) {
+	trainer := NewFFMTrainer(NewTrainerOption())
+	serving := NewServingModel(nil)
+	if _, err := serving.Score(*apiSamples(1)[0]); err != ErrModelNotLoaded {
+		t.Errorf("Score before Store error = %v, want ErrModelNotLoaded", err)
+	}
+
+	// 训练线程之间本来就无锁读取参数（Hogwild），这里只用一个训练线程，检查快照与训练之间的同步
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if _, err := trainer.Train(context.Background(), sample.NewSliceIterator(apiSamples(6000))); err != nil {
+			t.Errorf("Train: %v", err)
+		}
+	}()
+	// 多个调用方同时取快照
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 20; i++ {
+			trainer.PredictModel()
+		}
+	}()
+	probe := *apiSamples(1)[0]
+	for i := 0; i < 20; i++ {
+		serving.Store(trainer.PredictModel())
+		if serving.Load().MuBias == nil {
+			continue
+		}
+		if _, err := serving.Score(probe); err != nil {
+			t.Fatalf("Score: %v", err)
+		}
+	}
+	wg.Wait()
+
+	// 写模型文件与取快照同时进行
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		trainer.PredictModel()
+	}()
+	if err := trainer.OutputModel(filepath.Join(t.TempDir(), "model.txt"), "txt"); err != nil {
+		t.Errorf("OutputModel: %v", err)
+	}
+	wg.Wait()
+
+	serving.Store(trainer.PredictModel())
+	score, err := serving.Score(probe)
+	if err != nil || score <= 0.5 {
+		t.Errorf("final score = %v, %v, want > 0.5 for a positive pair", score, err)
+	}
+}
@@ -0,0 +1,213 @@
+package sample
+
+import (
+	"encoding/csv"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/xiongle/alphaFFM-go/pkg/config"
+)
+
+// HeaderParser 需要输入首行表头的解析器
+// PCFrame 在分发任何样本之前把首行交给 SetHeader
+type HeaderParser interface {
+	Parser
+	NeedHeader() bool
+	SetHeader(line string) error
+}
+
+// csvColumn 绑定到列位置后的列定义
+type csvColumn struct {
+	index int
+	def   *config.CSVColumn
+}
+
+// CSVParser 按列定义解析 CSV/TSV 样本
+// csv 按 RFC 4180 处理引号（字段内不能有换行），tsv 直接按分隔符切分
+type CSVParser struct {
+	schema      *config.CSVSchema
+	delimiter   rune
+	quoted      bool
+	labelIndex  int
+	weightIndex int
+	columns     []csvColumn
+	numColumns  int
+	bound       bool
+}
+
+// NewCSVParser 创建 CSV/TSV 解析器，format 为 csv 或 tsv
+// 没有表头时按 ColumnNames 立即绑定列位置
+func NewCSVParser(format string, schema *config.CSVSchema) (*CSVParser, error) {
+	if schema == nil {
+		return nil, fmt.Errorf("%s input format requires a schema", format)
+	}
+	p := &CSVParser{schema: schema, delimiter: ',', quoted: format == InputFormatCSV}
+	if format == InputFormatTSV {
+		p.delimiter = '\t'
+	}
+	if schema.Delimiter != "" {
+		p.delimiter = rune(schema.Delimiter[0])
+	}
+	if !schema.Header {
+		if err := p.bind(schema.ColumnNames); err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+// NeedHeader 输入首行是否为表头
+func (p *CSVParser) NeedHeader() bool {
+	return p.schema.Header
+}
+
+// SetHeader 根据表头绑定列位置
+func (p *CSVParser) SetHeader(line string) error {
+	names, err := p.split(line)
+	if err != nil {
+		return fmt.Errorf("invalid header: %v", err)
+	}
+	for i := range names {
+		names[i] = strings.TrimSpace(names[i])
+	}
+	return p.bind(names)
+}
+
+// bind 将列定义绑定到列位置
+func (p *CSVParser) bind(names []string) error {
+	index := make(map[string]int, len(names))
+	for i, name := range names {
+		if _, ok := index[name]; ok {
+			return fmt.Errorf("duplicate column %s in header", name)
+		}
+		index[name] = i
+	}
+
+	declared := map[string]bool{p.schema.Label: true}
+	if p.schema.Weight != "" {
+		declared[p.schema.Weight] = true
+	}
+	for _, name := range p.schema.Ignore {
+		declared[name] = true
+	}
+
+	var ok bool
+	if p.labelIndex, ok = index[p.schema.Label]; !ok {
+		return fmt.Errorf("label column %s not found in input", p.schema.Label)
+	}
+	p.weightIndex = -1
+	if p.schema.Weight != "" {
+		if p.weightIndex, ok = index[p.schema.Weight]; !ok {
+			return fmt.Errorf("weight column %s not found in input", p.schema.Weight)
+		}
+	}
+	p.columns = p.columns[:0]
+	for i := range p.schema.Columns {
+		def := &p.schema.Columns[i]
+		declared[def.Name] = true
+		idx, ok := index[def.Name]
+		if !ok {
+			return fmt.Errorf("column %s not found in input", def.Name)
+		}
+		p.columns = append(p.columns, csvColumn{index: idx, def: def})
+	}
+	for _, name := range names {
+		if !declared[name] {
+			return fmt.Errorf("column %s is not declared in schema (add it to columns or ignore)", name)
+		}
+	}
+
+	p.numColumns = len(names)
+	p.bound = true
+	return nil
+}
+
+// split 切分一行
+func (p *CSVParser) split(line string) ([]string, error) {
+	if !p.quoted {
+		return strings.Split(line, string(p.delimiter)), nil
+	}
+	r := csv.NewReader(strings.NewReader(line))
+	r.Comma = p.delimiter
+	r.FieldsPerRecord = -1
+	return r.Read()
+}
+
+// Parse 解析一行样本
+func (p *CSVParser) Parse(line string) (*FFMSample, error) {
+	if !p.bound {
+		return nil, parseErrorf(ReasonInvalidRecord, "csv header has not been read")
+	}
+	values, err := p.split(line)
+	if err != nil {
+		return nil, err
+	}
+	if len(values) != p.numColumns {
+		return nil, parseErrorf(ReasonInvalidRecord, "expect %d columns, got %d", p.numColumns, len(values))
+	}
+
+	label, err := strconv.ParseFloat(strings.TrimSpace(values[p.labelIndex]), 64)
+	if err != nil {
+		return nil, parseErrorf(ReasonInvalidLabel, "invalid label: %v", err)
+	}
+	sample := &FFMSample{
+		Y:      -1,
+		X:      make([]FeatureValue, 0, len(p.columns)),
+		Weight: 1.0,
+	}
+	if label > 0 {
+		sample.Y = 1
+	}
+	if p.weightIndex >= 0 {
+		sample.Weight, err = strconv.ParseFloat(strings.TrimSpace(values[p.weightIndex]), 64)
+		if err != nil || sample.Weight < 0 || math.IsNaN(sample.Weight) || math.IsInf(sample.Weight, 0) {
+			return nil, parseErrorf(ReasonInvalidWeight, "invalid weight: %s", values[p.weightIndex])
+		}
+	}
+
+	for _, c := range p.columns {
+		raw := strings.TrimSpace(values[c.index])
+		// 空值视为缺失
+		if raw == "" {
+			continue
+		}
+		if c.def.Type == config.ColumnCategorical {
+			sample.X = appendCategorical(sample.X, c.def, raw)
+			continue
+		}
+
+		v, err := strconv.ParseFloat(raw, 64)
+		if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+			return nil, parseErrorf(ReasonInvalidValue, "invalid numeric value in column %s: %s", c.def.Name, raw)
+		}
+		if c.def.Buckets != nil {
+			sample.X = append(sample.X, FeatureValue{
+				Field:   c.def.Field,
+				Feature: config.BucketFeature(c.def.Name, c.def.Buckets.Bucket(v)),
+				Value:   1.0,
+			})
+		} else if v != 0 {
+			sample.X = append(sample.X, FeatureValue{Field: c.def.Field, Feature: c.def.Name, Value: v})
+		}
+	}
+	return sample, nil
+}
+
+// appendCategorical 追加类别列的特征，多值列按分隔符拆开
+func appendCategorical(x []FeatureValue, def *config.CSVColumn, raw string) []FeatureValue {
+	values := []string{raw}
+	if def.Separator != "" {
+		values = strings.Split(raw, def.Separator)
+	}
+	for _, v := range values {
+		// 模型文件以空白分隔，取值中的空白替换为下划线
+		v = strings.Join(strings.Fields(v), "_")
+		if v == "" {
+			continue
+		}
+		x = append(x, FeatureValue{Field: def.Field, Feature: def.Name + "=" + v, Value: 1.0})
+	}
+	return x
+}
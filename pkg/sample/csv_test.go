@@ -0,0 +1,87 @@
+package sample
+
+import (
+	"testing"
+
+	"github.com/xiongle/alphaFFM-go/pkg/config"
+)
+
+func testSchema(t *testing.T) *config.CSVSchema {
+	s := &config.CSVSchema{
+		Header: true,
+		Label:  "click",
+		Weight: "w",
+		Columns: []config.CSVColumn{
+			{Name: "user_id", Field: "user"},
+			{Name: "tags", Field: "item", Separator: "|"},
+			{Name: "price", Field: "item", Type: config.ColumnNumeric, Buckets: &config.BucketRule{Boundaries: []float64{10, 50}}},
+			{Name: "ctr", Type: config.ColumnNumeric},
+		},
+		Ignore: []string{"ts"},
+	}
+	if err := s.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestCSVParser(t *testing.T) {
+	p, err := NewParser(InputFormatCSV, nil, testSchema(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	hp := p.(HeaderParser)
+	if !hp.NeedHeader() {
+		t.Fatal("expected header")
+	}
+	if err := hp.SetHeader("ts,ctr,click,tags,user_id,price,w"); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := p.Parse(`123,0.25,1,"a|new york",u1,50,2`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Y != 1 || s.Weight != 2 {
+		t.Errorf("label/weight: got %d/%v", s.Y, s.Weight)
+	}
+	want := []FeatureValue{
+		{Field: "user", Feature: "user_id=u1", Value: 1},
+		{Field: "item", Feature: "tags=a", Value: 1},
+		{Field: "item", Feature: "tags=new_york", Value: 1},
+		{Field: "item", Feature: "price_b2", Value: 1},
+		{Field: "ctr", Feature: "ctr", Value: 0.25},
+	}
+	if len(s.X) != len(want) {
+		t.Fatalf("features: got %+v", s.X)
+	}
+	for i := range want {
+		if s.X[i] != want[i] {
+			t.Errorf("feature %d: got %+v, want %+v", i, s.X[i], want[i])
+		}
+	}
+
+	// 空值视为缺失
+	if s, err := p.Parse("1,,0,,u2,,1"); err != nil || len(s.X) != 1 || s.Y != -1 {
+		t.Errorf("missing values: got %+v, %v", s, err)
+	}
+	if _, err := p.Parse("1,x,0,,u2,,1"); err == nil {
+		t.Error("expected error for invalid numeric value")
+	}
+	if _, err := p.Parse("1,0"); err == nil {
+		t.Error("expected error for wrong column count")
+	}
+}
+
+func TestCSVParserUndeclaredColumn(t *testing.T) {
+	p, err := NewCSVParser(InputFormatTSV, testSchema(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.SetHeader("ts\tctr\tclick\ttags\tuser_id\tprice\tw\tnew_col"); err == nil {
+		t.Error("expected error for undeclared column")
+	}
+	if err := p.SetHeader("ts\tctr\tclick\ttags\tprice\tw"); err == nil {
+		t.Error("expected error for missing column")
+	}
+}
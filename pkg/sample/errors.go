@@ -0,0 +1,230 @@
+package sample
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// 样本解析错误的原因
+const (
+	ReasonEmptyLine      = "empty_line"      // 空行
+	ReasonInvalidLabel   = "invalid_label"   // 标签无效
+	ReasonInvalidWeight  = "invalid_weight"  // 样本权重无效
+	ReasonInvalidFeature = "invalid_feature" // 特征格式错误
+	ReasonInvalidValue   = "invalid_value"   // 特征值无效
+	ReasonUnknownField   = "unknown_field"   // 无法确定特征的域
+	ReasonInvalidRecord  = "invalid_record"  // 整行格式错误（JSON、列数等）
+)
+
+// ParseError 样本解析错误
+type ParseError struct {
+	Source string // 输入名（文件路径），未知时为空
+	Line   int64  // 在输入中的行号，从1开始，0表示未知
+	Reason string // 错误原因，见 Reason* 常量
+	Err    error
+}
+
+// Error 实现 error 接口
+func (e *ParseError) Error() string {
+	switch {
+	case e.Line > 0 && e.Source != "":
+		return fmt.Sprintf("%s:%d: %v", e.Source, e.Line, e.Err)
+	case e.Line > 0:
+		return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+	default:
+		return e.Err.Error()
+	}
+}
+
+// Unwrap 返回原始错误
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// parseErrorf 创建指定原因的解析错误
+func parseErrorf(reason, format string, args ...interface{}) error {
+	return &ParseError{Reason: reason, Err: fmt.Errorf(format, args...)}
+}
+
+// AtLine 为解析错误加上输入位置，非 ParseError 的错误归为 invalid_record
+func AtLine(err error, source string, line int64) *ParseError {
+	var pe *ParseError
+	if errors.As(err, &pe) {
+		located := *pe
+		located.Source, located.Line = source, line
+		return &located
+	}
+	return &ParseError{Source: source, Line: line, Reason: ReasonInvalidRecord, Err: err}
+}
+
+// 坏行的处理方式
+const (
+	OnErrorSkip = "skip" // 跳过坏行继续处理（默认）
+	OnErrorFail = "fail" // 遇到第一个坏行立即失败
+)
+
+// errorRateMinLines 至少处理多少行之后才按错误率判断，避免开头几行的偶然错误导致失败
+const errorRateMinLines = 1000
+
+// maxWarnings 最多逐行打印多少条坏行警告，其余只计入汇总
+const maxWarnings = 10
+
+// ErrorPolicy 坏样本的处理策略
+type ErrorPolicy struct {
+	OnError      string  // skip 或 fail
+	MaxErrorRate float64 // 大于0时，坏行比例超过该值即失败
+	RejectedPath string  // 非空时把坏行写入该文件: 位置\t原因\t原始行
+}
+
+// Validate 验证策略并补全默认值
+func (p *ErrorPolicy) Validate() error {
+	switch p.OnError {
+	case "":
+		p.OnError = OnErrorSkip
+	case OnErrorSkip, OnErrorFail:
+	default:
+		return fmt.Errorf("unsupported error policy: %s (must be skip or fail)", p.OnError)
+	}
+	if p.MaxErrorRate < 0 || p.MaxErrorRate >= 1 {
+		return fmt.Errorf("max error rate must be in [0, 1): %v", p.MaxErrorRate)
+	}
+	return nil
+}
+
+// ErrorHandler 按策略处理坏样本，统计各原因的坏行数，并发安全
+type ErrorHandler struct {
+	policy ErrorPolicy
+
+	mu       sync.Mutex
+	lines    int64
+	rejected int64
+	reasons  map[string]int64
+	file     *os.File
+	writer   *bufio.Writer
+}
+
+// NewErrorHandler 创建坏样本处理器
+func NewErrorHandler(policy ErrorPolicy) (*ErrorHandler, error) {
+	if err := policy.Validate(); err != nil {
+		return nil, err
+	}
+	h := &ErrorHandler{
+		policy:  policy,
+		reasons: make(map[string]int64),
+	}
+	if policy.RejectedPath != "" {
+		f, err := os.Create(policy.RejectedPath)
+		if err != nil {
+			return nil, fmt.Errorf("open rejected lines file error: %v", err)
+		}
+		h.file = f
+		h.writer = bufio.NewWriter(f)
+	}
+	return h, nil
+}
+
+// Record 记录一批样本的处理结果，lines 为批次总行数，errs 与 rawLines 一一对应
+// 按策略需要终止时返回错误
+func (h *ErrorHandler) Record(lines int, errs []*ParseError, rawLines []string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.lines += int64(lines)
+	for i, e := range errs {
+		h.rejected++
+		h.reasons[e.Reason]++
+		if h.writer != nil {
+			fmt.Fprintf(h.writer, "%s\t%s\t%s\n", location(e), e.Reason, rawLines[i])
+		}
+		if h.policy.OnError == OnErrorFail {
+			return fmt.Errorf("invalid sample: %v", e)
+		}
+		if h.rejected <= maxWarnings {
+			fmt.Printf("Warning: skip invalid sample: %v\n", e)
+			if h.rejected == maxWarnings {
+				fmt.Println("Warning: further invalid samples are only counted in the summary")
+			}
+		}
+	}
+	if h.lines >= errorRateMinLines {
+		return h.checkRate()
+	}
+	return nil
+}
+
+// checkRate 检查坏行比例，调用方须持有锁
+func (h *ErrorHandler) checkRate() error {
+	if h.policy.MaxErrorRate <= 0 || h.lines == 0 {
+		return nil
+	}
+	if rate := float64(h.rejected) / float64(h.lines); rate > h.policy.MaxErrorRate {
+		return fmt.Errorf("invalid sample rate %.4f exceeds max error rate %v (%d of %d lines)",
+			rate, h.policy.MaxErrorRate, h.rejected, h.lines)
+	}
+	return nil
+}
+
+// location 坏行的位置
+func location(e *ParseError) string {
+	source := e.Source
+	if source == "" {
+		source = "input"
+	}
+	if e.Line <= 0 {
+		return source
+	}
+	return fmt.Sprintf("%s:%d", source, e.Line)
+}
+
+// Finish 输入处理完成后调用: 关闭坏行文件，并按最终的坏行比例检查策略
+func (h *ErrorHandler) Finish() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.file != nil {
+		err := h.writer.Flush()
+		if cerr := h.file.Close(); err == nil {
+			err = cerr
+		}
+		h.file = nil
+		if err != nil {
+			return fmt.Errorf("write rejected lines file error: %v", err)
+		}
+	}
+	return h.checkRate()
+}
+
+// Summary 坏行汇总，例如 "rejected 3 of 1000 lines (0.30%): invalid_label 2, unknown_field 1"
+func (h *ErrorHandler) Summary() string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.rejected == 0 {
+		return fmt.Sprintf("rejected 0 of %d lines", h.lines)
+	}
+	reasons := make([]string, 0, len(h.reasons))
+	for reason := range h.reasons {
+		reasons = append(reasons, reason)
+	}
+	sort.Slice(reasons, func(i, j int) bool {
+		if h.reasons[reasons[i]] != h.reasons[reasons[j]] {
+			return h.reasons[reasons[i]] > h.reasons[reasons[j]]
+		}
+		return reasons[i] < reasons[j]
+	})
+	parts := make([]string, len(reasons))
+	for i, reason := range reasons {
+		parts[i] = fmt.Sprintf("%s %d", reason, h.reasons[reason])
+	}
+	summary := fmt.Sprintf("rejected %d of %d lines (%.2f%%): %s",
+		h.rejected, h.lines, float64(h.rejected)*100/float64(h.lines), strings.Join(parts, ", "))
+	if h.policy.RejectedPath != "" {
+		summary += ", rejected lines written to " + h.policy.RejectedPath
+	}
+	return summary
+}
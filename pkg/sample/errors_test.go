@@ -0,0 +1,91 @@
+package sample
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseErrorReason(t *testing.T) {
+	for line, reason := range map[string]string{
+		"":               ReasonEmptyLine,
+		"x a:b:1":        ReasonInvalidLabel,
+		"1 a:b:x":        ReasonInvalidValue,
+		"1 a:b:c:d":      ReasonInvalidFeature,
+		"1 small:1":      ReasonUnknownField,
+		"1 a:b:1 c:d:1x": ReasonInvalidValue,
+	} {
+		_, err := ParseSample(line)
+		var pe *ParseError
+		if !errors.As(err, &pe) || pe.Reason != reason {
+			t.Errorf("%q: got %v, want reason %s", line, err, reason)
+		}
+	}
+
+	_, err := JSONLParser{}.Parse(`not json`)
+	located := AtLine(err, "part-0", 7)
+	if located.Reason != ReasonInvalidRecord || !strings.HasPrefix(located.Error(), "part-0:7: invalid json sample") {
+		t.Errorf("located error: %v (%s)", located, located.Reason)
+	}
+	if e := AtLine(errors.New("boom"), "", 3); e.Reason != ReasonInvalidRecord || e.Error() != "line 3: boom" {
+		t.Errorf("plain error: %v (%s)", e, e.Reason)
+	}
+}
+
+func TestErrorHandler(t *testing.T) {
+	bad := func(line int64, reason string) *ParseError {
+		return &ParseError{Source: "in", Line: line, Reason: reason, Err: errors.New(reason)}
+	}
+
+	path := filepath.Join(t.TempDir(), "rejected.txt")
+	h, err := NewErrorHandler(ErrorPolicy{RejectedPath: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := h.Record(10, []*ParseError{bad(2, ReasonInvalidLabel), bad(5, ReasonInvalidLabel), bad(9, ReasonUnknownField)},
+		[]string{"a", "b", "c"}); err != nil {
+		t.Fatalf("skip policy: %v", err)
+	}
+	if err := h.Finish(); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := h.Summary(), "rejected 3 of 10 lines (30.00%): invalid_label 2, unknown_field 1"; !strings.HasPrefix(got, want) {
+		t.Errorf("summary: got %q, want prefix %q", got, want)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "in:2\tinvalid_label\ta\nin:5\tinvalid_label\tb\nin:9\tunknown_field\tc\n" {
+		t.Errorf("rejected file: %q", data)
+	}
+
+	h, _ = NewErrorHandler(ErrorPolicy{OnError: OnErrorFail})
+	if err := h.Record(1, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.Record(1, []*ParseError{bad(2, ReasonInvalidValue)}, []string{"x"}); err == nil {
+		t.Error("fail policy: expected error")
+	}
+
+	// 错误率在处理足够多行之前只在 Finish 时检查
+	h, _ = NewErrorHandler(ErrorPolicy{MaxErrorRate: 0.1})
+	if err := h.Record(10, []*ParseError{bad(1, ReasonInvalidValue), bad(2, ReasonInvalidValue)}, []string{"x", "y"}); err != nil {
+		t.Errorf("rate checked too early: %v", err)
+	}
+	if err := h.Finish(); err == nil {
+		t.Error("max error rate: expected error")
+	}
+	h, _ = NewErrorHandler(ErrorPolicy{MaxErrorRate: 0.1})
+	if err := h.Record(2000, []*ParseError{bad(1, ReasonInvalidValue)}, []string{"x"}); err != nil || h.Finish() != nil {
+		t.Error("rate below max: unexpected error")
+	}
+
+	for _, p := range []ErrorPolicy{{OnError: "ignore"}, {MaxErrorRate: 1}, {MaxErrorRate: -0.1}} {
+		if _, err := NewErrorHandler(p); err == nil {
+			t.Errorf("%+v: expected error", p)
+		}
+	}
+}
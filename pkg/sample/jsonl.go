@@ -0,0 +1,143 @@
+package sample
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// jsonSample JSONL 样本
+type jsonSample struct {
+	Label    json.RawMessage            `json:"label"`
+	Weight   *float64                   `json:"weight"`
+	Features map[string]json.RawMessage `json:"features"`
+}
+
+// JSONLParser JSON Lines 格式解析器
+// 每行一个对象，例如 {"label":1,"weight":2,"features":{"user":["u1"],"item":{"i2":0.5},"age":37}}
+//   - label: 数值（大于0为正样本）或 bool
+//   - weight: 可选的样本权重，默认1
+//   - features: key 为 field，值可以是
+//     字符串: 单个特征，值为1
+//     字符串数组: 多个特征，值都为1
+//     对象: 特征 -> 值
+//     数值: 以 field 名作为特征名的数值特征
+//
+// 特征名中的空白替换为下划线（模型文件以空白分隔），field 按名称排序保证特征顺序稳定
+type JSONLParser struct{}
+
+// Parse 解析一行样本
+func (JSONLParser) Parse(line string) (*FFMSample, error) {
+	var js jsonSample
+	if err := json.Unmarshal([]byte(line), &js); err != nil {
+		return nil, parseErrorf(ReasonInvalidRecord, "invalid json sample: %v", err)
+	}
+
+	sample := &FFMSample{
+		Y:      -1,
+		X:      make([]FeatureValue, 0, len(js.Features)),
+		Weight: 1.0,
+	}
+	positive, err := parseJSONLabel(js.Label)
+	if err != nil {
+		return nil, err
+	}
+	if positive {
+		sample.Y = 1
+	}
+	if js.Weight != nil {
+		if *js.Weight < 0 || math.IsInf(*js.Weight, 0) {
+			return nil, parseErrorf(ReasonInvalidWeight, "invalid weight: %v", *js.Weight)
+		}
+		sample.Weight = *js.Weight
+	}
+
+	fields := make([]string, 0, len(js.Features))
+	for field := range js.Features {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	for _, field := range fields {
+		if field == "" || containsSpace(field) {
+			return nil, parseErrorf(ReasonInvalidFeature, "invalid field name: %q", field)
+		}
+		sample.X, err = appendJSONFeatures(sample.X, field, js.Features[field])
+		if err != nil {
+			return nil, parseErrorf(ReasonInvalidFeature, "field %s: %v", field, err)
+		}
+	}
+	return sample, nil
+}
+
+// parseJSONLabel 解析标签
+func parseJSONLabel(raw json.RawMessage) (bool, error) {
+	// json.Unmarshal 对 null 不报错，须单独当作缺失的标签，否则会被当作负样本
+	if len(raw) == 0 || string(bytes.TrimSpace(raw)) == "null" {
+		return false, parseErrorf(ReasonInvalidLabel, "missing label")
+	}
+	var b bool
+	if err := json.Unmarshal(raw, &b); err == nil {
+		return b, nil
+	}
+	var v float64
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return false, parseErrorf(ReasonInvalidLabel, "invalid label: %s", raw)
+	}
+	return v > 0, nil
+}
+
+// appendJSONFeatures 按值的类型追加一个field的特征
+// 类别特征命名为 field=value（与 CSV 的类别列相同），不同field的同名取值不会共用模型参数；数值特征命名为field名
+func appendJSONFeatures(x []FeatureValue, field string, raw json.RawMessage) ([]FeatureValue, error) {
+	add := func(feature string, value float64) {
+		feature = strings.Join(strings.Fields(feature), "_")
+		if feature != "" && value != 0 {
+			x = append(x, FeatureValue{Field: field, Feature: field + "=" + feature, Value: value})
+		}
+	}
+
+	switch trimmed := strings.TrimSpace(string(raw)); {
+	case trimmed == "null":
+		return x, nil
+	case strings.HasPrefix(trimmed, `"`):
+		var feature string
+		if err := json.Unmarshal(raw, &feature); err != nil {
+			return nil, err
+		}
+		add(feature, 1.0)
+	case strings.HasPrefix(trimmed, "["):
+		var features []string
+		if err := json.Unmarshal(raw, &features); err != nil {
+			return nil, fmt.Errorf("list values must be strings")
+		}
+		for _, feature := range features {
+			add(feature, 1.0)
+		}
+	case strings.HasPrefix(trimmed, "{"):
+		var weighted map[string]float64
+		if err := json.Unmarshal(raw, &weighted); err != nil {
+			return nil, fmt.Errorf("map values must be numbers")
+		}
+		features := make([]string, 0, len(weighted))
+		for feature := range weighted {
+			features = append(features, feature)
+		}
+		sort.Strings(features)
+		for _, feature := range features {
+			add(feature, weighted[feature])
+		}
+	default:
+		var value float64
+		if err := json.Unmarshal(raw, &value); err != nil {
+			return nil, fmt.Errorf("unsupported value: %s", trimmed)
+		}
+		if value != 0 {
+			x = append(x, FeatureValue{Field: field, Feature: field, Value: value})
+		}
+	}
+	return x, nil
+}
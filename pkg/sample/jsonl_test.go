@@ -0,0 +1,61 @@
+package sample
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestJSONLParser(t *testing.T) {
+	s, err := JSONLParser{}.Parse(`{"label":1,"weight":2,"features":{"user":["u1","u 2"],"item":{"i2":0.5,"i1":0},"age":37,"city":"bj","none":null}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Y != 1 || s.Weight != 2 {
+		t.Errorf("label/weight: got %d/%v", s.Y, s.Weight)
+	}
+	want := []FeatureValue{
+		{Field: "age", Feature: "age", Value: 37},
+		{Field: "city", Feature: "city=bj", Value: 1},
+		{Field: "item", Feature: "item=i2", Value: 0.5},
+		{Field: "user", Feature: "user=u1", Value: 1},
+		{Field: "user", Feature: "user=u_2", Value: 1},
+	}
+	if len(s.X) != len(want) {
+		t.Fatalf("features: got %+v", s.X)
+	}
+	for i := range want {
+		if s.X[i] != want[i] {
+			t.Errorf("feature %d: got %+v, want %+v", i, s.X[i], want[i])
+		}
+	}
+
+	// 不同field中相同的取值是不同的特征
+	if s, err := (JSONLParser{}).Parse(`{"label":1,"features":{"user":"123","item":"123"}}`); err != nil ||
+		len(s.X) != 2 || s.X[0].Feature != "item=123" || s.X[1].Feature != "user=123" {
+		t.Errorf("same value in two fields: got %+v, %v", s, err)
+	}
+
+	if s, err := (JSONLParser{}).Parse(`{"label":false,"features":{}}`); err != nil || s.Y != -1 || s.Weight != 1 {
+		t.Errorf("bool label: got %+v, %v", s, err)
+	}
+	// null 标签是缺失的标签，而不是负样本
+	var pe *ParseError
+	if _, err := (JSONLParser{}).Parse(`{"label": null ,"features":{"user":"u1"}}`); !errors.As(err, &pe) || pe.Reason != ReasonInvalidLabel {
+		t.Errorf("null label: got %v, want %s", err, ReasonInvalidLabel)
+	}
+	for _, line := range []string{
+		`{"features":{"user":"u1"}}`,
+		`{"label":1,"features":{"user":[1,2]}}`,
+		`{"label":1,"features":{"user":{"u1":"x"}}}`,
+		`{"label":1,"features":{"user":true}}`,
+		`{"label":1,"features":{"":"u1"}}`,
+		`{"label":1,"features":{"us er":"u1"}}`,
+		`{"label":1,"features":{"us\u00a0er":"u1"}}`,
+		`{"label":1,"features":{"us\rer":"u1"}}`,
+		`not json`,
+	} {
+		if _, err := (JSONLParser{}).Parse(line); err == nil {
+			t.Errorf("expected error for %s", line)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package sample
+
+import (
+	"strconv"
+	"strings"
+)
+
+// LabelMissing 多任务样本中某个任务没有标签（例如未点击的样本没有转化标签），不参与该任务的训练
+const LabelMissing = 0
+
+// missingLabelToken 文本样本中表示缺失标签的记号
+const missingLabelToken = "-"
+
+// parseLabel 解析标签列: 单个整数（大于0为正样本），或逗号分隔的多任务标签，例如 "1,0,-"
+func parseLabel(s string, sample *FFMSample) error {
+	if !strings.Contains(s, ",") {
+		label, err := strconv.Atoi(s)
+		if err != nil {
+			return parseErrorf(ReasonInvalidLabel, "invalid label: %v", err)
+		}
+		sample.Y = binaryLabel(label)
+		return nil
+	}
+
+	parts := strings.Split(s, ",")
+	sample.Labels = make([]int, len(parts))
+	known := false
+	for i, part := range parts {
+		if part == missingLabelToken {
+			continue
+		}
+		label, err := strconv.Atoi(part)
+		if err != nil {
+			return parseErrorf(ReasonInvalidLabel, "invalid label of task %d: %v", i, err)
+		}
+		sample.Labels[i] = binaryLabel(label)
+		known = true
+	}
+	if !known {
+		return parseErrorf(ReasonInvalidLabel, "all task labels are missing: %s", s)
+	}
+	// 第一个任务缺失时 Y 也是 LabelMissing，不能当作负样本
+	sample.Y = sample.Labels[0]
+	return nil
+}
+
+// CheckTaskLabels 检查样本的标签个数与任务数是否一致，tasks 小于2时样本只能有一个标签
+func CheckTaskLabels(s *FFMSample, tasks int) error {
+	got := len(s.Labels)
+	if s.Labels == nil {
+		got = 1
+	}
+	if tasks < 2 {
+		if got > 1 {
+			return parseErrorf(ReasonInvalidLabel, "got %d task labels, but the model has a single task", got)
+		}
+		return nil
+	}
+	if got != tasks {
+		return parseErrorf(ReasonInvalidLabel, "expected %d task labels, got %d", tasks, got)
+	}
+	return nil
+}
+
+func binaryLabel(label int) int {
+	if label > 0 {
+		return 1
+	}
+	return -1
+}
+
+// FormatLabels 按输入格式输出样本的标签: 单任务为 1 或 -1，多任务为逗号分隔的标签，缺失为 "-"
+func FormatLabels(s *FFMSample) string {
+	if s.Labels == nil {
+		return strconv.Itoa(s.Y)
+	}
+	parts := make([]string, len(s.Labels))
+	for i, label := range s.Labels {
+		if label == LabelMissing {
+			parts[i] = missingLabelToken
+		} else {
+			parts[i] = strconv.Itoa(label)
+		}
+	}
+	return strings.Join(parts, ",")
+}
@@ -0,0 +1,48 @@
+package sample
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParseTaskLabels(t *testing.T) {
+	s, err := ParseSampleWithConfig("1,0,- u:a:1 i:x:1", nil)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if s.Y != 1 || !reflect.DeepEqual(s.Labels, []int{1, -1, LabelMissing}) {
+		t.Errorf("Y %d labels %v, want 1 [1 -1 0]", s.Y, s.Labels)
+	}
+	if got := FormatLabels(s); got != "1,-1,-" {
+		t.Errorf("FormatLabels = %q", got)
+	}
+	if err := CheckTaskLabels(s, 3); err != nil {
+		t.Errorf("CheckTaskLabels: %v", err)
+	}
+	if err := CheckTaskLabels(s, 2); err == nil {
+		t.Error("expected error for label count mismatch")
+	}
+
+	// 第一个任务缺失时 Y 为 LabelMissing，不是负样本
+	s, err = LibFFMParser{}.Parse("-,1 0:1:1")
+	if err != nil {
+		t.Fatalf("parse libffm: %v", err)
+	}
+	if s.Y != LabelMissing || !reflect.DeepEqual(s.Labels, []int{LabelMissing, 1}) {
+		t.Errorf("Y %d labels %v, want 0 [0 1]", s.Y, s.Labels)
+	}
+
+	single, _ := ParseSampleWithConfig("0 u:a:1", nil)
+	if single.Labels != nil || CheckTaskLabels(single, 1) != nil || CheckTaskLabels(single, 2) == nil {
+		t.Errorf("single label sample: labels %v", single.Labels)
+	}
+
+	for _, line := range []string{"-,- u:a:1", "1,x u:a:1"} {
+		_, err := ParseSampleWithConfig(line, nil)
+		var pe *ParseError
+		if !errors.As(err, &pe) || pe.Reason != ReasonInvalidLabel {
+			t.Errorf("%q: error %v, want invalid label", line, err)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package sample
+
+import (
+	"math"
+
+	"github.com/xiongle/alphaFFM-go/pkg/config"
+)
+
+// Normalize 按配置归一化样本的特征值
+// 先按域归一化（每个域独立计算），再做样本级L2归一化；范数为0的域或样本保持不变
+func Normalize(s *FFMSample, cfg *config.FieldConfig) {
+	if cfg == nil || !cfg.HasNormalization() {
+		return
+	}
+
+	if len(cfg.FieldNorm) > 0 {
+		// 按域累计 L1、L2 和个数
+		type fieldSum struct {
+			l1, l2 float64
+			count  int
+		}
+		sums := make(map[string]*fieldSum)
+		for _, x := range s.X {
+			fs, ok := sums[x.Field]
+			if !ok {
+				fs = &fieldSum{}
+				sums[x.Field] = fs
+			}
+			fs.l1 += math.Abs(x.Value)
+			fs.l2 += x.Value * x.Value
+			fs.count++
+		}
+
+		for i := range s.X {
+			fs := sums[s.X[i].Field]
+			var denom float64
+			switch cfg.FieldNormFor(s.X[i].Field) {
+			case config.NormL1:
+				denom = fs.l1
+			case config.NormL2:
+				denom = math.Sqrt(fs.l2)
+			case config.NormCount:
+				denom = float64(fs.count)
+			}
+			if denom > 0 {
+				s.X[i].Value /= denom
+			}
+		}
+	}
+
+	if cfg.InstanceNorm {
+		norm := 0.0
+		for _, x := range s.X {
+			norm += x.Value * x.Value
+		}
+		if norm > 0 {
+			norm = math.Sqrt(norm)
+			for i := range s.X {
+				s.X[i].Value /= norm
+			}
+		}
+	}
+}
+
+// Prepare 对不经过文本解析的样本做与解析器相同的处理：按配置分桶（值为0的数值同样分桶），
+// 跳过值为0的特征，再按域和样本归一化。返回新的样本，不修改 s；没有分桶和归一化配置时直接返回 s
+func Prepare(s *FFMSample, cfg *config.FieldConfig) (*FFMSample, error) {
+	if cfg == nil || (len(cfg.Buckets) == 0 && !cfg.HasNormalization()) {
+		return s, nil
+	}
+	prepared := *s
+	prepared.X = make([]FeatureValue, 0, len(s.X))
+	for _, x := range s.X {
+		feature, value, err := cfg.Discretize(x.Feature, x.Value)
+		if err != nil {
+			return nil, parseErrorf(ReasonInvalidValue, "%v", err)
+		}
+		if value != 0 {
+			prepared.X = append(prepared.X, FeatureValue{Field: x.Field, Feature: feature, Value: value})
+		}
+	}
+	Normalize(&prepared, cfg)
+	return &prepared, nil
+}
+
+// normalizingParser 在其他解析器的结果上应用域配置中的归一化
+// 用于不使用域映射的输入格式（libffm、csv/tsv、jsonl）
+type normalizingParser struct {
+	Parser
+	cfg *config.FieldConfig
+}
+
+// Parse 解析并归一化一行样本
+func (p *normalizingParser) Parse(line string) (*FFMSample, error) {
+	s, err := p.Parser.Parse(line)
+	if err != nil {
+		return nil, err
+	}
+	Normalize(s, p.cfg)
+	return s, nil
+}
+
+// NeedHeader 转发给被包装的解析器
+func (p *normalizingParser) NeedHeader() bool {
+	h, ok := p.Parser.(HeaderParser)
+	return ok && h.NeedHeader()
+}
+
+// SetHeader 转发给被包装的解析器
+func (p *normalizingParser) SetHeader(line string) error {
+	if h, ok := p.Parser.(HeaderParser); ok {
+		return h.SetHeader(line)
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package sample
+
+import (
+	"math"
+	"testing"
+
+	"github.com/xiongle/alphaFFM-go/pkg/config"
+)
+
+func TestNormalize(t *testing.T) {
+	cfg := config.NewFieldConfig()
+	cfg.Mode = "explicit"
+	cfg.FieldNorm = map[string]string{"tag": config.NormCount, "item": config.NormL1, "*": config.NormL2}
+	if err := cfg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := ParseSampleWithConfig("1 tag:t1:1 tag:t2:1 tag:t3:1 tag:t4:1 item:i1:3 item:i2:-1 user:age:3 user:u1:4", cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []float64{0.25, 0.25, 0.25, 0.25, 0.75, -0.25, 0.6, 0.8}
+	for i, w := range want {
+		if math.Abs(s.X[i].Value-w) > 1e-12 {
+			t.Errorf("feature %s: got %v, want %v", s.X[i].Feature, s.X[i].Value, w)
+		}
+	}
+
+	cfg.FieldNorm = nil
+	cfg.InstanceNorm = true
+	s, err = ParseSampleWithConfig("1 a:x:3 b:y:4", cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(s.X[0].Value-0.6) > 1e-12 || math.Abs(s.X[1].Value-0.8) > 1e-12 {
+		t.Errorf("instance norm: got %+v", s.X)
+	}
+
+	// 其他格式通过包装的解析器归一化
+	p, err := NewParser(InputFormatLibFFM, cfg, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err = p.Parse("1 0:1:3 1:2:4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(s.X[0].Value-0.6) > 1e-12 || math.Abs(s.X[1].Value-0.8) > 1e-12 {
+		t.Errorf("libffm instance norm: got %+v", s.X)
+	}
+
+	cfg.FieldNorm = map[string]string{"tag": "max"}
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error for unsupported normalization")
+	}
+}
@@ -0,0 +1,126 @@
+package sample
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/xiongle/alphaFFM-go/pkg/config"
+)
+
+// 输入格式
+const (
+	InputFormatFFM    = "ffm"    // label field:feature:value 或 feature:value，默认；多任务标签为 1,0,-
+	InputFormatLibFFM = "libffm" // libffm格式: label field_id:feature_id:value，field和feature都是非负整数；支持多任务标签
+	InputFormatCSV    = "csv"    // 按列定义解析的CSV
+	InputFormatTSV    = "tsv"    // 按列定义解析的TSV
+	InputFormatJSONL  = "jsonl"  // JSON Lines: {"label":1,"features":{"user":["u1"],"item":{"i2":0.5}}}
+)
+
+// Parser 样本解析器
+type Parser interface {
+	Parse(line string) (*FFMSample, error)
+}
+
+// NewParser 根据输入格式创建解析器
+// fieldConfig 用于 ffm 格式，schema 用于 csv/tsv 格式
+func NewParser(format string, fieldConfig *config.FieldConfig, schema *config.CSVSchema) (Parser, error) {
+	var parser Parser
+	switch format {
+	case "", InputFormatFFM:
+		// ffm 格式在 ParseSampleWithConfig 中完成归一化
+		return &ffmParser{fieldConfig: fieldConfig}, nil
+	case InputFormatLibFFM:
+		parser = LibFFMParser{}
+	case InputFormatCSV, InputFormatTSV:
+		p, err := NewCSVParser(format, schema)
+		if err != nil {
+			return nil, err
+		}
+		parser = p
+	case InputFormatJSONL:
+		parser = JSONLParser{}
+	default:
+		return nil, fmt.Errorf("unsupported input format: %s (must be ffm, libffm, csv, tsv or jsonl)", format)
+	}
+
+	// 其他格式只使用域配置中的归一化设置
+	if fieldConfig == nil {
+		return parser, nil
+	}
+	if format != InputFormatCSV && format != InputFormatTSV {
+		fmt.Printf("Warning: field mapping in field config is ignored in %s input format\n", format)
+	}
+	if !fieldConfig.HasNormalization() {
+		return parser, nil
+	}
+	return &normalizingParser{Parser: parser, cfg: fieldConfig}, nil
+}
+
+// NewZeroKeepingParser 创建保留值为0的特征的 ffm 格式解析器
+// 训练时值为0的数值特征同样会分桶，拟合分桶边界时需要统计这些0
+func NewZeroKeepingParser(fieldConfig *config.FieldConfig) Parser {
+	return &ffmParser{fieldConfig: fieldConfig, keepZeros: true}
+}
+
+// ffmParser 默认格式解析器
+type ffmParser struct {
+	fieldConfig *config.FieldConfig
+	keepZeros   bool // 保留值为0的特征
+}
+
+// Parse 解析一行样本
+func (p *ffmParser) Parse(line string) (*FFMSample, error) {
+	return parseSample(line, p.fieldConfig, p.keepZeros)
+}
+
+// LibFFMParser libffm格式解析器
+// 严格要求每个特征为 field_id:feature_id:value，ID 为非负整数，
+// field 和 feature 名称规范化为十进制ID（"007" 与 "7" 为同一个ID），与 libffm 模型互转时一一对应
+type LibFFMParser struct{}
+
+// Parse 解析一行样本
+func (LibFFMParser) Parse(line string) (*FFMSample, error) {
+	parts := strings.Fields(line)
+	if len(parts) == 0 {
+		return nil, parseErrorf(ReasonEmptyLine, "empty line")
+	}
+
+	sample := &FFMSample{
+		X:      make([]FeatureValue, 0, len(parts)-1),
+		Weight: 1.0,
+	}
+	if err := parseLabel(parts[0], sample); err != nil {
+		return nil, err
+	}
+
+	for i := 1; i < len(parts); i++ {
+		kv := strings.Split(parts[i], ":")
+		if len(kv) != 3 {
+			return nil, parseErrorf(ReasonInvalidFeature, "invalid libffm feature format: %s (expect field_id:feature_id:value)", parts[i])
+		}
+		fieldID, err := strconv.ParseUint(kv[0], 10, 31)
+		if err != nil {
+			return nil, parseErrorf(ReasonInvalidFeature, "invalid libffm field id: %s", parts[i])
+		}
+		featureID, err := strconv.ParseUint(kv[1], 10, 31)
+		if err != nil {
+			return nil, parseErrorf(ReasonInvalidFeature, "invalid libffm feature id: %s", parts[i])
+		}
+		value, err := strconv.ParseFloat(kv[2], 64)
+		if err != nil {
+			return nil, parseErrorf(ReasonInvalidValue, "invalid feature value: %v", err)
+		}
+
+		// 跳过值为0的特征
+		if value != 0 {
+			sample.X = append(sample.X, FeatureValue{
+				Field:   strconv.FormatUint(fieldID, 10),
+				Feature: strconv.FormatUint(featureID, 10),
+				Value:   value,
+			})
+		}
+	}
+
+	return sample, nil
+}
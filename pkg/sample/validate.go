@@ -0,0 +1,90 @@
+package sample
+
+import (
+	"io"
+	"math"
+	"strings"
+	"unicode"
+)
+
+// NewFFMSample 创建权重为1的样本，positive 为 true 时标签为1，否则为-1
+func NewFFMSample(positive bool, x []FeatureValue) *FFMSample {
+	y := -1
+	if positive {
+		y = 1
+	}
+	return &FFMSample{Y: y, X: x, Weight: 1.0}
+}
+
+// Validate 检查在代码中构造的样本，规则与解析文本样本时相同
+func (s *FFMSample) Validate() error {
+	if s.Labels == nil && s.Y != 1 && s.Y != -1 {
+		return parseErrorf(ReasonInvalidLabel, "invalid label: %d (must be 1 or -1)", s.Y)
+	}
+	if s.Labels != nil {
+		// 多任务样本以 Labels 为准，Y 为第一个任务的标签，可以是 LabelMissing
+		if s.Y != 1 && s.Y != -1 && s.Y != LabelMissing {
+			return parseErrorf(ReasonInvalidLabel, "invalid label: %d (must be 1, -1 or LabelMissing)", s.Y)
+		}
+		known := false
+		for i, label := range s.Labels {
+			if label != 1 && label != -1 && label != LabelMissing {
+				return parseErrorf(ReasonInvalidLabel, "invalid label of task %d: %d (must be 1, -1 or LabelMissing)", i, label)
+			}
+			known = known || label != LabelMissing
+		}
+		if !known {
+			return parseErrorf(ReasonInvalidLabel, "all task labels are missing")
+		}
+	}
+	if s.Weight < 0 || math.IsNaN(s.Weight) || math.IsInf(s.Weight, 0) {
+		return parseErrorf(ReasonInvalidWeight, "invalid weight: %v", s.Weight)
+	}
+	return ValidateFeatures(s.X)
+}
+
+// ValidateFeatures 检查特征: 域名和特征名非空且不含空白字符（模型文件按空白分隔），取值有限
+func ValidateFeatures(x []FeatureValue) error {
+	for _, fv := range x {
+		if fv.Field == "" || containsSpace(fv.Field) {
+			return parseErrorf(ReasonInvalidFeature, "invalid field name %q of feature %q", fv.Field, fv.Feature)
+		}
+		if fv.Feature == "" || containsSpace(fv.Feature) {
+			return parseErrorf(ReasonInvalidFeature, "invalid feature name %q in field '%s'", fv.Feature, fv.Field)
+		}
+		if math.IsNaN(fv.Value) || math.IsInf(fv.Value, 0) {
+			return parseErrorf(ReasonInvalidValue, "invalid value for feature '%s': %v", fv.Feature, fv.Value)
+		}
+	}
+	return nil
+}
+
+// containsSpace 是否含有空白字符，与模型加载时 strings.Fields 的分隔规则一致
+func containsSpace(s string) bool {
+	return strings.IndexFunc(s, unicode.IsSpace) >= 0
+}
+
+// SampleIterator 逐个产生样本，没有更多样本时返回 io.EOF
+type SampleIterator interface {
+	Next() (*FFMSample, error)
+}
+
+// sliceIterator 遍历内存中的样本
+type sliceIterator struct {
+	samples []*FFMSample
+	next    int
+}
+
+// NewSliceIterator 返回遍历 samples 的迭代器
+func NewSliceIterator(samples []*FFMSample) SampleIterator {
+	return &sliceIterator{samples: samples}
+}
+
+func (it *sliceIterator) Next() (*FFMSample, error) {
+	if it.next >= len(it.samples) {
+		return nil, io.EOF
+	}
+	s := it.samples[it.next]
+	it.next++
+	return s, nil
+}
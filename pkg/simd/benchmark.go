@@ -105,5 +105,6 @@ func PrintBenchmarkResults(results []*BenchmarkResult) {
 			r.Speedup,
 		)
 	}
-	fmt.Println("================================================\n")
+	fmt.Println("================================================")
+	fmt.Println()
 }
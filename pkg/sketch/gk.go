@@ -0,0 +1,153 @@
+package sketch
+
+import (
+	"math"
+	"sort"
+)
+
+// gkTuple GK摘要中的一项
+// g 为与前一项的最小秩之差，delta 为该项最大秩与最小秩之差
+type gkTuple struct {
+	v     float64
+	g     int64
+	delta int64
+}
+
+// GK Greenwald-Khanna 流式分位数摘要
+// 任意分位数查询的秩误差不超过 eps*n，占用空间 O((1/eps)*log(eps*n))
+// 非并发安全
+type GK struct {
+	eps     float64
+	n       int64
+	tuples  []gkTuple
+	period  int64 // 每插入多少个值压缩一次
+	inserts int64
+}
+
+// NewGK 创建GK摘要，eps 为允许的秩误差比例（如 0.001）
+func NewGK(eps float64) *GK {
+	if eps <= 0 || eps >= 1 {
+		eps = 0.001
+	}
+	period := int64(math.Floor(1 / (2 * eps)))
+	if period < 1 {
+		period = 1
+	}
+	return &GK{eps: eps, period: period}
+}
+
+// Count 已插入的值个数
+func (s *GK) Count() int64 {
+	return s.n
+}
+
+// Insert 插入一个值，NaN 被忽略
+func (s *GK) Insert(v float64) {
+	if math.IsNaN(v) {
+		return
+	}
+	i := sort.Search(len(s.tuples), func(i int) bool { return s.tuples[i].v > v })
+
+	t := gkTuple{v: v, g: 1}
+	// 首尾的值秩是精确的
+	if i > 0 && i < len(s.tuples) {
+		t.delta = int64(math.Floor(2 * s.eps * float64(s.n)))
+	}
+	s.tuples = append(s.tuples, gkTuple{})
+	copy(s.tuples[i+1:], s.tuples[i:])
+	s.tuples[i] = t
+	s.n++
+
+	s.inserts++
+	if s.inserts%s.period == 0 {
+		s.compress()
+	}
+}
+
+// compress 合并相邻的项，保持误差界
+func (s *GK) compress() {
+	if len(s.tuples) < 3 {
+		return
+	}
+	threshold := int64(math.Floor(2 * s.eps * float64(s.n)))
+	// 从后往前合并，保留首尾两项
+	out := make([]gkTuple, 0, len(s.tuples))
+	out = append(out, s.tuples[len(s.tuples)-1])
+	for i := len(s.tuples) - 2; i >= 1; i-- {
+		cur := s.tuples[i]
+		last := &out[len(out)-1]
+		if cur.g+last.g+last.delta <= threshold {
+			last.g += cur.g
+			continue
+		}
+		out = append(out, cur)
+	}
+	out = append(out, s.tuples[0])
+	for i, j := 0, len(out)-1; i < j; i, j = i+1, j-1 {
+		out[i], out[j] = out[j], out[i]
+	}
+	s.tuples = out
+}
+
+// Query 返回 phi 分位数（0 <= phi <= 1）的近似值，没有数据时返回 NaN
+func (s *GK) Query(phi float64) float64 {
+	if s.n == 0 {
+		return math.NaN()
+	}
+	if phi <= 0 {
+		return s.tuples[0].v
+	}
+	if phi >= 1 {
+		return s.tuples[len(s.tuples)-1].v
+	}
+
+	rank := phi * float64(s.n)
+	bound := rank + s.eps*float64(s.n)
+	var rmin int64
+	for i, t := range s.tuples {
+		rmin += t.g
+		if i+1 < len(s.tuples) {
+			next := s.tuples[i+1]
+			if float64(rmin+next.g+next.delta) > bound {
+				return t.v
+			}
+		}
+	}
+	return s.tuples[len(s.tuples)-1].v
+}
+
+// Min 最小值
+func (s *GK) Min() float64 {
+	if s.n == 0 {
+		return math.NaN()
+	}
+	return s.tuples[0].v
+}
+
+// Max 最大值
+func (s *GK) Max() float64 {
+	if s.n == 0 {
+		return math.NaN()
+	}
+	return s.tuples[len(s.tuples)-1].v
+}
+
+// Boundaries 计算把数据分成 buckets 个等频桶的边界
+// 重复的分位点（如大量相同的值）和等于最小值的分位点（会产生空的桶0）会被去掉，
+// 因此返回的边界可能少于 buckets-1 个
+func (s *GK) Boundaries(buckets int) []float64 {
+	var boundaries []float64
+	for i := 1; i < buckets; i++ {
+		q := s.Query(float64(i) / float64(buckets))
+		if math.IsNaN(q) {
+			return nil
+		}
+		if q <= s.Min() {
+			continue
+		}
+		if len(boundaries) == 0 || q > boundaries[len(boundaries)-1] {
+			boundaries = append(boundaries, q)
+		}
+	}
+	return boundaries
+}
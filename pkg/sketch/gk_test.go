@@ -0,0 +1,56 @@
+package sketch
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+func TestGKRankError(t *testing.T) {
+	const n = 200000
+	const eps = 0.001
+	rng := rand.New(rand.NewSource(1))
+	s := NewGK(eps)
+	values := make([]float64, n)
+	for i := range values {
+		values[i] = rng.ExpFloat64() * 100
+		s.Insert(values[i])
+	}
+	sort.Float64s(values)
+
+	if s.Count() != n || s.Min() != values[0] || s.Max() != values[n-1] {
+		t.Fatalf("count/min/max: %d %v %v", s.Count(), s.Min(), s.Max())
+	}
+	for _, phi := range []float64{0.01, 0.1, 0.25, 0.5, 0.75, 0.9, 0.99} {
+		q := s.Query(phi)
+		rank := sort.SearchFloat64s(values, q)
+		if math.Abs(float64(rank)-phi*n) > 2*eps*n {
+			t.Errorf("phi %v: value %v has rank %d, want %v ± %v", phi, q, rank, phi*n, eps*n)
+		}
+	}
+	if len(s.tuples) > n/10 {
+		t.Errorf("sketch not compressed: %d tuples", len(s.tuples))
+	}
+}
+
+func TestGKBoundaries(t *testing.T) {
+	s := NewGK(0.001)
+	// 一半是0
+	for i := 0; i < 1000; i++ {
+		s.Insert(0)
+		s.Insert(float64(i))
+	}
+	b := s.Boundaries(10)
+	if len(b) == 0 || b[0] <= 0 {
+		t.Fatalf("boundaries: %v", b)
+	}
+	for i := 1; i < len(b); i++ {
+		if b[i] <= b[i-1] {
+			t.Fatalf("boundaries not increasing: %v", b)
+		}
+	}
+	if NewGK(0.01).Boundaries(10) != nil {
+		t.Error("expected nil boundaries without data")
+	}
+}
@@ -0,0 +1,27 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// SignalContext 返回收到 SIGINT/SIGTERM 时取消的 context
+// 第一次信号触发优雅退出，之后恢复默认行为，再次发送信号会直接终止进程
+func SignalContext() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		select {
+		case sig := <-ch:
+			fmt.Printf("received %v, stopping after in-flight batches (send again to exit immediately)\n", sig)
+			cancel()
+		case <-ctx.Done():
+		}
+		signal.Stop(ch)
+	}()
+	return ctx, cancel
+}
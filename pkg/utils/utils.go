@@ -1,9 +1,12 @@
 package utils
 
 import (
+	"fmt"
 	"math"
 	"math/rand"
+	"strconv"
 	"strings"
+	"time"
 )
 
 const kPrecision = 0.0000000001
@@ -60,3 +63,27 @@ func Abs(x float64) float64 {
 	return math.Abs(x)
 }
 
+
+// ParseInterval 解析间隔参数
+// 纯数字表示样本（行）数，例如 "100000"；带时间单位表示时长，例如 "30s"、"10m"
+// 返回: (行数, 时长, 错误)，两者中只有一个非零
+func ParseInterval(s string) (int64, time.Duration, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, 0, nil
+	}
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		if n < 0 {
+			return 0, 0, fmt.Errorf("invalid interval %q: must not be negative", s)
+		}
+		return n, 0, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid interval %q: expected a line count or a duration such as 30s", s)
+	}
+	if d < 0 {
+		return 0, 0, fmt.Errorf("invalid interval %q: must not be negative", s)
+	}
+	return 0, d, nil
+}
@@ -21,7 +21,7 @@ cat test_data.txt | ./bin/ffm_train -m model_auto.txt -dim 1,1,4 -core 1 2>&1 |
 echo ""
 
 echo "提取的 Field 列表:"
-head -1 model_auto.txt
+grep -m1 "^FIELDS" model_auto.txt
 echo ""
 
 echo "解释:"
@@ -44,7 +44,7 @@ cat test_data_explicit_field.txt | ./bin/ffm_train -m model_explicit.txt -dim 1,
 echo ""
 
 echo "提取的 Field 列表:"
-head -1 model_explicit.txt
+grep -m1 "^FIELDS" model_explicit.txt
 echo ""
 
 echo "解释:"
@@ -57,8 +57,8 @@ echo "Field 数量对比"
 echo "=========================================="
 echo ""
 
-AUTO_FIELDS=$(head -1 model_auto.txt | awk '{print NF-1}')
-EXPLICIT_FIELDS=$(head -1 model_explicit.txt | awk '{print NF-1}')
+AUTO_FIELDS=$(grep -m1 "^FIELDS" model_auto.txt | awk '{print NF-1}')
+EXPLICIT_FIELDS=$(grep -m1 "^FIELDS" model_explicit.txt | awk '{print NF-1}')
 
 echo "自动提取模式: $AUTO_FIELDS 个 field"
 echo "显式指定模式: $EXPLICIT_FIELDS 个 field"
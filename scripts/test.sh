@@ -119,7 +119,14 @@ echo ""
 
 echo -e "${YELLOW}测试 7: 模型文件检查${NC}"
 echo "  检查模型文件格式..."
-FIRST_LINE=$(head -1 test_model_scalar.txt)
+META_LINE=$(head -1 test_model_scalar.txt)
+if [[ $META_LINE == META* ]]; then
+    echo -e "${GREEN}✓ 模型文件包含 META 头${NC}"
+else
+    echo -e "${RED}✗ 模型文件缺少 META 头${NC}"
+fi
+
+FIRST_LINE=$(head -2 test_model_scalar.txt | tail -1)
 if [[ $FIRST_LINE == FIELDS* ]]; then
     echo -e "${GREEN}✓ 模型文件包含 FIELDS 头${NC}"
     echo "    $FIRST_LINE"
@@ -127,7 +134,7 @@ else
     echo -e "${RED}✗ 模型文件格式错误${NC}"
 fi
 
-SECOND_LINE=$(head -2 test_model_scalar.txt | tail -1)
+SECOND_LINE=$(head -3 test_model_scalar.txt | tail -1)
 if [[ $SECOND_LINE == bias* ]]; then
     echo -e "${GREEN}✓ 模型文件包含 bias 行${NC}"
 else
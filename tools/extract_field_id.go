@@ -38,7 +38,7 @@ func main() {
 
 	fmt.Println("==========================================")
 	fmt.Println("使用建议：")
-	fmt.Println("  - 特征ID > 4294967296 (2^32) 时自动提取域ID")
+	fmt.Println("  - 特征ID >= numeric_field_threshold（默认1000000）时自动提取域ID")
 	fmt.Println("  - 小特征使用配置文件映射")
 	fmt.Println("==========================================")
 }